@@ -24,10 +24,15 @@ import (
 )
 
 type Config struct {
-	Log     LogConfig
-	Mysql   MysqlConfig
-	Jenkins JenkinsConfig
-	Sonar   SonarConfig
+	Log       LogConfig
+	Mysql     MysqlConfig
+	Jenkins   JenkinsConfig
+	Sonar     SonarConfig
+	Auth      AuthConfig
+	Rbac      RbacConfig
+	Ldap      LdapConfig
+	Operation OperationConfig
+	Trash     TrashConfig
 }
 
 type LogConfig struct {
@@ -54,6 +59,54 @@ type SonarConfig struct {
 	Token   string `default:""`
 }
 
+// AuthConfig selects how the request user is authenticated.
+// Mode jwt verifies bearer tokens with the HMAC secret, RSA public key or JWKS file,
+// mode header trusts the username header and must only be used behind a trusted proxy.
+// Platform admins can impersonate users with the Impersonate-User header, impersonated requests
+// are read-only unless AllowImpersonatedWrites is set.
+type AuthConfig struct {
+	Mode             string `default:"jwt"` // jwt, header
+	JwtSecret        string `default:""`
+	JwtPublicKeyFile string `default:""`
+	JwksFile         string `default:""`
+	JwtIssuer        string `default:""`
+	UsernameClaim    string `default:"username"`
+	UsernameHeader   string `default:"X-Token-Username"`
+
+	AllowImpersonatedWrites bool `default:"false"`
+}
+
+// RbacConfig configures the global roles.
+// Administrators is a comma separated list of users who always have the platform-admin role,
+// with RestrictProjectCreation only platform-admin and project-creator users can create projects.
+type RbacConfig struct {
+	Administrators          []string `default:"admin"`
+	RestrictProjectCreation bool     `default:"false"`
+}
+
+// OperationConfig configures the workers executing async requests.
+type OperationConfig struct {
+	Workers int `default:"4"`
+}
+
+// TrashConfig configures how long deleted projects can be restored before they are purged.
+type TrashConfig struct {
+	RetentionDays int `default:"7"`
+}
+
+// LdapConfig configures the group sync from an ldap server, it is disabled without address.
+// Members are usernames taken from the UserIdAttribute of member dns, or plain member values like memberUid.
+type LdapConfig struct {
+	Address            string `default:""` // ldap://host:389, ldaps://host:636
+	BindDN             string `default:""`
+	BindPassword       string `default:""`
+	BaseDN             string `default:""`
+	GroupFilter        string `default:"(objectClass=groupOfNames)"`
+	GroupNameAttribute string `default:"cn"`
+	MemberAttribute    string `default:"member"`
+	UserIdAttribute    string `default:"uid"`
+}
+
 func (m *MysqlConfig) GetUrl() string {
 	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", m.User, m.Password, m.Host, m.Port, m.Database)
 }
@@ -77,6 +130,26 @@ func ParseFlag() {
 
 var profilingServerStarted = false
 
+const redactedValue = "******"
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redacted returns a copy of the config without passwords, tokens and secrets to be logged.
+func (c *Config) redacted() *Config {
+	redacted := *c
+	redacted.Mysql.Password = redact(c.Mysql.Password)
+	redacted.Jenkins.Password = redact(c.Jenkins.Password)
+	redacted.Sonar.Token = redact(c.Sonar.Token)
+	redacted.Auth.JwtSecret = redact(c.Auth.JwtSecret)
+	redacted.Ldap.BindPassword = redact(c.Ldap.BindPassword)
+	return &redacted
+}
+
 func LoadConf() *Config {
 	ParseFlag()
 
@@ -91,7 +164,7 @@ func LoadConf() *Config {
 		panic(err)
 	}
 	logger.SetLevelByString(config.Log.Level)
-	logger.Info("LoadConf: %+v", config)
+	logger.Info("LoadConf: %+v", config.redacted())
 
 	return config
 }
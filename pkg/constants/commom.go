@@ -21,6 +21,8 @@ const (
 	StatusPending    = "pending"
 	StatusWorking    = "working"
 	StatusSuccessful = "successful"
+	StatusTrashed    = "trashed"
+	StatusArchived   = "archived"
 )
 
 const (
@@ -36,7 +38,3 @@ const (
 	VisibilityPrivate = "private"
 	VisibilityPublic  = "public"
 )
-
-const (
-	KS_ADMIN = "admin"
-)
@@ -0,0 +1,2 @@
+ALTER TABLE `project_membership`
+  ADD COLUMN `expire_time` TIMESTAMP NULL DEFAULT NULL;
@@ -0,0 +1,13 @@
+CREATE TABLE `impersonation_log` (
+  `log_id`      VARCHAR(50)  NOT NULL,
+  `admin`       VARCHAR(50)  NOT NULL,
+  `username`    VARCHAR(50)  NOT NULL,
+  `method`      VARCHAR(10)  NOT NULL,
+  `path`        VARCHAR(255) NOT NULL,
+  `status_code` INT          NOT NULL,
+  `outcome`     VARCHAR(50)  NOT NULL,
+  `create_time` TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`log_id`),
+  KEY `impersonation_log_admin_idx` (`admin`),
+  KEY `impersonation_log_username_idx` (`username`)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE `project_creation_step` (
+  `project_id`  VARCHAR(50) NOT NULL,
+  `step`        VARCHAR(50) NOT NULL,
+  `seq`         INT         NOT NULL,
+  `status`      VARCHAR(50) NOT NULL,
+  `message`     TEXT        NOT NULL,
+  `status_time` TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`project_id`, `step`)
+);
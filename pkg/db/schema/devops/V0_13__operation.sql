@@ -0,0 +1,17 @@
+CREATE TABLE `operation` (
+  `operation_id` VARCHAR(50)  NOT NULL,
+  `method`       VARCHAR(10)  NOT NULL,
+  `path`         VARCHAR(255) NOT NULL,
+  `body`         MEDIUMTEXT   NOT NULL,
+  `operator`     VARCHAR(50)  NOT NULL,
+  `impersonator` VARCHAR(50)  NOT NULL DEFAULT '',
+  `status`       VARCHAR(50)  NOT NULL,
+  `status_code`  INT          NOT NULL DEFAULT 0,
+  `steps`        TEXT         NOT NULL,
+  `result`       MEDIUMTEXT   NOT NULL,
+  `error`        TEXT         NOT NULL,
+  `create_time`  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `status_time`  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`operation_id`),
+  KEY `operation_status_idx` (`status`, `create_time`)
+);
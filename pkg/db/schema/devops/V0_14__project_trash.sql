@@ -0,0 +1,2 @@
+ALTER TABLE `project`
+  ADD COLUMN `delete_time` TIMESTAMP NULL DEFAULT NULL;
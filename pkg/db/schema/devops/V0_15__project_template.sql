@@ -0,0 +1,11 @@
+CREATE TABLE `project_template` (
+  `template_id` VARCHAR(50) NOT NULL,
+  `name`        VARCHAR(50) NOT NULL,
+  `description` TEXT        NOT NULL,
+  `content`     MEDIUMTEXT  NOT NULL,
+  `creator`     VARCHAR(50) NOT NULL,
+  `create_time` TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `status_time` TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`template_id`),
+  UNIQUE KEY `project_template_name_idx` (`name`)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE `project_quota` (
+  `project_id`          VARCHAR(50) NOT NULL,
+  `max_pipelines`       INT         NOT NULL DEFAULT 0,
+  `max_credentials`     INT         NOT NULL DEFAULT 0,
+  `max_members`         INT         NOT NULL DEFAULT 0,
+  `max_concurrent_runs` INT         NOT NULL DEFAULT 0,
+  `max_retained_builds` INT         NOT NULL DEFAULT 0,
+  `updater`             VARCHAR(50) NOT NULL DEFAULT '',
+  `status_time`         TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`project_id`)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE `project_label` (
+  `project_id`  VARCHAR(50)  NOT NULL,
+  `label_key`   VARCHAR(317) NOT NULL,
+  `label_value` VARCHAR(63)  NOT NULL DEFAULT '',
+  PRIMARY KEY (`project_id`, `label_key`),
+  KEY `project_label_idx` (`label_key`, `label_value`)
+);
+
+CREATE TABLE `project_annotation` (
+  `project_id`       VARCHAR(50)  NOT NULL,
+  `annotation_key`   VARCHAR(317) NOT NULL,
+  `annotation_value` TEXT         NOT NULL,
+  PRIMARY KEY (`project_id`, `annotation_key`)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE `operation`
+  ADD COLUMN `owner`          VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN `heartbeat_time` TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP;
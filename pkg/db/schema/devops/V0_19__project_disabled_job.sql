@@ -0,0 +1,7 @@
+CREATE TABLE `project_disabled_job` (
+  `project_id`  VARCHAR(50)  NOT NULL,
+  `job_name`    VARCHAR(255) NOT NULL,
+  `reason`      VARCHAR(50)  NOT NULL,
+  `create_time` TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`project_id`, `job_name`)
+);
@@ -0,0 +1,7 @@
+ALTER TABLE `project_credential`
+  ADD COLUMN `key_fingerprint` VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN `key_algorithm`   VARCHAR(50)  NOT NULL DEFAULT '',
+  ADD COLUMN `not_after`       TIMESTAMP    NULL DEFAULT NULL,
+  ADD COLUMN `rotate_time`     TIMESTAMP    NULL DEFAULT NULL;
+
+CREATE INDEX `project_credential_not_after_idx` ON `project_credential` (`not_after`);
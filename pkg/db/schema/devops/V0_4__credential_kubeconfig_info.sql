@@ -0,0 +1,7 @@
+ALTER TABLE `project_credential`
+  ADD COLUMN `kubeconfig_info` TEXT;
+
+UPDATE `project_credential` SET `kubeconfig_info` = '';
+
+ALTER TABLE `project_credential`
+  MODIFY COLUMN `kubeconfig_info` TEXT NOT NULL;
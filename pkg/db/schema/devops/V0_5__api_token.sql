@@ -0,0 +1,16 @@
+CREATE TABLE `api_token` (
+  `token_id`       VARCHAR(50)  NOT NULL,
+  `name`           VARCHAR(255) NOT NULL,
+  `type`           VARCHAR(50)  NOT NULL,
+  `owner`          VARCHAR(50)  NOT NULL,
+  `project_id`     VARCHAR(50)  NOT NULL DEFAULT '',
+  `role`           VARCHAR(50)  NOT NULL DEFAULT '',
+  `token_hash`     VARCHAR(64)  NOT NULL,
+  `status`         VARCHAR(50)  NOT NULL,
+  `create_time`    TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expire_time`    TIMESTAMP    NULL DEFAULT NULL,
+  `last_used_time` TIMESTAMP    NULL DEFAULT NULL,
+  PRIMARY KEY (`token_id`),
+  UNIQUE KEY `api_token_hash_idx` (`token_hash`),
+  KEY `api_token_owner_idx` (`owner`)
+);
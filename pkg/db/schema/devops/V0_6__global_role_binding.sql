@@ -0,0 +1,7 @@
+CREATE TABLE `global_role_binding` (
+  `username`    VARCHAR(50) NOT NULL,
+  `role`        VARCHAR(50) NOT NULL,
+  `grant_by`    VARCHAR(50) NOT NULL,
+  `create_time` TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`username`, `role`)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE `project_role` (
+  `project_id`          VARCHAR(50) NOT NULL,
+  `name`                VARCHAR(50) NOT NULL,
+  `description`         TEXT        NOT NULL,
+  `permissions`         TEXT        NOT NULL,
+  `jenkins_permissions` TEXT        NOT NULL,
+  `creator`             VARCHAR(50) NOT NULL,
+  `create_time`         TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`project_id`, `name`)
+);
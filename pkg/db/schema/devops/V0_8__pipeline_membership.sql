@@ -0,0 +1,9 @@
+CREATE TABLE `pipeline_membership` (
+  `project_id`  VARCHAR(50)  NOT NULL,
+  `pipeline_id` VARCHAR(255) NOT NULL,
+  `username`    VARCHAR(50)  NOT NULL,
+  `role`        VARCHAR(50)  NOT NULL,
+  `grant_by`    VARCHAR(50)  NOT NULL,
+  `create_time` TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`project_id`, `pipeline_id`, `username`)
+);
@@ -0,0 +1,28 @@
+CREATE TABLE `user_group` (
+  `group_id`    VARCHAR(50)  NOT NULL,
+  `name`        VARCHAR(255) NOT NULL,
+  `description` TEXT         NOT NULL,
+  `source`      VARCHAR(50)  NOT NULL,
+  `external_id` VARCHAR(255) NOT NULL DEFAULT '',
+  `creator`     VARCHAR(50)  NOT NULL,
+  `create_time` TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`group_id`),
+  KEY `user_group_external_id_idx` (`source`, `external_id`)
+);
+
+CREATE TABLE `group_membership` (
+  `group_id`    VARCHAR(50) NOT NULL,
+  `username`    VARCHAR(50) NOT NULL,
+  `create_time` TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`group_id`, `username`),
+  KEY `group_membership_username_idx` (`username`)
+);
+
+CREATE TABLE `project_group_binding` (
+  `project_id`  VARCHAR(50) NOT NULL,
+  `group_id`    VARCHAR(50) NOT NULL,
+  `role`        VARCHAR(50) NOT NULL,
+  `grant_by`    VARCHAR(50) NOT NULL,
+  `create_time` TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`project_id`, `group_id`)
+);
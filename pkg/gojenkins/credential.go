@@ -13,6 +13,8 @@ limitations under the License.
 
 package gojenkins
 
+import "encoding/xml"
+
 const SSHCrenditalStaplerClass = "com.cloudbees.jenkins.plugins.sshcredentials.impl.BasicSSHUserPrivateKey"
 const DirectSSHCrenditalStaplerClass = "com.cloudbees.jenkins.plugins.sshcredentials.impl.BasicSSHUserPrivateKey$DirectEntryPrivateKeySource"
 const UsernamePassswordCredentialStaplerClass = "com.cloudbees.plugins.credentials.impl.UsernamePasswordCredentialsImpl"
@@ -20,6 +22,7 @@ const SecretTextCredentialStaplerClass = "org.jenkinsci.plugins.plaincredentials
 const KubeconfigCredentialStaplerClass = "com.microsoft.jenkins.kubernetes.credentials.KubeconfigCredentials"
 const DirectKubeconfigCredentialStaperClass = "com.microsoft.jenkins.kubernetes.credentials.KubeconfigCredentials$DirectEntryKubeconfigSource"
 const GLOBALScope = "GLOBAL"
+const GlobalCredentialDomain = "_"
 
 type CreateSshCredentialRequest struct {
 	Credentials SshCredential `json:"credentials"`
@@ -103,6 +106,37 @@ type CredentialResponse struct {
 	Domain      string `json:"domain"`
 }
 
+type CredentialDomain struct {
+	XMLName        xml.Name                       `xml:"com.cloudbees.plugins.credentials.domains.Domain"`
+	Name           string                         `xml:"name"`
+	Description    string                         `xml:"description"`
+	Specifications CredentialDomainSpecifications `xml:"specifications"`
+}
+
+type CredentialDomainSpecifications struct {
+	Hostnames []*HostnameSpecification `xml:"com.cloudbees.plugins.credentials.domains.HostnameSpecification"`
+	Schemes   []*SchemeSpecification   `xml:"com.cloudbees.plugins.credentials.domains.SchemeSpecification"`
+}
+
+type HostnameSpecification struct {
+	Includes string `xml:"includes"`
+	Excludes string `xml:"excludes"`
+}
+
+type SchemeSpecification struct {
+	Schemes string `xml:"schemes"`
+}
+
+type CredentialDomainResponse struct {
+	Name        string `json:"urlName"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description"`
+	Global      bool   `json:"global"`
+	Credentials []*struct {
+		Id string `json:"id"`
+	} `json:"credentials"`
+}
+
 func NewCreateSshCredentialRequest(id, username, passphrase, privateKey, description string) *CreateSshCredentialRequest {
 
 	keySource := PrivateKeySource{
@@ -17,6 +17,7 @@ package gojenkins
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"log"
@@ -891,6 +892,129 @@ func (j *Jenkins) DeleteCredentialInFolder(domain, id string, folders ...string)
 	return &id, nil
 }
 
+func (j *Jenkins) CreateCredentialDomainInFolder(domain *CredentialDomain, folders ...string) (*string, error) {
+	prePath := ""
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("folder name shoud not be nil")
+	}
+	for _, folder := range folders {
+		prePath = prePath + fmt.Sprintf("/job/%s", folder)
+	}
+	config, err := xml.Marshal(domain)
+	if err != nil {
+		return nil, err
+	}
+	response, err := j.Requester.PostXML(prePath+"/credentials/store/folder/createDomain",
+		string(config), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(strconv.Itoa(response.StatusCode))
+	}
+	return &domain.Name, nil
+}
+
+func (j *Jenkins) UpdateCredentialDomainInFolder(domain *CredentialDomain, folders ...string) (*string, error) {
+	prePath := ""
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("folder name shoud not be nil")
+	}
+	for _, folder := range folders {
+		prePath = prePath + fmt.Sprintf("/job/%s", folder)
+	}
+	config, err := xml.Marshal(domain)
+	if err != nil {
+		return nil, err
+	}
+	response, err := j.Requester.PostXML(prePath+
+		fmt.Sprintf("/credentials/store/folder/domain/%s/config.xml", domain.Name),
+		string(config), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(strconv.Itoa(response.StatusCode))
+	}
+	return &domain.Name, nil
+}
+
+func (j *Jenkins) GetCredentialDomainInFolder(domain string, folders ...string) (*CredentialDomain, error) {
+	prePath := ""
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("folder name shoud not be nil")
+	}
+	for _, folder := range folders {
+		prePath = prePath + fmt.Sprintf("/job/%s", folder)
+	}
+	config := ""
+	response, err := j.Requester.GetXML(prePath+
+		fmt.Sprintf("/credentials/store/folder/domain/%s/config.xml", domain),
+		&config, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(strconv.Itoa(response.StatusCode))
+	}
+	credentialDomain := &CredentialDomain{}
+	err = xml.Unmarshal([]byte(config), credentialDomain)
+	if err != nil {
+		return nil, err
+	}
+	return credentialDomain, nil
+}
+
+func (j *Jenkins) GetCredentialDomainsInFolder(folders ...string) ([]*CredentialDomainResponse, error) {
+	prePath := ""
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("folder name shoud not be nil")
+	}
+	for _, folder := range folders {
+		prePath = prePath + fmt.Sprintf("/job/%s", folder)
+	}
+	var responseStruct = &struct {
+		Domains map[string]*CredentialDomainResponse `json:"domains"`
+	}{}
+	response, err := j.Requester.GetJSON(prePath+
+		"/credentials/store/folder/",
+		responseStruct, map[string]string{
+			"depth": "2",
+		})
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(strconv.Itoa(response.StatusCode))
+	}
+	responseArray := make([]*CredentialDomainResponse, 0)
+	for domainName, domain := range responseStruct.Domains {
+		domain.Name = domainName
+		responseArray = append(responseArray, domain)
+	}
+	return responseArray, nil
+}
+
+func (j *Jenkins) DeleteCredentialDomainInFolder(domain string, folders ...string) (*string, error) {
+	prePath := ""
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("folder name shoud not be nil")
+	}
+	for _, folder := range folders {
+		prePath = prePath + fmt.Sprintf("/job/%s", folder)
+	}
+	response, err := j.Requester.Post(prePath+
+		fmt.Sprintf("/credentials/store/folder/domain/%s/doDelete", domain),
+		nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(strconv.Itoa(response.StatusCode))
+	}
+	return &domain, nil
+}
+
 func (j *Jenkins) GetGlobalRole(roleName string) (*GlobalRole, error) {
 	roleResponse := &GlobalRoleResponse{
 		RoleName: roleName,
@@ -228,3 +228,41 @@ func (n *Node) GetLogText() (string, error) {
 
 	return log, nil
 }
+
+type executorResponse struct {
+	CurrentExecutable *struct {
+		URL string `json:"url"`
+	} `json:"currentExecutable"`
+}
+
+type busyComputersResponse struct {
+	Computers []struct {
+		Executors       []executorResponse `json:"executors"`
+		OneOffExecutors []executorResponse `json:"oneOffExecutors"`
+	} `json:"computer"`
+}
+
+// GetRunningBuildUrls returns the urls of the builds running on all nodes,
+// pipelines run on one-off executors and their node blocks on the executors of agents.
+func (j *Jenkins) GetRunningBuildUrls() ([]string, error) {
+	computers := new(busyComputersResponse)
+	qr := map[string]string{
+		"tree": "computer[executors[currentExecutable[url]],oneOffExecutors[currentExecutable[url]]]",
+	}
+	_, err := j.Requester.GetJSON("/computer", computers, qr)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, computer := range computers.Computers {
+		for _, executor := range append(computer.Executors, computer.OneOffExecutors...) {
+			if executor.CurrentExecutable == nil || seen[executor.CurrentExecutable.URL] {
+				continue
+			}
+			seen[executor.CurrentExecutable.URL] = true
+			urls = append(urls, executor.CurrentExecutable.URL)
+		}
+	}
+	return urls, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/utils/idutils"
+)
+
+const (
+	ApiTokenTableName          = "api_token"
+	ApiTokenPrefix             = "token-"
+	ApiTokenIdColumn           = "token_id"
+	ApiTokenOwnerColumn        = "owner"
+	ApiTokenTypeColumn         = "type"
+	ApiTokenHashColumn         = "token_hash"
+	ApiTokenLastUsedTimeColumn = "last_used_time"
+)
+
+const (
+	ApiTokenTypePersonal       = "personal"
+	ApiTokenTypeServiceAccount = "service_account"
+)
+
+// ApiToken only keeps the hash of the token secret.
+type ApiToken struct {
+	TokenId      string     `json:"token_id" db:"token_id"`
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	Owner        string     `json:"owner"`
+	ProjectId    string     `json:"project_id,omitempty" db:"project_id"`
+	Role         string     `json:"role,omitempty"`
+	TokenHash    string     `json:"-"`
+	Status       string     `json:"status"`
+	CreateTime   time.Time  `json:"create_time"`
+	ExpireTime   *time.Time `json:"expire_time,omitempty"`
+	LastUsedTime *time.Time `json:"last_used_time,omitempty"`
+}
+
+var ApiTokenColumns = GetColumnsFromStruct(&ApiToken{})
+
+func NewApiToken(name, tokenType, owner, projectId, role, tokenHash string, expireTime *time.Time) *ApiToken {
+	return &ApiToken{
+		TokenId:    idutils.GetUuid(ApiTokenPrefix),
+		Name:       name,
+		Type:       tokenType,
+		Owner:      owner,
+		ProjectId:  projectId,
+		Role:       role,
+		TokenHash:  tokenHash,
+		Status:     constants.StatusActive,
+		CreateTime: time.Now(),
+		ExpireTime: expireTime,
+	}
+}
@@ -7,17 +7,29 @@ import (
 )
 
 const (
-	ProjectCredentialTableName    = "project_credential"
-	ProjectCredentialIdColumn     = "credential_id"
-	ProjectCredentialDomainColumn = "domain"
+	ProjectCredentialTableName            = "project_credential"
+	ProjectCredentialIdColumn             = "credential_id"
+	ProjectCredentialDomainColumn         = "domain"
+	ProjectCredentialCreatorColumn        = "creator"
+	ProjectCredentialCreateTimeColumn     = "create_time"
+	ProjectCredentialKeyFingerprintColumn = "key_fingerprint"
+	ProjectCredentialKeyAlgorithmColumn   = "key_algorithm"
+	ProjectCredentialNotAfterColumn       = "not_after"
+	ProjectCredentialRotateTimeColumn     = "rotate_time"
+	ProjectCredentialKubeconfigInfoColumn = "kubeconfig_info"
 )
 
 type ProjectCredential struct {
-	ProjectId    string    `json:"project_id"`
-	CredentialId string    `json:"credential_id"`
-	Domain       string    `json:"domain"`
-	Creator      string    `json:"creator"`
-	CreateTime   time.Time `json:"create_time"`
+	ProjectId      string     `json:"project_id"`
+	CredentialId   string     `json:"credential_id"`
+	Domain         string     `json:"domain"`
+	Creator        string     `json:"creator"`
+	CreateTime     time.Time  `json:"create_time"`
+	KeyFingerprint string     `json:"key_fingerprint,omitempty"`
+	KeyAlgorithm   string     `json:"key_algorithm,omitempty"`
+	NotAfter       *time.Time `json:"not_after,omitempty"`
+	RotateTime     *time.Time `json:"rotate_time,omitempty"`
+	KubeconfigInfo string     `json:"-"`
 }
 
 var ProjectCredentialColumns = GetColumnsFromStruct(&ProjectCredential{})
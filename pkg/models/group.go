@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"kubesphere.io/devops/pkg/utils/idutils"
+)
+
+const (
+	GroupTableName         = "user_group"
+	GroupPrefix            = "group-"
+	GroupIdColumn          = "group_id"
+	GroupNameColumn        = "name"
+	GroupDescriptionColumn = "description"
+	GroupSourceColumn      = "source"
+	GroupExternalIdColumn  = "external_id"
+
+	GroupMembershipTableName      = "group_membership"
+	GroupMembershipUsernameColumn = "username"
+
+	ProjectGroupBindingTableName  = "project_group_binding"
+	ProjectGroupBindingRoleColumn = "role"
+)
+
+const (
+	GroupSourceLocal = "local"
+	GroupSourceLdap  = "ldap"
+)
+
+// Group is a set of users, ldap groups use their dn as external id.
+type Group struct {
+	GroupId     string    `json:"group_id" db:"group_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Source      string    `json:"source"`
+	ExternalId  string    `json:"external_id,omitempty"`
+	Creator     string    `json:"creator"`
+	CreateTime  time.Time `json:"create_time"`
+}
+
+var GroupColumns = GetColumnsFromStruct(&Group{})
+
+func NewGroup(name, description, source, externalId, creator string) *Group {
+	return &Group{
+		GroupId:     idutils.GetUuid(GroupPrefix),
+		Name:        name,
+		Description: description,
+		Source:      source,
+		ExternalId:  externalId,
+		Creator:     creator,
+		CreateTime:  time.Now(),
+	}
+}
+
+type GroupMembership struct {
+	GroupId    string    `json:"group_id" db:"group_id"`
+	Username   string    `json:"username"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+var GroupMembershipColumns = GetColumnsFromStruct(&GroupMembership{})
+
+func NewGroupMembership(groupId, username string) *GroupMembership {
+	return &GroupMembership{
+		GroupId:    groupId,
+		Username:   username,
+		CreateTime: time.Now(),
+	}
+}
+
+// ProjectGroupBinding grants a project role to every member of a group.
+type ProjectGroupBinding struct {
+	ProjectId  string    `json:"project_id" db:"project_id"`
+	GroupId    string    `json:"group_id" db:"group_id"`
+	Role       string    `json:"role"`
+	GrantBy    string    `json:"grant_by"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+var ProjectGroupBindingColumns = GetColumnsFromStruct(&ProjectGroupBinding{})
+
+func NewProjectGroupBinding(projectId, groupId, role, grantBy string) *ProjectGroupBinding {
+	return &ProjectGroupBinding{
+		ProjectId:  projectId,
+		GroupId:    groupId,
+		Role:       role,
+		GrantBy:    grantBy,
+		CreateTime: time.Now(),
+	}
+}
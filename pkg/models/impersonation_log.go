@@ -0,0 +1,60 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"kubesphere.io/devops/pkg/utils/idutils"
+)
+
+const (
+	ImpersonationLogTableName        = "impersonation_log"
+	ImpersonationLogPrefix           = "imp-"
+	ImpersonationLogAdminColumn      = "admin"
+	ImpersonationLogUsernameColumn   = "username"
+	ImpersonationLogCreateTimeColumn = "create_time"
+)
+
+const (
+	ImpersonationOutcomeRefused   = "refused"
+	ImpersonationOutcomeSucceeded = "succeeded"
+	ImpersonationOutcomeFailed    = "failed"
+)
+
+type ImpersonationLog struct {
+	LogId      string    `json:"log_id" db:"log_id"`
+	Admin      string    `json:"admin"`
+	Username   string    `json:"username"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	StatusCode int       `json:"status_code"`
+	Outcome    string    `json:"outcome"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+var ImpersonationLogColumns = GetColumnsFromStruct(&ImpersonationLog{})
+
+func NewImpersonationLog(admin, username, method, path string, statusCode int, outcome string) *ImpersonationLog {
+	return &ImpersonationLog{
+		LogId:      idutils.GetUuid(ImpersonationLogPrefix),
+		Admin:      admin,
+		Username:   username,
+		Method:     method,
+		Path:       path,
+		StatusCode: statusCode,
+		Outcome:    outcome,
+		CreateTime: time.Now(),
+	}
+}
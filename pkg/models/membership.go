@@ -13,13 +13,18 @@ limitations under the License.
 
 package models
 
-import "kubesphere.io/devops/pkg/constants"
+import (
+	"time"
+
+	"kubesphere.io/devops/pkg/constants"
+)
 
 const (
-	ProjectMembershipTableName       = "project_membership"
-	ProjectMembershipUsernameColumn  = "username"
-	ProjectMembershipProjectIdColumn = "project_id"
-	ProjectMembershipRoleColumn      = "role"
+	ProjectMembershipTableName        = "project_membership"
+	ProjectMembershipUsernameColumn   = "username"
+	ProjectMembershipProjectIdColumn  = "project_id"
+	ProjectMembershipRoleColumn       = "role"
+	ProjectMembershipExpireTimeColumn = "expire_time"
 )
 
 type ProjectMembership struct {
@@ -28,6 +33,8 @@ type ProjectMembership struct {
 	Role      string `json:"role"`
 	Status    string `json:"status"`
 	GrantBy   string `json:"grand_by,omitempty"`
+	// ExpireTime is the expire time of the invitation of a pending membership
+	ExpireTime *time.Time `json:"expire_time,omitempty"`
 }
 
 var ProjectMembershipColumns = GetColumnsFromStruct(&ProjectMembership{})
@@ -41,3 +48,15 @@ func NewProjectMemberShip(username, projectId, role, grantBy string) *ProjectMem
 		GrantBy:   grantBy,
 	}
 }
+
+// NewProjectInvitation returns a pending membership which becomes active once the invitee accepts it.
+func NewProjectInvitation(username, projectId, role, grantBy string, expireTime time.Time) *ProjectMembership {
+	return &ProjectMembership{
+		Username:   username,
+		ProjectId:  projectId,
+		Role:       role,
+		Status:     constants.StatusPending,
+		GrantBy:    grantBy,
+		ExpireTime: &expireTime,
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/utils/idutils"
+)
+
+const (
+	OperationTableName        = "operation"
+	OperationPrefix           = "op-"
+	OperationIdColumn         = "operation_id"
+	OperationStatusCodeColumn = "status_code"
+	OperationStepsColumn      = "steps"
+	OperationResultColumn     = "result"
+	OperationErrorColumn      = "error"
+	OperationCreateTimeColumn = "create_time"
+	OperationOwnerColumn      = "owner"
+	OperationHeartbeatColumn  = "heartbeat_time"
+)
+
+// Operation is a mutating request executed by the operation workers,
+// Steps and Result are json strings. Owner is the instance executing the operation,
+// it updates HeartbeatTime while the operation is working.
+type Operation struct {
+	OperationId   string    `json:"operation_id" db:"operation_id"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	Body          string    `json:"-"`
+	Operator      string    `json:"operator"`
+	Impersonator  string    `json:"impersonator,omitempty"`
+	Status        string    `json:"status"`
+	StatusCode    int       `json:"status_code"`
+	Steps         string    `json:"-"`
+	Result        string    `json:"-"`
+	Error         string    `json:"error,omitempty"`
+	CreateTime    time.Time `json:"create_time"`
+	StatusTime    time.Time `json:"status_time"`
+	Owner         string    `json:"-"`
+	HeartbeatTime time.Time `json:"-"`
+}
+
+var OperationColumns = GetColumnsFromStruct(&Operation{})
+
+func NewOperation(method, path, body, operator, impersonator string) *Operation {
+	now := time.Now()
+	return &Operation{
+		OperationId:   idutils.GetUuid(OperationPrefix),
+		Method:        method,
+		Path:          path,
+		Body:          body,
+		Operator:      operator,
+		Impersonator:  impersonator,
+		Status:        constants.StatusPending,
+		Steps:         "[]",
+		CreateTime:    now,
+		StatusTime:    now,
+		HeartbeatTime: now,
+	}
+}
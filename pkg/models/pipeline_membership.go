@@ -0,0 +1,44 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "time"
+
+const (
+	PipelineMembershipTableName        = "pipeline_membership"
+	PipelineMembershipPipelineIdColumn = "pipeline_id"
+)
+
+// PipelineMembership binds a project member to a role of a single pipeline.
+type PipelineMembership struct {
+	ProjectId  string    `json:"project_id" db:"project_id"`
+	PipelineId string    `json:"pipeline_id" db:"pipeline_id"`
+	Username   string    `json:"username"`
+	Role       string    `json:"role"`
+	GrantBy    string    `json:"grant_by"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+var PipelineMembershipColumns = GetColumnsFromStruct(&PipelineMembership{})
+
+func NewPipelineMembership(projectId, pipelineId, username, role, grantBy string) *PipelineMembership {
+	return &PipelineMembership{
+		ProjectId:  projectId,
+		PipelineId: pipelineId,
+		Username:   username,
+		Role:       role,
+		GrantBy:    grantBy,
+		CreateTime: time.Now(),
+	}
+}
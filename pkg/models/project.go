@@ -28,6 +28,8 @@ const (
 	ProjectDescriptionColumn = "description"
 	ProjectIdColumn          = "project_id"
 	ProjectExtraColumn       = "extra"
+	ProjectVisibilityColumn  = "visibility"
+	ProjectDeleteTimeColumn  = "delete_time"
 )
 
 type Project struct {
@@ -39,6 +41,8 @@ type Project struct {
 	Status      string    `json:"status"`
 	Visibility  string    `json:"visibility"`
 	Extra       string    `json:"extra"`
+	// DeleteTime is the time the project was moved to the trash
+	DeleteTime *time.Time `json:"delete_time,omitempty"`
 }
 
 func NewProject(name, description, creator, extra string) *Project {
@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"kubesphere.io/devops/pkg/constants"
+)
+
+const (
+	ProjectCreationStepTableName     = "project_creation_step"
+	ProjectCreationStepStepColumn    = "step"
+	ProjectCreationStepSeqColumn     = "seq"
+	ProjectCreationStepMessageColumn = "message"
+)
+
+// ProjectCreationStep records the progress of a step of the project creation,
+// the steps of a failed creation are rolled back in reverse order.
+type ProjectCreationStep struct {
+	ProjectId  string    `json:"project_id" db:"project_id"`
+	Step       string    `json:"step"`
+	Seq        int       `json:"seq"`
+	Status     string    `json:"status"`
+	Message    string    `json:"message"`
+	StatusTime time.Time `json:"status_time"`
+}
+
+var ProjectCreationStepColumns = GetColumnsFromStruct(&ProjectCreationStep{})
+
+func NewProjectCreationStep(projectId, step string, seq int) *ProjectCreationStep {
+	return &ProjectCreationStep{
+		ProjectId:  projectId,
+		Step:       step,
+		Seq:        seq,
+		Status:     constants.StatusPending,
+		StatusTime: time.Now(),
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "time"
+
+const (
+	ProjectDisabledJobTableName    = "project_disabled_job"
+	ProjectDisabledJobNameColumn   = "job_name"
+	ProjectDisabledJobReasonColumn = "reason"
+)
+
+// ProjectDisabledJob is a job disabled by the api, only these jobs are enabled again
+// so jobs disabled by the team are kept disabled.
+type ProjectDisabledJob struct {
+	ProjectId  string    `json:"project_id" db:"project_id"`
+	JobName    string    `json:"job_name"`
+	Reason     string    `json:"reason"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+var ProjectDisabledJobColumns = GetColumnsFromStruct(&ProjectDisabledJob{})
+
+func NewProjectDisabledJob(projectId, jobName, reason string) *ProjectDisabledJob {
+	return &ProjectDisabledJob{
+		ProjectId:  projectId,
+		JobName:    jobName,
+		Reason:     reason,
+		CreateTime: time.Now(),
+	}
+}
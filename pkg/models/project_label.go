@@ -0,0 +1,55 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+const (
+	ProjectLabelTableName      = "project_label"
+	ProjectLabelKeyColumn      = "label_key"
+	ProjectLabelValueColumn    = "label_value"
+	ProjectAnnotationTableName = "project_annotation"
+)
+
+// ProjectLabel is a key/value label of a project, projects are selected by labels.
+type ProjectLabel struct {
+	ProjectId  string `json:"project_id" db:"project_id"`
+	LabelKey   string `json:"label_key"`
+	LabelValue string `json:"label_value"`
+}
+
+var ProjectLabelColumns = GetColumnsFromStruct(&ProjectLabel{})
+
+func NewProjectLabel(projectId, key, value string) *ProjectLabel {
+	return &ProjectLabel{
+		ProjectId:  projectId,
+		LabelKey:   key,
+		LabelValue: value,
+	}
+}
+
+// ProjectAnnotation is a non-identifying metadata of a project, it is not indexed.
+type ProjectAnnotation struct {
+	ProjectId       string `json:"project_id" db:"project_id"`
+	AnnotationKey   string `json:"annotation_key"`
+	AnnotationValue string `json:"annotation_value"`
+}
+
+var ProjectAnnotationColumns = GetColumnsFromStruct(&ProjectAnnotation{})
+
+func NewProjectAnnotation(projectId, key, value string) *ProjectAnnotation {
+	return &ProjectAnnotation{
+		ProjectId:       projectId,
+		AnnotationKey:   key,
+		AnnotationValue: value,
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "time"
+
+const (
+	ProjectQuotaTableName = "project_quota"
+)
+
+// ProjectQuota limits the resources of a project, a limit of 0 means unlimited.
+type ProjectQuota struct {
+	ProjectId         string    `json:"project_id" db:"project_id"`
+	MaxPipelines      int       `json:"max_pipelines"`
+	MaxCredentials    int       `json:"max_credentials"`
+	MaxMembers        int       `json:"max_members"`
+	MaxConcurrentRuns int       `json:"max_concurrent_runs"`
+	MaxRetainedBuilds int       `json:"max_retained_builds"`
+	Updater           string    `json:"updater"`
+	StatusTime        time.Time `json:"status_time"`
+}
+
+var ProjectQuotaColumns = GetColumnsFromStruct(&ProjectQuota{})
+
+func NewProjectQuota(projectId, updater string) *ProjectQuota {
+	return &ProjectQuota{
+		ProjectId:  projectId,
+		Updater:    updater,
+		StatusTime: time.Now(),
+	}
+}
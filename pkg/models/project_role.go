@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "time"
+
+const (
+	ProjectRoleTableName                = "project_role"
+	ProjectRoleNameColumn               = "name"
+	ProjectRoleDescriptionColumn        = "description"
+	ProjectRolePermissionsColumn        = "permissions"
+	ProjectRoleJenkinsPermissionsColumn = "jenkins_permissions"
+)
+
+// ProjectRole is a custom role of a project,
+// permissions and jenkins permissions are stored as json.
+type ProjectRole struct {
+	ProjectId          string    `json:"project_id" db:"project_id"`
+	Name               string    `json:"name"`
+	Description        string    `json:"description"`
+	Permissions        string    `json:"-"`
+	JenkinsPermissions string    `json:"-"`
+	Creator            string    `json:"creator"`
+	CreateTime         time.Time `json:"create_time"`
+}
+
+var ProjectRoleColumns = GetColumnsFromStruct(&ProjectRole{})
+
+func NewProjectRole(projectId, name, description, permissions, jenkinsPermissions, creator string) *ProjectRole {
+	return &ProjectRole{
+		ProjectId:          projectId,
+		Name:               name,
+		Description:        description,
+		Permissions:        permissions,
+		JenkinsPermissions: jenkinsPermissions,
+		Creator:            creator,
+		CreateTime:         time.Now(),
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"kubesphere.io/devops/pkg/utils/idutils"
+)
+
+const (
+	ProjectTemplateTableName         = "project_template"
+	ProjectTemplatePrefix            = "template-"
+	ProjectTemplateIdColumn          = "template_id"
+	ProjectTemplateNameColumn        = "name"
+	ProjectTemplateDescriptionColumn = "description"
+	ProjectTemplateContentColumn     = "content"
+	ProjectTemplateStatusTimeColumn  = "status_time"
+)
+
+// ProjectTemplate is the starter setup of new projects managed by platform admins,
+// Content is a json string.
+type ProjectTemplate struct {
+	TemplateId  string    `json:"template_id" db:"template_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Content     string    `json:"-"`
+	Creator     string    `json:"creator"`
+	CreateTime  time.Time `json:"create_time"`
+	StatusTime  time.Time `json:"status_time"`
+}
+
+var ProjectTemplateColumns = GetColumnsFromStruct(&ProjectTemplate{})
+
+func NewProjectTemplate(name, description, content, creator string) *ProjectTemplate {
+	now := time.Now()
+	return &ProjectTemplate{
+		TemplateId:  idutils.GetUuid(ProjectTemplatePrefix),
+		Name:        name,
+		Description: description,
+		Content:     content,
+		Creator:     creator,
+		CreateTime:  now,
+		StatusTime:  now,
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/config"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/service/projects"
+	"kubesphere.io/devops/pkg/utils/jwtutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+const (
+	AuthModeJwt    = "jwt"
+	AuthModeHeader = "header"
+
+	jwtLeeway = time.Minute
+)
+
+// ApiTokenAuthenticator resolves an api token to the username of its identity.
+type ApiTokenAuthenticator interface {
+	AuthenticateApiToken(token string) (string, error)
+}
+
+// ApiTokenAuthMiddleware authenticates requests with api tokens,
+// other requests are passed to the next auth middleware.
+type ApiTokenAuthMiddleware struct {
+	Authenticator ApiTokenAuthenticator
+}
+
+func (mw *ApiTokenAuthMiddleware) MiddlewareFunc(handler rest.HandlerFunc) rest.HandlerFunc {
+	return func(w rest.ResponseWriter, r *rest.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !projects.IsApiToken(token) {
+			handler(w, r)
+			return
+		}
+		username, err := mw.Authenticator.AuthenticateApiToken(token)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+		r.Env[userutils.RemoteUserEnv] = username
+		handler(w, r)
+	}
+}
+
+// JwtAuthMiddleware authenticates requests with a bearer token.
+type JwtAuthMiddleware struct {
+	Verifier      *jwtutils.Verifier
+	UsernameClaim string
+}
+
+func (mw *JwtAuthMiddleware) MiddlewareFunc(handler rest.HandlerFunc) rest.HandlerFunc {
+	return func(w rest.ResponseWriter, r *rest.Request) {
+		if authenticated(r) {
+			handler(w, r)
+			return
+		}
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "Bearer ") {
+			unauthorized(w, fmt.Errorf("bearer token is required"))
+			return
+		}
+		claims, err := mw.Verifier.Verify(strings.TrimPrefix(authorization, "Bearer "))
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+		username := claims.String(mw.UsernameClaim)
+		if username == "" {
+			unauthorized(w, fmt.Errorf("token has no claim [%s]", mw.UsernameClaim))
+			return
+		}
+		r.Env[userutils.RemoteUserEnv] = username
+		handler(w, r)
+	}
+}
+
+// HeaderAuthMiddleware trusts the username header set by an authenticating proxy.
+type HeaderAuthMiddleware struct {
+	Header string
+}
+
+func (mw *HeaderAuthMiddleware) MiddlewareFunc(handler rest.HandlerFunc) rest.HandlerFunc {
+	return func(w rest.ResponseWriter, r *rest.Request) {
+		if authenticated(r) {
+			handler(w, r)
+			return
+		}
+		username := r.Header.Get(mw.Header)
+		if username == "" {
+			unauthorized(w, fmt.Errorf("header [%s] is required", mw.Header))
+			return
+		}
+		r.Env[userutils.RemoteUserEnv] = username
+		handler(w, r)
+	}
+}
+
+func authenticated(r *rest.Request) bool {
+	_, ok := r.Env[userutils.RemoteUserEnv]
+	return ok
+}
+
+func unauthorized(w rest.ResponseWriter, err error) {
+	logger.Warn("failed to authenticate request, %+v", err)
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	rest.Error(w, err.Error(), http.StatusUnauthorized)
+}
+
+// NewAuthMiddleware creates the middleware of the configured auth mode,
+// jwt mode needs at least one key.
+func NewAuthMiddleware(cfg *config.AuthConfig) (rest.Middleware, error) {
+	switch cfg.Mode {
+	case AuthModeHeader:
+		logger.Warn("header auth mode is enabled, header [%s] is trusted without verification", cfg.UsernameHeader)
+		return &HeaderAuthMiddleware{Header: cfg.UsernameHeader}, nil
+	case AuthModeJwt:
+		verifier := &jwtutils.Verifier{
+			Issuer: cfg.JwtIssuer,
+			Leeway: jwtLeeway,
+		}
+		if cfg.JwtSecret != "" {
+			verifier.HMACSecret = []byte(cfg.JwtSecret)
+		}
+		if cfg.JwtPublicKeyFile != "" {
+			data, err := ioutil.ReadFile(cfg.JwtPublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			key, err := jwtutils.ParseRSAPublicKey(data)
+			if err != nil {
+				return nil, err
+			}
+			verifier.RSAKeys = map[string]*rsa.PublicKey{"": key}
+		}
+		if cfg.JwksFile != "" {
+			data, err := ioutil.ReadFile(cfg.JwksFile)
+			if err != nil {
+				return nil, err
+			}
+			keys, err := jwtutils.ParseJWKS(data)
+			if err != nil {
+				return nil, err
+			}
+			if verifier.RSAKeys == nil {
+				verifier.RSAKeys = keys
+			} else {
+				for kid, key := range keys {
+					verifier.RSAKeys[kid] = key
+				}
+			}
+		}
+		if len(verifier.HMACSecret) == 0 && len(verifier.RSAKeys) == 0 {
+			return nil, fmt.Errorf("jwt auth mode needs a jwt secret, public key file or jwks file")
+		}
+		return &JwtAuthMiddleware{Verifier: verifier, UsernameClaim: cfg.UsernameClaim}, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth mode [%s]", cfg.Mode)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/service/projects"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+const ImpersonateUserHeader = "Impersonate-User"
+
+// ImpersonationAuditor authorizes and records impersonated requests.
+type ImpersonationAuditor interface {
+	CanImpersonate(username string) bool
+	RecordImpersonation(log *models.ImpersonationLog) error
+}
+
+// ImpersonationMiddleware lets platform admins send requests as another user,
+// it must be used after the auth middlewares.
+type ImpersonationMiddleware struct {
+	Auditor     ImpersonationAuditor
+	AllowWrites bool
+}
+
+func readOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func (mw *ImpersonationMiddleware) MiddlewareFunc(handler rest.HandlerFunc) rest.HandlerFunc {
+	recordedHandler := (&rest.RecorderMiddleware{}).MiddlewareFunc(handler)
+	return func(w rest.ResponseWriter, r *rest.Request) {
+		username := r.Header.Get(ImpersonateUserHeader)
+		if username == "" {
+			handler(w, r)
+			return
+		}
+		admin := userutils.GetUserNameFromRequest(r)
+		if !mw.Auditor.CanImpersonate(admin) || projects.IsApiTokenUsername(admin) {
+			err := fmt.Errorf("user [%s] can not impersonate users", admin)
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusForbidden)
+			mw.record(models.NewImpersonationLog(admin, username, r.Method, r.URL.Path,
+				http.StatusForbidden, models.ImpersonationOutcomeRefused))
+			return
+		}
+		if projects.IsApiTokenUsername(username) {
+			err := fmt.Errorf("api token identity [%s] can not be impersonated", username)
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			mw.record(models.NewImpersonationLog(admin, username, r.Method, r.URL.Path,
+				http.StatusBadRequest, models.ImpersonationOutcomeRefused))
+			return
+		}
+		if !mw.AllowWrites && !readOnlyMethod(r.Method) {
+			err := fmt.Errorf("impersonated requests are read-only")
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusForbidden)
+			mw.record(models.NewImpersonationLog(admin, username, r.Method, r.URL.Path,
+				http.StatusForbidden, models.ImpersonationOutcomeRefused))
+			return
+		}
+
+		logger.Info("user [%s] impersonates user [%s], %s %s", admin, username, r.Method, r.URL.Path)
+		r.Env[userutils.RemoteUserEnv] = username
+		r.Env[userutils.ImpersonatorEnv] = admin
+		recordedHandler(w, r)
+		statusCode, _ := r.Env["STATUS_CODE"].(int)
+		outcome := models.ImpersonationOutcomeSucceeded
+		if statusCode >= http.StatusBadRequest {
+			outcome = models.ImpersonationOutcomeFailed
+		}
+		mw.record(models.NewImpersonationLog(admin, username, r.Method, r.URL.Path, statusCode, outcome))
+	}
+}
+
+func (mw *ImpersonationMiddleware) record(log *models.ImpersonationLog) {
+	err := mw.Auditor.RecordImpersonation(log)
+	if err != nil {
+		logger.Error("failed to record impersonation of user [%s] by [%s], %+v", log.Username, log.Admin, err)
+	}
+}
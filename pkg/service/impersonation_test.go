@@ -0,0 +1,93 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type fakeImpersonationAuditor struct {
+	logs []*models.ImpersonationLog
+}
+
+func (a *fakeImpersonationAuditor) CanImpersonate(username string) bool {
+	return username == "admin"
+}
+
+func (a *fakeImpersonationAuditor) RecordImpersonation(log *models.ImpersonationLog) error {
+	a.logs = append(a.logs, log)
+	return nil
+}
+
+func newImpersonationTestHandler(t *testing.T, auditor ImpersonationAuditor) http.Handler {
+	api := rest.NewApi()
+	api.Use(&HeaderAuthMiddleware{Header: "X-Token-Username"}, &ImpersonationMiddleware{Auditor: auditor})
+	router, err := rest.MakeRouter(
+		rest.Get("/whoami", func(w rest.ResponseWriter, r *rest.Request) {
+			w.WriteJson(map[string]string{
+				"username":     userutils.GetUserNameFromRequest(r),
+				"impersonator": userutils.GetImpersonatorFromRequest(r),
+			})
+		}),
+		rest.Post("/whoami", func(w rest.ResponseWriter, r *rest.Request) {
+			w.WriteJson(map[string]string{})
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.SetApp(router)
+	return api.MakeHandler()
+}
+
+func TestImpersonationMiddleware(t *testing.T) {
+	auditor := &fakeImpersonationAuditor{}
+	handler := newImpersonationTestHandler(t, auditor)
+	for _, item := range []struct {
+		method     string
+		username   string
+		statusCode int
+		outcome    string
+	}{
+		{http.MethodGet, "admin", http.StatusOK, models.ImpersonationOutcomeSucceeded},
+		{http.MethodPost, "admin", http.StatusForbidden, models.ImpersonationOutcomeRefused},
+		// failed attempts of non-admins are audited as well
+		{http.MethodGet, "alice", http.StatusForbidden, models.ImpersonationOutcomeRefused},
+	} {
+		auditor.logs = nil
+		request := httptest.NewRequest(item.method, "/whoami", nil)
+		request.Header.Set("X-Token-Username", item.username)
+		request.Header.Set(ImpersonateUserHeader, "bob")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != item.statusCode {
+			t.Fatalf("%s by [%s] should return %d, got %d", item.method, item.username, item.statusCode, recorder.Code)
+		}
+		if len(auditor.logs) != 1 {
+			t.Fatalf("request should be recorded once, got %d", len(auditor.logs))
+		}
+		log := auditor.logs[0]
+		if log.Admin != item.username || log.Username != "bob" || log.Outcome != item.outcome ||
+			log.StatusCode != item.statusCode || log.Path != "/whoami" {
+			t.Fatalf("unexpected impersonation log %+v", log)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/service/projects"
+	"kubesphere.io/devops/pkg/utils/idutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+const (
+	operationPollInterval = 5 * time.Second
+	// working operations without heartbeat for operationHeartbeatTimeout are failed by any instance
+	operationHeartbeatInterval = 30 * time.Second
+	operationHeartbeatTimeout  = 3 * operationHeartbeatInterval
+	// operationRequestStep is the step of operations whose handler does not record steps
+	operationRequestStep = "request"
+)
+
+// asyncRoutes are the mutating routes which can be executed as operations with ?async=true.
+var asyncRoutes = []*regexp.Regexp{
+	regexp.MustCompile(`^POST /projects$`),
+	regexp.MustCompile(`^DELETE /projects/[^/]+$`),
+	regexp.MustCompile(`^POST /projects/[^/]+/members$`),
+	regexp.MustCompile(`^(PATCH|DELETE) /projects/[^/]+/members/[^/]+$`),
+}
+
+func isAsyncRoute(method, path string) bool {
+	route := fmt.Sprintf("%s %s", method, path)
+	for _, asyncRoute := range asyncRoutes {
+		if asyncRoute.MatchString(route) {
+			return true
+		}
+	}
+	return false
+}
+
+// OperationStore persists the operations, the state survives restarts.
+type OperationStore interface {
+	CreateOperation(operation *models.Operation) error
+	ClaimPendingOperation(owner string) (*models.Operation, error)
+	UpdateOperationSteps(operationId string, steps []*projects.OperationStep) error
+	FinishOperation(operationId string, statusCode int, body []byte) error
+	HeartbeatOperations(owner string) error
+	FailInterruptedOperations(owner string, staleBefore time.Time) error
+}
+
+// OperationPool executes the pending operations with a fixed number of workers,
+// each operation replays its request against the api handler as the operator.
+// Owner identifies the instance in the claimed operations, it is the hostname so a restarted
+// instance fails the operations it was executing.
+type OperationPool struct {
+	Store   OperationStore
+	Handler rest.HandlerFunc
+	Workers int
+	Owner   string
+	wakeup  chan struct{}
+}
+
+func operationPoolOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return idutils.GetUuid("instance-")
+	}
+	return hostname
+}
+
+func NewOperationPool(store OperationStore, handler rest.HandlerFunc, workers int) *OperationPool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &OperationPool{
+		Store:   store,
+		Handler: handler,
+		Workers: workers,
+		Owner:   operationPoolOwner(),
+		wakeup:  make(chan struct{}, workers),
+	}
+}
+
+func (p *OperationPool) Start() {
+	err := p.Store.FailInterruptedOperations(p.Owner, time.Now().Add(-operationHeartbeatTimeout))
+	if err != nil {
+		logger.Error("failed to fail interrupted operations, %+v", err)
+	}
+	for i := 0; i < p.Workers; i++ {
+		go p.work()
+	}
+	go p.heartbeat()
+}
+
+// heartbeat keeps the operations of this instance alive and fails the operations of stopped instances.
+func (p *OperationPool) heartbeat() {
+	for range time.Tick(operationHeartbeatInterval) {
+		err := p.Store.HeartbeatOperations(p.Owner)
+		if err != nil {
+			logger.Error("failed to heartbeat operations, %+v", err)
+		}
+		err = p.Store.FailInterruptedOperations("", time.Now().Add(-operationHeartbeatTimeout))
+		if err != nil {
+			logger.Error("failed to fail interrupted operations, %+v", err)
+		}
+	}
+}
+
+// Wakeup notifies an idle worker of a new operation.
+func (p *OperationPool) Wakeup() {
+	select {
+	case p.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (p *OperationPool) work() {
+	for {
+		operation, err := p.Store.ClaimPendingOperation(p.Owner)
+		if err != nil {
+			logger.Error("failed to claim operation, %+v", err)
+		}
+		if operation == nil {
+			select {
+			case <-p.wakeup:
+			case <-time.After(operationPollInterval):
+			}
+			continue
+		}
+		p.execute(operation)
+	}
+}
+
+func (p *OperationPool) execute(operation *models.Operation) {
+	var lock sync.Mutex
+	steps := make([]*projects.OperationStep, 0)
+	recordStep := func(step, status, message string) {
+		lock.Lock()
+		defer lock.Unlock()
+		steps = projects.SetOperationStep(steps, step, status, message)
+		err := p.Store.UpdateOperationSteps(operation.OperationId, steps)
+		if err != nil {
+			logger.Error("failed to record step [%s] of operation [%s], %+v", step, operation.OperationId, err)
+		}
+	}
+
+	writer := &operationResponseWriter{header: make(http.Header)}
+	request, err := http.NewRequest(operation.Method, operation.Path, bytes.NewBufferString(operation.Body))
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(writer, err.Error(), http.StatusInternalServerError)
+	} else {
+		request.Header.Set("Content-Type", "application/json")
+		env := map[string]interface{}{
+			userutils.RemoteUserEnv:           operation.Operator,
+			projects.OperationStepRecorderEnv: projects.OperationStepRecorder(recordStep),
+		}
+		if operation.Impersonator != "" {
+			env[userutils.ImpersonatorEnv] = operation.Impersonator
+		}
+		p.serve(writer, &rest.Request{Request: request, Env: env})
+	}
+
+	lock.Lock()
+	recorded := len(steps) > 0
+	lock.Unlock()
+	if !recorded {
+		status, message := constants.StatusSuccessful, ""
+		if writer.statusCode >= http.StatusBadRequest {
+			status, message = constants.StatusFailed, http.StatusText(writer.statusCode)
+		}
+		recordStep(operationRequestStep, status, message)
+	}
+	err = p.Store.FinishOperation(operation.OperationId, writer.statusCode, writer.body.Bytes())
+	if err != nil {
+		logger.Error("failed to finish operation [%s], %+v", operation.OperationId, err)
+	}
+}
+
+// serve runs the handler, a panic fails the operation instead of the worker.
+func (p *OperationPool) serve(writer *operationResponseWriter, request *rest.Request) {
+	defer func() {
+		if reason := recover(); reason != nil {
+			logger.Error("operation handler panic, %+v", reason)
+			writer.body.Reset()
+			writer.statusCode = 0
+			rest.Error(writer, fmt.Sprintf("%v", reason), http.StatusInternalServerError)
+		}
+	}()
+	p.Handler(writer, request)
+}
+
+// operationResponseWriter keeps the response of an operation in memory.
+type operationResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (w *operationResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *operationResponseWriter) WriteJson(v interface{}) error {
+	b, err := w.EncodeJson(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (w *operationResponseWriter) EncodeJson(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (w *operationResponseWriter) WriteHeader(code int) {
+	if w.statusCode == 0 {
+		w.statusCode = code
+	}
+}
+
+func (w *operationResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+// OperationMiddleware stores the requests of async routes with ?async=true as operations
+// and responds 202 Accepted, it must be used after the auth middlewares.
+type OperationMiddleware struct {
+	Pool *OperationPool
+}
+
+func (mw *OperationMiddleware) MiddlewareFunc(handler rest.HandlerFunc) rest.HandlerFunc {
+	return func(w rest.ResponseWriter, r *rest.Request) {
+		query := r.URL.Query()
+		if query.Get("async") != "true" || !isAsyncRoute(r.Method, r.URL.Path) {
+			handler(w, r)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query.Del("async")
+		path := r.URL.Path
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+		operation := models.NewOperation(r.Method, path, string(body),
+			userutils.GetUserNameFromRequest(r), userutils.GetImpersonatorFromRequest(r))
+		err = mw.Pool.Store.CreateOperation(operation)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		mw.Pool.Wakeup()
+		w.Header().Set("Location", fmt.Sprintf("%s/operations/%s", APIVersion, operation.OperationId))
+		w.WriteHeader(http.StatusAccepted)
+		w.WriteJson(operation)
+	}
+}
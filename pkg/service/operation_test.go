@@ -0,0 +1,204 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/service/projects"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type fakeOperationStore struct {
+	lock       sync.Mutex
+	operations []*models.Operation
+	steps      map[string][]*projects.OperationStep
+	results    map[string]string
+	now        time.Time
+}
+
+func (s *fakeOperationStore) CreateOperation(operation *models.Operation) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.operations = append(s.operations, operation)
+	return nil
+}
+
+func (s *fakeOperationStore) ClaimPendingOperation(owner string) (*models.Operation, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, operation := range s.operations {
+		if operation.Status == constants.StatusPending {
+			operation.Status, operation.Owner, operation.HeartbeatTime = constants.StatusWorking, owner, s.now
+			return operation, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeOperationStore) UpdateOperationSteps(operationId string, steps []*projects.OperationStep) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.steps[operationId] = steps
+	return nil
+}
+
+func (s *fakeOperationStore) FinishOperation(operationId string, statusCode int, body []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, operation := range s.operations {
+		if operation.OperationId == operationId {
+			operation.StatusCode = statusCode
+		}
+	}
+	s.results[operationId] = string(body)
+	return nil
+}
+
+func (s *fakeOperationStore) HeartbeatOperations(owner string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, operation := range s.operations {
+		if operation.Status == constants.StatusWorking && operation.Owner == owner {
+			operation.HeartbeatTime = s.now
+		}
+	}
+	return nil
+}
+
+func (s *fakeOperationStore) FailInterruptedOperations(owner string, staleBefore time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, operation := range s.operations {
+		if operation.Status != constants.StatusWorking {
+			continue
+		}
+		if (owner != "" && operation.Owner == owner) || operation.HeartbeatTime.Before(staleBefore) {
+			operation.Status = constants.StatusFailed
+		}
+	}
+	return nil
+}
+
+func TestIsAsyncRoute(t *testing.T) {
+	for route, expected := range map[string]bool{
+		"POST /projects":                            true,
+		"DELETE /projects/project-abc":              true,
+		"POST /projects/project-abc/members":        true,
+		"PATCH /projects/project-abc/members/alice": true,
+		"GET /projects/project-abc":                 false,
+		"POST /projects/project-abc/credentials":    false,
+	} {
+		parts := strings.SplitN(route, " ", 2)
+		if isAsyncRoute(parts[0], parts[1]) != expected {
+			t.Fatalf("route [%s] async should be %t", route, expected)
+		}
+	}
+}
+
+func TestOperationMiddleware(t *testing.T) {
+	store := &fakeOperationStore{
+		steps:   make(map[string][]*projects.OperationStep),
+		results: make(map[string]string),
+	}
+	router, err := rest.MakeRouter(
+		rest.Post("/projects", func(w rest.ResponseWriter, r *rest.Request) {
+			if recorder := projects.GetOperationStepRecorder(r); recorder != nil {
+				recorder("create_folder", constants.StatusSuccessful, "")
+			}
+			request := make(map[string]string)
+			r.DecodeJsonPayload(&request)
+			w.WriteJson(map[string]string{
+				"name":    request["name"],
+				"creator": userutils.GetUserNameFromRequest(r),
+			})
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := NewOperationPool(store, router.AppFunc(), 1)
+	api := rest.NewApi()
+	api.Use(&HeaderAuthMiddleware{Header: "X-Token-Username"}, &OperationMiddleware{Pool: pool})
+	api.SetApp(router)
+
+	request := httptest.NewRequest(http.MethodPost, "/projects?async=true", strings.NewReader(`{"name":"demo"}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Token-Username", "alice")
+	recorder := httptest.NewRecorder()
+	api.MakeHandler().ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("async request should return %d, got %d", http.StatusAccepted, recorder.Code)
+	}
+	if len(store.operations) != 1 {
+		t.Fatalf("async request should create an operation")
+	}
+	operation := store.operations[0]
+	if operation.Path != "/projects" || operation.Operator != "alice" || operation.Body != `{"name":"demo"}` {
+		t.Fatalf("unexpected operation %+v", operation)
+	}
+
+	claimed, _ := store.ClaimPendingOperation(pool.Owner)
+	pool.execute(claimed)
+	if operation.StatusCode != http.StatusOK {
+		t.Fatalf("operation should finish with %d, got %d", http.StatusOK, operation.StatusCode)
+	}
+	result := make(map[string]string)
+	err = json.Unmarshal([]byte(store.results[operation.OperationId]), &result)
+	if err != nil || result["name"] != "demo" || result["creator"] != "alice" {
+		t.Fatalf("unexpected operation result %s", store.results[operation.OperationId])
+	}
+	steps := store.steps[operation.OperationId]
+	if len(steps) != 1 || steps[0].Name != "create_folder" {
+		t.Fatalf("operation should record the steps of the handler, got %+v", steps)
+	}
+}
+
+func TestOperationPool_StartFailsOwnedOrStaleOperations(t *testing.T) {
+	now := time.Now()
+	store := &fakeOperationStore{now: now}
+	newWorking := func(owner string, heartbeat time.Time) *models.Operation {
+		operation := models.NewOperation(http.MethodPost, "/projects", "", "alice", "")
+		operation.Status, operation.Owner, operation.HeartbeatTime = constants.StatusWorking, owner, heartbeat
+		store.operations = append(store.operations, operation)
+		return operation
+	}
+	own := newWorking("instance-a", now)
+	live := newWorking("instance-b", now)
+	stale := newWorking("instance-c", now.Add(-2*operationHeartbeatTimeout))
+
+	pool := &OperationPool{Store: store, Workers: 0, Owner: "instance-a"}
+	pool.Start()
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if own.Status != constants.StatusFailed {
+		t.Fatalf("operation of the restarted instance should fail, got %s", own.Status)
+	}
+	if live.Status != constants.StatusWorking {
+		t.Fatalf("operation of a live instance should keep working, got %s", live.Status)
+	}
+	if stale.Status != constants.StatusFailed {
+		t.Fatalf("operation without heartbeat should fail, got %s", stale.Status)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+)
+
+const (
+	// ApiTokenSecretPrefix makes api tokens distinguishable from jwt bearer tokens
+	ApiTokenSecretPrefix = "kst_"
+	// ApiTokenUsernamePrefix is the username prefix of requests authenticated by api tokens
+	ApiTokenUsernamePrefix = "token:"
+	apiTokenSecretLength   = 32
+)
+
+var projectRoleLevel = map[string]int{
+	ProjectReporter:   1,
+	ProjectDeveloper:  2,
+	ProjectMaintainer: 3,
+	ProjectOwner:      4,
+}
+
+func IsApiToken(token string) bool {
+	return strings.HasPrefix(token, ApiTokenSecretPrefix)
+}
+
+func IsApiTokenUsername(username string) bool {
+	return strings.HasPrefix(username, ApiTokenUsernamePrefix)
+}
+
+func hashApiToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateApiToken returns the token secret and its hash, only the hash is stored.
+func generateApiToken() (string, string, error) {
+	random := make([]byte, apiTokenSecretLength)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", "", err
+	}
+	secret := ApiTokenSecretPrefix + base64.RawURLEncoding.EncodeToString(random)
+	return secret, hashApiToken(secret), nil
+}
+
+// lowerProjectRole returns the role with less permissions, an empty ceiling does not limit the role.
+// Custom roles can not be compared with the ceiling, they are limited to read access.
+func lowerProjectRole(role, ceiling string) string {
+	if _, ok := projectRoleLevel[role]; !ok && ceiling != "" {
+		return ProjectReporter
+	}
+	if ceiling == "" || projectRoleLevel[role] <= projectRoleLevel[ceiling] {
+		return role
+	}
+	return ceiling
+}
+
+func (s *ProjectService) getActiveApiToken(tokenId string) (*models.ApiToken, error) {
+	token := &models.ApiToken{}
+	err := s.Ds.Db.Select(models.ApiTokenColumns...).
+		From(models.ApiTokenTableName).
+		Where(db.And(
+			db.Eq(models.ApiTokenIdColumn, tokenId),
+			db.Eq(constants.StatusColumn, constants.StatusActive))).
+		LoadOne(token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// AuthenticateApiToken resolves a token secret to the username of the token identity.
+func (s *ProjectService) AuthenticateApiToken(secret string) (string, error) {
+	token := &models.ApiToken{}
+	err := s.Ds.Db.Select(models.ApiTokenColumns...).
+		From(models.ApiTokenTableName).
+		Where(db.And(
+			db.Eq(models.ApiTokenHashColumn, hashApiToken(secret)),
+			db.Eq(constants.StatusColumn, constants.StatusActive))).
+		LoadOne(token)
+	if err != nil {
+		if err == db.ErrNotFound {
+			return "", fmt.Errorf("invalid api token")
+		}
+		return "", err
+	}
+	now := time.Now()
+	if token.ExpireTime != nil && token.ExpireTime.Before(now) {
+		return "", fmt.Errorf("api token [%s] is expired", token.TokenId)
+	}
+	_, err = s.Ds.Db.Update(models.ApiTokenTableName).
+		Set(models.ApiTokenLastUsedTimeColumn, now).
+		Where(db.Eq(models.ApiTokenIdColumn, token.TokenId)).Exec()
+	if err != nil {
+		logger.Warn("failed to update last used time of api token [%s], %+v", token.TokenId, err)
+	}
+	return ApiTokenUsernamePrefix + token.TokenId, nil
+}
+
+// getApiTokenProjectRole returns the role of a token identity in a project.
+// Service account tokens have their own role, personal tokens act as their owner limited by the role ceiling.
+func (s *ProjectService) getApiTokenProjectRole(tokenId, projectId string) (string, error) {
+	token, err := s.getActiveApiToken(tokenId)
+	if err != nil {
+		return "", err
+	}
+	if token.ProjectId != "" && token.ProjectId != projectId {
+		return "", fmt.Errorf("api token [%s] is not allowed to access project [%s]", tokenId, projectId)
+	}
+	if token.Type == models.ApiTokenTypeServiceAccount {
+		return token.Role, nil
+	}
+	role := ProjectOwner
+	if !s.isPlatformAdmin(token.Owner) {
+		role, err = s.getMemberProjectRole(token.Owner, projectId)
+		if err != nil {
+			return "", err
+		}
+	}
+	return lowerProjectRole(role, token.Role), nil
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+	"github.com/gocraft/dbr"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type CreateApiTokenRequest struct {
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	ProjectId  string     `json:"project_id"`
+	Role       string     `json:"role"`
+	ExpireTime *time.Time `json:"expire_time"`
+}
+
+func (s *ProjectService) CreateApiTokenHandler(w rest.ResponseWriter, r *rest.Request) {
+	request := &CreateApiTokenRequest{}
+	operator := userutils.GetUserNameFromRequest(r)
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// a token with role ceiling must not be able to create a token without it
+	if IsApiTokenUsername(operator) {
+		err := fmt.Errorf("api token can not be created with api token")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if govalidator.IsNull(request.Type) {
+		request.Type = models.ApiTokenTypePersonal
+	}
+	err = validateCreateApiTokenRequest(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Type == models.ApiTokenTypeServiceAccount {
+		err = s.checkProjectUserInRole(operator, request.ProjectId, []string{ProjectOwner})
+	} else if !govalidator.IsNull(request.ProjectId) {
+		err = s.checkProjectUserInRole(operator, request.ProjectId, AllRoleSlice)
+	}
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	secret, tokenHash, err := generateApiToken()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	token := models.NewApiToken(request.Name, request.Type, operator, request.ProjectId, request.Role,
+		tokenHash, request.ExpireTime)
+	_, err = s.Ds.Db.InsertInto(models.ApiTokenTableName).
+		Columns(models.ApiTokenColumns...).
+		Record(token).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// the secret is only returned once
+	w.WriteJson(struct {
+		*models.ApiToken
+		Token string `json:"token"`
+	}{ApiToken: token, Token: secret})
+	return
+}
+
+func validateCreateApiTokenRequest(request *CreateApiTokenRequest) error {
+	if govalidator.IsNull(request.Name) {
+		return fmt.Errorf("error need name of api token")
+	}
+	if !govalidator.IsNull(request.Role) && !reflectutils.In(request.Role, AllRoleSlice) {
+		return fmt.Errorf("error role should in %s", AllRoleSlice)
+	}
+	if request.ExpireTime != nil && request.ExpireTime.Before(time.Now()) {
+		return fmt.Errorf("error expire time should be in the future")
+	}
+	switch request.Type {
+	case models.ApiTokenTypePersonal:
+	case models.ApiTokenTypeServiceAccount:
+		if govalidator.IsNull(request.ProjectId) || govalidator.IsNull(request.Role) {
+			return fmt.Errorf("error service account token need project_id and role")
+		}
+	default:
+		return fmt.Errorf("error unsupport api token type [%s]", request.Type)
+	}
+	return nil
+}
+
+// GetApiTokensHandler lists the tokens of operator,
+// project owners can list the service account tokens of their project with project_id.
+func (s *ProjectService) GetApiTokensHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	projectId := r.URL.Query().Get("project_id")
+	conditions := []dbr.Builder{db.Eq(constants.StatusColumn, constants.StatusActive)}
+	if govalidator.IsNull(projectId) {
+		conditions = append(conditions, db.Eq(models.ApiTokenOwnerColumn, operator))
+	} else {
+		err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		conditions = append(conditions,
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ApiTokenTypeColumn, models.ApiTokenTypeServiceAccount))
+	}
+	tokens := make([]*models.ApiToken, 0)
+	_, err := s.Ds.Db.Select(models.ApiTokenColumns...).
+		From(models.ApiTokenTableName).
+		Where(db.And(conditions...)).
+		Load(&tokens)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(tokens)
+	return
+}
+
+// DeleteApiTokenHandler revokes a token, service account tokens can also be revoked by project owners.
+func (s *ProjectService) DeleteApiTokenHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	tokenId := r.PathParams["tid"]
+	token, err := s.getActiveApiToken(tokenId)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if operator != token.Owner && !s.isPlatformAdmin(operator) &&
+		operator != ApiTokenUsernamePrefix+token.TokenId {
+		if token.Type != models.ApiTokenTypeServiceAccount {
+			err = fmt.Errorf("user [%s] can not revoke api token [%s]", operator, tokenId)
+		} else {
+			err = s.checkProjectUserInRole(operator, token.ProjectId, []string{ProjectOwner})
+		}
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	_, err = s.Ds.Db.Update(models.ApiTokenTableName).
+		Set(constants.StatusColumn, constants.StatusDeleted).
+		Where(db.Eq(models.ApiTokenIdColumn, tokenId)).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(struct {
+		TokenId string `json:"token_id"`
+	}{TokenId: tokenId})
+	return
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"testing"
+)
+
+func TestGenerateApiToken(t *testing.T) {
+	secret, tokenHash, err := generateApiToken()
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if !IsApiToken(secret) {
+		t.Fatalf("token [%s] should have prefix %s", secret, ApiTokenSecretPrefix)
+	}
+	if hashApiToken(secret) != tokenHash || len(tokenHash) != 64 {
+		t.Fatalf("token hash should be the sha256 of secret, got %s", tokenHash)
+	}
+	other, _, err := generateApiToken()
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if other == secret {
+		t.Fatalf("tokens should be random")
+	}
+}
+
+func TestLowerProjectRole(t *testing.T) {
+	for _, test := range []struct {
+		role     string
+		ceiling  string
+		expected string
+	}{
+		{ProjectOwner, "", ProjectOwner},
+		{ProjectOwner, ProjectDeveloper, ProjectDeveloper},
+		{ProjectReporter, ProjectMaintainer, ProjectReporter},
+		{ProjectMaintainer, ProjectMaintainer, ProjectMaintainer},
+		{"release-manager", "", "release-manager"},
+		{"release-manager", ProjectOwner, ProjectReporter},
+	} {
+		if role := lowerProjectRole(test.role, test.ceiling); role != test.expected {
+			t.Fatalf("role %s with ceiling %s should be %s, got %s", test.role, test.ceiling, test.expected, role)
+		}
+	}
+}
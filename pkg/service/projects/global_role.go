@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+)
+
+const (
+	GlobalRolePlatformAdmin  = "platform-admin"
+	GlobalRoleAuditor        = "auditor"
+	GlobalRoleProjectCreator = "project-creator"
+)
+
+var AllGlobalRoleSlice = []string{GlobalRolePlatformAdmin, GlobalRoleAuditor, GlobalRoleProjectCreator}
+
+var JenkinsGlobalPermissionMap = map[string]gojenkins.GlobalPermissionIds{
+	GlobalRolePlatformAdmin: gojenkins.GlobalPermissionIds{
+		Administer: true,
+	},
+	GlobalRoleAuditor: gojenkins.GlobalPermissionIds{
+		GlobalRead:   true,
+		ItemRead:     true,
+		ItemDiscover: true,
+		ViewRead:     true,
+	},
+	GlobalRoleProjectCreator: gojenkins.GlobalPermissionIds{
+		GlobalRead: true,
+		ItemCreate: true,
+	},
+}
+
+func GetGlobalRoleName(role string) string {
+	return fmt.Sprintf("kubesphere-%s", role)
+}
+
+// getGlobalRoles returns the global roles bound to a user,
+// configured administrators always have the platform-admin role.
+func (s *ProjectService) getGlobalRoles(username string) ([]string, error) {
+	roles := make([]string, 0)
+	if username == "" {
+		return roles, nil
+	}
+	if reflectutils.In(username, s.Rbac.Administrators) {
+		roles = append(roles, GlobalRolePlatformAdmin)
+	}
+	bindings := make([]*models.GlobalRoleBinding, 0)
+	_, err := s.Ds.Db.Select(models.GlobalRoleBindingColumns...).
+		From(models.GlobalRoleBindingTableName).
+		Where(db.Eq(models.GlobalRoleBindingUsernameColumn, username)).
+		Load(&bindings)
+	if err != nil {
+		return nil, err
+	}
+	for _, binding := range bindings {
+		if !reflectutils.In(binding.Role, roles) {
+			roles = append(roles, binding.Role)
+		}
+	}
+	return roles, nil
+}
+
+// hasGlobalRole reports whether the user has one of the roles, errors are treated as no role.
+func (s *ProjectService) hasGlobalRole(username string, roles ...string) bool {
+	userRoles, err := s.getGlobalRoles(username)
+	if err != nil {
+		logger.Error("failed to get global roles of user [%s], %+v", username, err)
+		return false
+	}
+	for _, role := range userRoles {
+		if reflectutils.In(role, roles) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ProjectService) isPlatformAdmin(username string) bool {
+	return s.hasGlobalRole(username, GlobalRolePlatformAdmin)
+}
+
+// canCreateProject checks the project-creator role if project creation is restricted.
+func (s *ProjectService) canCreateProject(username string) bool {
+	if !s.Rbac.RestrictProjectCreation {
+		return true
+	}
+	return s.hasGlobalRole(username, GlobalRolePlatformAdmin, GlobalRoleProjectCreator)
+}
+
+// getJenkinsGlobalRole gets the mirrored jenkins role of a global role, it is created if not exists.
+func (s *ProjectService) getJenkinsGlobalRole(role string) (*gojenkins.GlobalRole, error) {
+	globalRole, err := s.Ds.Jenkins.GetGlobalRole(GetGlobalRoleName(role))
+	if err != nil {
+		return nil, err
+	}
+	if globalRole != nil {
+		return globalRole, nil
+	}
+	return s.Ds.Jenkins.AddGlobalRole(GetGlobalRoleName(role), JenkinsGlobalPermissionMap[role], true)
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type GlobalRoleResponse struct {
+	Name            string                      `json:"name"`
+	JenkinsRole     string                      `json:"jenkins_role"`
+	ConfiguredUsers []string                    `json:"configured_users,omitempty"`
+	Users           []*models.GlobalRoleBinding `json:"users"`
+}
+
+type AddGlobalRoleUserRequest struct {
+	Username string `json:"username"`
+}
+
+func (s *ProjectService) GetGlobalRolesHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	if !s.hasGlobalRole(operator, GlobalRolePlatformAdmin, GlobalRoleAuditor) {
+		err := fmt.Errorf("user [%s] can not get global roles", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	bindings := make([]*models.GlobalRoleBinding, 0)
+	_, err := s.Ds.Db.Select(models.GlobalRoleBindingColumns...).
+		From(models.GlobalRoleBindingTableName).
+		Load(&bindings)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response := make([]*GlobalRoleResponse, 0)
+	for _, role := range AllGlobalRoleSlice {
+		roleResponse := &GlobalRoleResponse{
+			Name:        role,
+			JenkinsRole: GetGlobalRoleName(role),
+			Users:       make([]*models.GlobalRoleBinding, 0),
+		}
+		if role == GlobalRolePlatformAdmin {
+			roleResponse.ConfiguredUsers = s.Rbac.Administrators
+		}
+		for _, binding := range bindings {
+			if binding.Role == role {
+				roleResponse.Users = append(roleResponse.Users, binding)
+			}
+		}
+		response = append(response, roleResponse)
+	}
+	w.WriteJson(response)
+	return
+}
+
+func (s *ProjectService) AddGlobalRoleUserHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	role := r.PathParams["role"]
+	request := &AddGlobalRoleUserRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(request.Username) {
+		err := fmt.Errorf("error need username")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !reflectutils.In(role, AllGlobalRoleSlice) {
+		err := fmt.Errorf("err global role [%s] not in [%s]", role, AllGlobalRoleSlice)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not grant global roles", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	binding := &models.GlobalRoleBinding{}
+	err = s.Ds.Db.Select(models.GlobalRoleBindingColumns...).
+		From(models.GlobalRoleBindingTableName).
+		Where(db.And(
+			db.Eq(models.GlobalRoleBindingUsernameColumn, request.Username),
+			db.Eq(models.GlobalRoleBindingRoleColumn, role))).LoadOne(binding)
+	if err != nil && err != db.ErrNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != db.ErrNotFound {
+		err = fmt.Errorf("user [%s] already has global role [%s]", request.Username, role)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	globalRole, err := s.getJenkinsGlobalRole(role)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	err = globalRole.AssignRole(request.Username)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+
+	binding = models.NewGlobalRoleBinding(request.Username, role, operator)
+	_, err = s.Ds.Db.InsertInto(models.GlobalRoleBindingTableName).
+		Columns(models.GlobalRoleBindingColumns...).
+		Record(binding).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(binding)
+	return
+}
+
+func (s *ProjectService) DeleteGlobalRoleUserHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	role := r.PathParams["role"]
+	username := r.PathParams["uid"]
+	if !reflectutils.In(role, AllGlobalRoleSlice) {
+		err := fmt.Errorf("err global role [%s] not in [%s]", role, AllGlobalRoleSlice)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not revoke global roles", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	binding := &models.GlobalRoleBinding{}
+	err := s.Ds.Db.Select(models.GlobalRoleBindingColumns...).
+		From(models.GlobalRoleBindingTableName).
+		Where(db.And(
+			db.Eq(models.GlobalRoleBindingUsernameColumn, username),
+			db.Eq(models.GlobalRoleBindingRoleColumn, role))).LoadOne(binding)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	globalRole, err := s.getJenkinsGlobalRole(role)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	err = globalRole.UnAssignRole(username)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+
+	_, err = s.Ds.Db.DeleteFrom(models.GlobalRoleBindingTableName).
+		Where(db.And(
+			db.Eq(models.GlobalRoleBindingUsernameColumn, username),
+			db.Eq(models.GlobalRoleBindingRoleColumn, role))).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(struct {
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}{Username: username, Role: role})
+	return
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+)
+
+// projectRoleRank ranks custom roles above developer and below maintainer,
+// so owner and maintainer bindings are never hidden by a custom role.
+func projectRoleRank(role string) int {
+	if level, ok := projectRoleLevel[role]; ok {
+		return 2 * level
+	}
+	return 2*projectRoleLevel[ProjectDeveloper] + 1
+}
+
+// higherProjectRole returns the role with the higher rank, custom roles of the same rank
+// are ordered by name so the result does not depend on the order of the bindings.
+func higherProjectRole(role, other string) string {
+	if role == "" {
+		return other
+	}
+	if other == "" {
+		return role
+	}
+	rank, otherRank := projectRoleRank(role), projectRoleRank(other)
+	if otherRank > rank || (otherRank == rank && other < role) {
+		return other
+	}
+	return role
+}
+
+func (s *ProjectService) getGroup(groupId string) (*models.Group, error) {
+	group := &models.Group{}
+	err := s.Ds.Db.Select(models.GroupColumns...).
+		From(models.GroupTableName).
+		Where(db.Eq(models.GroupIdColumn, groupId)).
+		LoadOne(group)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *ProjectService) getGroupMembers(groupId string) ([]string, error) {
+	memberships := make([]*models.GroupMembership, 0)
+	_, err := s.Ds.Db.Select(models.GroupMembershipColumns...).
+		From(models.GroupMembershipTableName).
+		Where(db.Eq(models.GroupIdColumn, groupId)).
+		Load(&memberships)
+	if err != nil {
+		return nil, err
+	}
+	usernames := make([]string, 0)
+	for _, membership := range memberships {
+		usernames = append(usernames, membership.Username)
+	}
+	return usernames, nil
+}
+
+func (s *ProjectService) getGroupBindings(groupId string) ([]*models.ProjectGroupBinding, error) {
+	bindings := make([]*models.ProjectGroupBinding, 0)
+	_, err := s.Ds.Db.Select(models.ProjectGroupBindingColumns...).
+		From(models.ProjectGroupBindingTableName).
+		Where(db.Eq(models.GroupIdColumn, groupId)).
+		Load(&bindings)
+	if err != nil {
+		return nil, err
+	}
+	return bindings, nil
+}
+
+// getUserGroupBindings returns the group bindings of the groups of a user,
+// the bindings of all projects are returned with an empty project id.
+func (s *ProjectService) getUserGroupBindings(username, projectId string) ([]*models.ProjectGroupBinding, error) {
+	memberships := make([]*models.GroupMembership, 0)
+	_, err := s.Ds.Db.Select(models.GroupMembershipColumns...).
+		From(models.GroupMembershipTableName).
+		Where(db.Eq(models.GroupMembershipUsernameColumn, username)).
+		Load(&memberships)
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]*models.ProjectGroupBinding, 0)
+	if len(memberships) == 0 {
+		return bindings, nil
+	}
+	groupIds := make([]string, 0)
+	for _, membership := range memberships {
+		groupIds = append(groupIds, membership.GroupId)
+	}
+	conditions := db.Eq(models.GroupIdColumn, groupIds)
+	if projectId != "" {
+		conditions = db.And(conditions, db.Eq(models.ProjectIdColumn, projectId))
+	}
+	_, err = s.Ds.Db.Select(models.ProjectGroupBindingColumns...).
+		From(models.ProjectGroupBindingTableName).
+		Where(conditions).
+		Load(&bindings)
+	if err != nil {
+		return nil, err
+	}
+	return bindings, nil
+}
+
+// getMemberProjectRoles returns the direct role and the group roles of a user in a project.
+func (s *ProjectService) getMemberProjectRoles(username, projectId string) (string, []string, error) {
+	membership := &models.ProjectMembership{}
+	err := s.Ds.Db.Select(models.ProjectMembershipColumns...).
+		From(models.ProjectMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipUsernameColumn, username),
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+			db.Eq(constants.StatusColumn, constants.StatusActive))).LoadOne(membership)
+	if err != nil && err != db.ErrNotFound {
+		return "", nil, err
+	}
+	bindings, err := s.getUserGroupBindings(username, projectId)
+	if err != nil {
+		return "", nil, err
+	}
+	groupRoles := make([]string, 0)
+	for _, binding := range bindings {
+		if !reflectutils.In(binding.Role, groupRoles) {
+			groupRoles = append(groupRoles, binding.Role)
+		}
+	}
+	return membership.Role, groupRoles, nil
+}
+
+// getMemberProjectRole returns the highest role of the direct and group bindings of a user,
+// db.ErrNotFound is returned if the user is not a member.
+func (s *ProjectService) getMemberProjectRole(username, projectId string) (string, error) {
+	role, groupRoles, err := s.getMemberProjectRoles(username, projectId)
+	if err != nil {
+		return "", err
+	}
+	for _, groupRole := range groupRoles {
+		role = higherProjectRole(role, groupRole)
+	}
+	if role == "" {
+		return "", db.ErrNotFound
+	}
+	return role, nil
+}
+
+// setJenkinsRoleAssignment assigns or unassigns the jenkins project and pipeline roles of a project role.
+func (s *ProjectService) setJenkinsRoleAssignment(projectId, role, username string, assign bool) error {
+	for _, roleName := range []string{GetProjectRoleName(projectId, role), GetPipelineRoleName(projectId, role)} {
+		jenkinsRole, err := s.Ds.Jenkins.GetProjectRole(roleName)
+		if err != nil {
+			return err
+		}
+		if jenkinsRole == nil {
+			return fmt.Errorf("jenkins role [%s] not found", roleName)
+		}
+		if assign {
+			err = jenkinsRole.AssignRole(username)
+		} else {
+			err = jenkinsRole.UnAssignRole(username)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileJenkinsUserRoles assigns the given roles to a user if the user holds them by direct or
+// group bindings, and unassigns them otherwise.
+func (s *ProjectService) reconcileJenkinsUserRoles(projectId, username string, roles []string) error {
+	directRole, groupRoles, err := s.getMemberProjectRoles(username, projectId)
+	if err != nil {
+		return err
+	}
+	held := append(groupRoles, directRole)
+	for _, role := range roles {
+		err = s.setJenkinsRoleAssignment(projectId, role, username, reflectutils.In(role, held))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getProjectRoleUsernames returns the users holding a role of a project by direct or group bindings.
+func (s *ProjectService) getProjectRoleUsernames(projectId, role string) ([]string, error) {
+	memberships := make([]*models.ProjectMembership, 0)
+	_, err := s.Ds.Db.Select(models.ProjectMembershipColumns...).
+		From(models.ProjectMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+			db.Eq(models.ProjectMembershipRoleColumn, role),
+			db.Eq(constants.StatusColumn, constants.StatusActive))).
+		Load(&memberships)
+	if err != nil {
+		return nil, err
+	}
+	usernames := make([]string, 0)
+	for _, membership := range memberships {
+		usernames = append(usernames, membership.Username)
+	}
+	bindings := make([]*models.ProjectGroupBinding, 0)
+	_, err = s.Ds.Db.Select(models.ProjectGroupBindingColumns...).
+		From(models.ProjectGroupBindingTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectGroupBindingRoleColumn, role))).
+		Load(&bindings)
+	if err != nil {
+		return nil, err
+	}
+	for _, binding := range bindings {
+		members, err := s.getGroupMembers(binding.GroupId)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			if !reflectutils.In(member, usernames) {
+				usernames = append(usernames, member)
+			}
+		}
+	}
+	return usernames, nil
+}
+
+// addGroupMember adds a user to a group and assigns the jenkins roles of the group bindings.
+func (s *ProjectService) addGroupMember(groupId, username string) error {
+	_, err := s.Ds.Db.InsertInto(models.GroupMembershipTableName).
+		Columns(models.GroupMembershipColumns...).
+		Record(models.NewGroupMembership(groupId, username)).Exec()
+	if err != nil {
+		return err
+	}
+	return s.reconcileGroupMemberJenkinsRoles(groupId, username)
+}
+
+// removeGroupMember removes a user from a group and unassigns the jenkins roles
+// which are not held by other bindings.
+func (s *ProjectService) removeGroupMember(groupId, username string) error {
+	_, err := s.Ds.Db.DeleteFrom(models.GroupMembershipTableName).
+		Where(db.And(
+			db.Eq(models.GroupIdColumn, groupId),
+			db.Eq(models.GroupMembershipUsernameColumn, username))).Exec()
+	if err != nil {
+		return err
+	}
+	return s.reconcileGroupMemberJenkinsRoles(groupId, username)
+}
+
+func (s *ProjectService) reconcileGroupMemberJenkinsRoles(groupId, username string) error {
+	bindings, err := s.getGroupBindings(groupId)
+	if err != nil {
+		return err
+	}
+	for _, binding := range bindings {
+		err = s.reconcileJenkinsUserRoles(binding.ProjectId, username, []string{binding.Role})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteProjectGroupBinding removes a group binding and unassigns the jenkins roles
+// which are not held by other bindings.
+func (s *ProjectService) deleteProjectGroupBinding(binding *models.ProjectGroupBinding) error {
+	_, err := s.Ds.Db.DeleteFrom(models.ProjectGroupBindingTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, binding.ProjectId),
+			db.Eq(models.GroupIdColumn, binding.GroupId))).Exec()
+	if err != nil {
+		return err
+	}
+	members, err := s.getGroupMembers(binding.GroupId)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		err = s.reconcileJenkinsUserRoles(binding.ProjectId, member, []string{binding.Role})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteGroup removes a group with its bindings and memberships.
+func (s *ProjectService) deleteGroup(groupId string) error {
+	bindings, err := s.getGroupBindings(groupId)
+	if err != nil {
+		return err
+	}
+	for _, binding := range bindings {
+		err = s.deleteProjectGroupBinding(binding)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = s.Ds.Db.DeleteFrom(models.GroupMembershipTableName).
+		Where(db.Eq(models.GroupIdColumn, groupId)).Exec()
+	if err != nil {
+		return err
+	}
+	_, err = s.Ds.Db.DeleteFrom(models.GroupTableName).
+		Where(db.Eq(models.GroupIdColumn, groupId)).Exec()
+	return err
+}
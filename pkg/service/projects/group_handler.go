@@ -0,0 +1,421 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type CreateGroupRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type AddGroupMemberRequest struct {
+	Username string `json:"username"`
+}
+
+type AddProjectGroupRequest struct {
+	GroupId string `json:"group_id"`
+	Role    string `json:"role"`
+}
+
+type GroupResponse struct {
+	*models.Group
+	Members []string `json:"members"`
+}
+
+func (s *ProjectService) GetGroupsHandler(w rest.ResponseWriter, r *rest.Request) {
+	groups := make([]*models.Group, 0)
+	_, err := s.Ds.Db.Select(models.GroupColumns...).
+		From(models.GroupTableName).
+		Load(&groups)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(groups)
+	return
+}
+
+func (s *ProjectService) GetGroupHandler(w rest.ResponseWriter, r *rest.Request) {
+	groupId := r.PathParams["gid"]
+	group, err := s.getGroup(groupId)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	members, err := s.getGroupMembers(groupId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(&GroupResponse{Group: group, Members: members})
+	return
+}
+
+func (s *ProjectService) CreateGroupHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	request := &CreateGroupRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(request.Name) {
+		err := fmt.Errorf("error need name of group")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not create groups", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	group := models.NewGroup(request.Name, request.Description, models.GroupSourceLocal, "", operator)
+	_, err = s.Ds.Db.InsertInto(models.GroupTableName).
+		Columns(models.GroupColumns...).
+		Record(group).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(group)
+	return
+}
+
+func (s *ProjectService) DeleteGroupHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	groupId := r.PathParams["gid"]
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not delete groups", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	_, err := s.getGroup(groupId)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.deleteGroup(groupId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(struct {
+		GroupId string `json:"group_id"`
+	}{GroupId: groupId})
+	return
+}
+
+// getLocalGroup returns a group whose members can be changed by the api,
+// the members of ldap groups are managed by the sync.
+func (s *ProjectService) getLocalGroup(w rest.ResponseWriter, operator, groupId string) (*models.Group, bool) {
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not manage group members", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return nil, false
+	}
+	group, err := s.getGroup(groupId)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return nil, false
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if group.Source != models.GroupSourceLocal {
+		err = fmt.Errorf("members of group [%s] are managed by %s", group.Name, group.Source)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return group, true
+}
+
+func (s *ProjectService) AddGroupMemberHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	groupId := r.PathParams["gid"]
+	request := &AddGroupMemberRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(request.Username) {
+		err := fmt.Errorf("error need username")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.getLocalGroup(w, operator, groupId); !ok {
+		return
+	}
+	members, err := s.getGroupMembers(groupId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, member := range members {
+		if member == request.Username {
+			err := fmt.Errorf("user [%s] have been added to group", request.Username)
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	}
+	err = s.addGroupMember(groupId, request.Username)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(struct {
+		GroupId  string `json:"group_id"`
+		Username string `json:"username"`
+	}{GroupId: groupId, Username: request.Username})
+	return
+}
+
+func (s *ProjectService) DeleteGroupMemberHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	groupId := r.PathParams["gid"]
+	username := r.PathParams["uid"]
+	if _, ok := s.getLocalGroup(w, operator, groupId); !ok {
+		return
+	}
+	err := s.removeGroupMember(groupId, username)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(struct {
+		GroupId  string `json:"group_id"`
+		Username string `json:"username"`
+	}{GroupId: groupId, Username: username})
+	return
+}
+
+func (s *ProjectService) SyncGroupsHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not sync groups", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if s.Ldap.Address == "" {
+		err := fmt.Errorf("ldap is not configured")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report, err := s.SyncLdapGroups()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteJson(report)
+	return
+}
+
+func (s *ProjectService) GetProjectGroupsHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkProjectUserInRole(operator, projectId, AllRoleSlice)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	bindings := make([]*models.ProjectGroupBinding, 0)
+	_, err = s.Ds.Db.Select(models.ProjectGroupBindingColumns...).
+		From(models.ProjectGroupBindingTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		Load(&bindings)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(bindings)
+	return
+}
+
+// AddProjectGroupHandler grants a project role to the members of a group.
+func (s *ProjectService) AddProjectGroupHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	request := &AddProjectGroupRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectMemberManager(operator, projectId, request.Role)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectRoleExists(projectId, request.Role)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = s.getGroup(request.GroupId)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	count, err := s.Ds.Db.Select(models.GroupIdColumn).
+		From(models.ProjectGroupBindingTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.GroupIdColumn, request.GroupId))).Count()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		err = fmt.Errorf("group [%s] have been added to project", request.GroupId)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	binding := models.NewProjectGroupBinding(projectId, request.GroupId, request.Role, operator)
+	_, err = s.Ds.Db.InsertInto(models.ProjectGroupBindingTableName).
+		Columns(models.ProjectGroupBindingColumns...).
+		Record(binding).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	members, err := s.getGroupMembers(request.GroupId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, member := range members {
+		err = s.setJenkinsRoleAssignment(projectId, request.Role, member, true)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+			return
+		}
+	}
+	w.WriteJson(binding)
+	return
+}
+
+func (s *ProjectService) DeleteProjectGroupHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	groupId := r.PathParams["gid"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkProjectUserPermission(operator, projectId, PermissionManageMembers)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	binding := &models.ProjectGroupBinding{}
+	err = s.Ds.Db.Select(models.ProjectGroupBindingColumns...).
+		From(models.ProjectGroupBindingTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.GroupIdColumn, groupId))).LoadOne(binding)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.checkProjectMemberManager(operator, projectId, binding.Role)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.deleteProjectGroupBinding(binding)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(struct {
+		GroupId string `json:"group_id"`
+	}{GroupId: groupId})
+	return
+}
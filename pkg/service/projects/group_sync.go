@@ -0,0 +1,216 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"time"
+
+	"kubesphere.io/devops/pkg/config"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/ldaputils"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+)
+
+const (
+	// GroupSyncCreator is the creator of groups created by the ldap sync
+	GroupSyncCreator  = "system"
+	GroupSyncInterval = time.Hour
+	ldapTimeout       = 30 * time.Second
+)
+
+type GroupSyncReport struct {
+	Created        []string `json:"created"`
+	Updated        []string `json:"updated"`
+	Deleted        []string `json:"deleted"`
+	AddedMembers   int      `json:"added_members"`
+	RemovedMembers int      `json:"removed_members"`
+}
+
+type ldapGroup struct {
+	DN          string
+	Name        string
+	Description string
+	Members     []string
+}
+
+// ldapGroupFromEntry returns nil for entries without group name.
+func ldapGroupFromEntry(entry *ldaputils.Entry, cfg *config.LdapConfig) *ldapGroup {
+	name := entry.GetAttributeValue(cfg.GroupNameAttribute)
+	if name == "" {
+		return nil
+	}
+	group := &ldapGroup{
+		DN:          entry.DN,
+		Name:        name,
+		Description: entry.GetAttributeValue("description"),
+		Members:     make([]string, 0),
+	}
+	for _, member := range entry.GetAttributeValues(cfg.MemberAttribute) {
+		username := ldaputils.GetRDNValue(member, cfg.UserIdAttribute)
+		if username != "" && !reflectutils.In(username, group.Members) {
+			group.Members = append(group.Members, username)
+		}
+	}
+	return group
+}
+
+func diffGroupMembers(current, desired []string) ([]string, []string) {
+	added := make([]string, 0)
+	for _, username := range desired {
+		if !reflectutils.In(username, current) {
+			added = append(added, username)
+		}
+	}
+	removed := make([]string, 0)
+	for _, username := range current {
+		if !reflectutils.In(username, desired) {
+			removed = append(removed, username)
+		}
+	}
+	return added, removed
+}
+
+func (s *ProjectService) searchLdapGroups() ([]*ldapGroup, error) {
+	if s.Ldap.Address == "" {
+		return nil, fmt.Errorf("ldap is not configured")
+	}
+	conn, err := ldaputils.Dial(s.Ldap.Address, ldapTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if s.Ldap.BindDN != "" {
+		err = conn.Bind(s.Ldap.BindDN, s.Ldap.BindPassword)
+		if err != nil {
+			return nil, err
+		}
+	}
+	entries, err := conn.Search(&ldaputils.SearchRequest{
+		BaseDN:     s.Ldap.BaseDN,
+		Scope:      ldaputils.ScopeWholeSubtree,
+		Filter:     s.Ldap.GroupFilter,
+		Attributes: []string{s.Ldap.GroupNameAttribute, s.Ldap.MemberAttribute, "description"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*ldapGroup, 0)
+	for _, entry := range entries {
+		group := ldapGroupFromEntry(entry, &s.Ldap)
+		if group == nil {
+			logger.Warn("ldap entry [%s] has no group name attribute [%s]", entry.DN, s.Ldap.GroupNameAttribute)
+			continue
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// checkLdapGroupSearch refuses a search without groups while ldap groups exist,
+// a wrong base dn or filter or a size limit would otherwise delete all the ldap groups and their bindings.
+func checkLdapGroupSearch(found, existing int) error {
+	if found == 0 && existing > 0 {
+		return fmt.Errorf("ldap group search returned no groups but %d ldap groups exist, "+
+			"check the base dn and group filter, no group is deleted", existing)
+	}
+	return nil
+}
+
+// SyncLdapGroups creates, updates and deletes the ldap groups and their members,
+// the jenkins roles of the group bindings follow the member changes.
+func (s *ProjectService) SyncLdapGroups() (*GroupSyncReport, error) {
+	report := &GroupSyncReport{
+		Created: make([]string, 0),
+		Updated: make([]string, 0),
+		Deleted: make([]string, 0),
+	}
+	ldapGroups, err := s.searchLdapGroups()
+	if err != nil {
+		return report, err
+	}
+	groups := make([]*models.Group, 0)
+	_, err = s.Ds.Db.Select(models.GroupColumns...).
+		From(models.GroupTableName).
+		Where(db.Eq(models.GroupSourceColumn, models.GroupSourceLdap)).
+		Load(&groups)
+	if err != nil {
+		return report, err
+	}
+	err = checkLdapGroupSearch(len(ldapGroups), len(groups))
+	if err != nil {
+		return report, err
+	}
+	existing := make(map[string]*models.Group)
+	for _, group := range groups {
+		existing[group.ExternalId] = group
+	}
+
+	for _, ldapGroup := range ldapGroups {
+		group, ok := existing[ldapGroup.DN]
+		delete(existing, ldapGroup.DN)
+		switch {
+		case !ok:
+			group = models.NewGroup(ldapGroup.Name, ldapGroup.Description, models.GroupSourceLdap, ldapGroup.DN,
+				GroupSyncCreator)
+			_, err = s.Ds.Db.InsertInto(models.GroupTableName).
+				Columns(models.GroupColumns...).
+				Record(group).Exec()
+			if err != nil {
+				return report, err
+			}
+			report.Created = append(report.Created, group.Name)
+		case group.Name != ldapGroup.Name || group.Description != ldapGroup.Description:
+			_, err = s.Ds.Db.Update(models.GroupTableName).
+				Set(models.GroupNameColumn, ldapGroup.Name).
+				Set(models.GroupDescriptionColumn, ldapGroup.Description).
+				Where(db.Eq(models.GroupIdColumn, group.GroupId)).Exec()
+			if err != nil {
+				return report, err
+			}
+			report.Updated = append(report.Updated, ldapGroup.Name)
+		}
+
+		members, err := s.getGroupMembers(group.GroupId)
+		if err != nil {
+			return report, err
+		}
+		added, removed := diffGroupMembers(members, ldapGroup.Members)
+		for _, username := range added {
+			err = s.addGroupMember(group.GroupId, username)
+			if err != nil {
+				return report, err
+			}
+		}
+		for _, username := range removed {
+			err = s.removeGroupMember(group.GroupId, username)
+			if err != nil {
+				return report, err
+			}
+		}
+		report.AddedMembers += len(added)
+		report.RemovedMembers += len(removed)
+	}
+
+	for _, group := range existing {
+		err = s.deleteGroup(group.GroupId)
+		if err != nil {
+			return report, err
+		}
+		report.Deleted = append(report.Deleted, group.Name)
+	}
+	return report, nil
+}
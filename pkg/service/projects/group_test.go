@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"reflect"
+	"testing"
+
+	"kubesphere.io/devops/pkg/config"
+	"kubesphere.io/devops/pkg/utils/ldaputils"
+)
+
+func TestHigherProjectRole(t *testing.T) {
+	for _, item := range []struct {
+		role     string
+		other    string
+		expected string
+	}{
+		{"", ProjectDeveloper, ProjectDeveloper},
+		{ProjectMaintainer, "", ProjectMaintainer},
+		{ProjectDeveloper, ProjectOwner, ProjectOwner},
+		{ProjectOwner, ProjectReporter, ProjectOwner},
+		{"release-manager", ProjectDeveloper, "release-manager"},
+		{ProjectDeveloper, "release-manager", "release-manager"},
+		{"release-manager", ProjectReporter, "release-manager"},
+		{"release-manager", ProjectOwner, ProjectOwner},
+		{ProjectOwner, "release-manager", ProjectOwner},
+		{"release-manager", ProjectMaintainer, ProjectMaintainer},
+		{ProjectMaintainer, "release-manager", ProjectMaintainer},
+		{"release-manager", "auditor", "auditor"},
+		{"auditor", "release-manager", "auditor"},
+	} {
+		if role := higherProjectRole(item.role, item.other); role != item.expected {
+			t.Fatalf("higher role of %s and %s should be %s, got %s", item.role, item.other, item.expected, role)
+		}
+	}
+}
+
+func TestDiffGroupMembers(t *testing.T) {
+	added, removed := diffGroupMembers([]string{"alice", "bob"}, []string{"bob", "carol"})
+	if !reflect.DeepEqual(added, []string{"carol"}) {
+		t.Fatalf("added members should be [carol], got %v", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"alice"}) {
+		t.Fatalf("removed members should be [alice], got %v", removed)
+	}
+}
+
+func TestLdapGroupFromEntry(t *testing.T) {
+	cfg := &config.LdapConfig{
+		GroupNameAttribute: "cn",
+		MemberAttribute:    "member",
+		UserIdAttribute:    "uid",
+	}
+	entry := &ldaputils.Entry{
+		DN: "cn=devops,ou=groups,dc=example,dc=com",
+		Attributes: map[string][]string{
+			"cn":          {"devops"},
+			"description": {"devops team"},
+			"member": {
+				"uid=alice,ou=people,dc=example,dc=com",
+				"uid=bob,ou=people,dc=example,dc=com",
+				"uid=alice,ou=people,dc=example,dc=com",
+			},
+		},
+	}
+	group := ldapGroupFromEntry(entry, cfg)
+	if group == nil {
+		t.Fatalf("group should not be nil")
+	}
+	if group.Name != "devops" || group.Description != "devops team" || group.DN != entry.DN {
+		t.Fatalf("unexpected group %+v", group)
+	}
+	if !reflect.DeepEqual(group.Members, []string{"alice", "bob"}) {
+		t.Fatalf("members should be [alice bob], got %v", group.Members)
+	}
+	if ldapGroupFromEntry(&ldaputils.Entry{DN: "cn=empty"}, cfg) != nil {
+		t.Fatalf("entry without group name should be skipped")
+	}
+}
+
+func TestCheckLdapGroupSearch(t *testing.T) {
+	if err := checkLdapGroupSearch(0, 3); err == nil {
+		t.Fatalf("empty search should be refused when ldap groups exist")
+	}
+	for _, item := range [][2]int{{0, 0}, {2, 3}, {3, 0}} {
+		if err := checkLdapGroupSearch(item[0], item[1]); err != nil {
+			t.Fatalf("search of %d groups with %d existing should be accepted, got %+v", item[0], item[1], err)
+		}
+	}
+}
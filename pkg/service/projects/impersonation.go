@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+	"github.com/gocraft/dbr"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+// CanImpersonate checks that a user can send requests as other users.
+func (s *ProjectService) CanImpersonate(username string) bool {
+	return s.isPlatformAdmin(username)
+}
+
+func (s *ProjectService) RecordImpersonation(log *models.ImpersonationLog) error {
+	_, err := s.Ds.Db.InsertInto(models.ImpersonationLogTableName).
+		Columns(models.ImpersonationLogColumns...).
+		Record(log).Exec()
+	return err
+}
+
+// GetImpersonationLogsHandler returns the latest impersonated requests,
+// they can be filtered by admin and impersonated user.
+func (s *ProjectService) GetImpersonationLogsHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	if !s.isPlatformAdmin(operator) || userutils.GetImpersonatorFromRequest(r) != "" {
+		err := fmt.Errorf("user [%s] can not view impersonation logs", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	var limit, offset uint64 = db.DefaultSelectLimit, 0
+	var err error
+	if limitParam := r.URL.Query().Get("limit"); !govalidator.IsNull(limitParam) {
+		limit, err = strconv.ParseUint(limitParam, 10, 64)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if offsetParam := r.URL.Query().Get("offset"); !govalidator.IsNull(offsetParam) {
+		offset, err = strconv.ParseUint(offsetParam, 10, 64)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var conditions []dbr.Builder
+	if admin := r.URL.Query().Get("admin"); !govalidator.IsNull(admin) {
+		conditions = append(conditions, db.Eq(models.ImpersonationLogAdminColumn, admin))
+	}
+	if username := r.URL.Query().Get("username"); !govalidator.IsNull(username) {
+		conditions = append(conditions, db.Eq(models.ImpersonationLogUsernameColumn, username))
+	}
+	query := s.Ds.Db.Select(models.ImpersonationLogColumns...).
+		From(models.ImpersonationLogTableName)
+	if len(conditions) > 0 {
+		query.Where(db.And(conditions...))
+	}
+	logs := make([]*models.ImpersonationLog, 0)
+	_, err = query.OrderDir(models.ImpersonationLogCreateTimeColumn, false).
+		Limit(db.GetLimit(limit)).Offset(db.GetOffset(offset)).
+		Load(&logs)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(logs)
+	return
+}
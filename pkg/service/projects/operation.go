@@ -0,0 +1,179 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/models"
+)
+
+// OperationStepRecorderEnv is the request env key of the step recorder of an operation,
+// it is only set when the request is executed by an operation worker.
+const OperationStepRecorderEnv = "OPERATION_STEP_RECORDER"
+
+// OperationStepRecorder records the status of a step of the running operation.
+type OperationStepRecorder func(step, status, message string)
+
+type OperationStep struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// GetOperationStepRecorder returns nil if the request is not executed as an operation.
+func GetOperationStepRecorder(r *rest.Request) OperationStepRecorder {
+	recorder, _ := r.Env[OperationStepRecorderEnv].(OperationStepRecorder)
+	return recorder
+}
+
+// SetOperationStep updates the step with the same name or appends a new step.
+func SetOperationStep(steps []*OperationStep, name, status, message string) []*OperationStep {
+	for _, step := range steps {
+		if step.Name == name {
+			step.Status = status
+			step.Error = message
+			return steps
+		}
+	}
+	return append(steps, &OperationStep{Name: name, Status: status, Error: message})
+}
+
+func (s *ProjectService) CreateOperation(operation *models.Operation) error {
+	_, err := s.Ds.Db.InsertInto(models.OperationTableName).
+		Columns(models.OperationColumns...).
+		Record(operation).Exec()
+	return err
+}
+
+func (s *ProjectService) getOperation(operationId string) (*models.Operation, error) {
+	operation := &models.Operation{}
+	err := s.Ds.Db.Select(models.OperationColumns...).
+		From(models.OperationTableName).
+		Where(db.Eq(models.OperationIdColumn, operationId)).
+		LoadOne(operation)
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// ClaimPendingOperation marks the oldest pending operation as working by the owner and returns it,
+// nil is returned if there is no pending operation. Workers of several instances
+// can claim concurrently, only one of them updates the status.
+func (s *ProjectService) ClaimPendingOperation(owner string) (*models.Operation, error) {
+	for {
+		operation := &models.Operation{}
+		err := s.Ds.Db.Select(models.OperationColumns...).
+			From(models.OperationTableName).
+			Where(db.Eq(constants.StatusColumn, constants.StatusPending)).
+			OrderDir(models.OperationCreateTimeColumn, true).
+			Limit(1).
+			LoadOne(operation)
+		if err == db.ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		result, err := s.Ds.Db.Update(models.OperationTableName).
+			Set(constants.StatusColumn, constants.StatusWorking).
+			Set(constants.StatusTimeColumn, now).
+			Set(models.OperationOwnerColumn, owner).
+			Set(models.OperationHeartbeatColumn, now).
+			Where(db.And(
+				db.Eq(models.OperationIdColumn, operation.OperationId),
+				db.Eq(constants.StatusColumn, constants.StatusPending))).Exec()
+		if err != nil {
+			return nil, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected == 1 {
+			operation.Status, operation.Owner, operation.HeartbeatTime = constants.StatusWorking, owner, now
+			return operation, nil
+		}
+	}
+}
+
+func (s *ProjectService) UpdateOperationSteps(operationId string, steps []*OperationStep) error {
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return err
+	}
+	_, err = s.Ds.Db.Update(models.OperationTableName).
+		Set(models.OperationStepsColumn, string(data)).
+		Where(db.Eq(models.OperationIdColumn, operationId)).Exec()
+	return err
+}
+
+// FinishOperation records the response of the executed request,
+// the error message is taken from the error response.
+func (s *ProjectService) FinishOperation(operationId string, statusCode int, body []byte) error {
+	status, result, message := constants.StatusSuccessful, string(body), ""
+	if statusCode >= http.StatusBadRequest {
+		status, result = constants.StatusFailed, ""
+		errorResponse := make(map[string]string)
+		if json.Unmarshal(body, &errorResponse) == nil {
+			message = errorResponse[rest.ErrorFieldName]
+		}
+		if message == "" {
+			message = http.StatusText(statusCode)
+		}
+	}
+	_, err := s.Ds.Db.Update(models.OperationTableName).
+		Set(constants.StatusColumn, status).
+		Set(models.OperationStatusCodeColumn, statusCode).
+		Set(models.OperationResultColumn, result).
+		Set(models.OperationErrorColumn, message).
+		Set(constants.StatusTimeColumn, time.Now()).
+		Where(db.Eq(models.OperationIdColumn, operationId)).Exec()
+	return err
+}
+
+// HeartbeatOperations marks the working operations of the owner as alive.
+func (s *ProjectService) HeartbeatOperations(owner string) error {
+	_, err := s.Ds.Db.Update(models.OperationTableName).
+		Set(models.OperationHeartbeatColumn, time.Now()).
+		Where(db.And(
+			db.Eq(constants.StatusColumn, constants.StatusWorking),
+			db.Eq(models.OperationOwnerColumn, owner))).Exec()
+	return err
+}
+
+// FailInterruptedOperations fails the working operations of the owner, which was restarted,
+// and the operations without heartbeat since staleBefore, whose instance stopped.
+// They may have been partially applied so they are not executed again.
+// The operations of other live instances are not changed, an empty owner only fails stale operations.
+func (s *ProjectService) FailInterruptedOperations(owner string, staleBefore time.Time) error {
+	interrupted := db.Lt(models.OperationHeartbeatColumn, staleBefore)
+	if owner != "" {
+		interrupted = db.Or(db.Eq(models.OperationOwnerColumn, owner), interrupted)
+	}
+	_, err := s.Ds.Db.Update(models.OperationTableName).
+		Set(constants.StatusColumn, constants.StatusFailed).
+		Set(models.OperationErrorColumn, "operation was interrupted by a restart").
+		Set(constants.StatusTimeColumn, time.Now()).
+		Where(db.And(db.Eq(constants.StatusColumn, constants.StatusWorking), interrupted)).Exec()
+	return err
+}
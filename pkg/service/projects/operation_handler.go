@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type OperationResponse struct {
+	*models.Operation
+	Steps    []*OperationStep `json:"steps"`
+	Finished int              `json:"finished_steps"`
+	Result   json.RawMessage  `json:"result,omitempty"`
+}
+
+func newOperationResponse(operation *models.Operation) (*OperationResponse, error) {
+	response := &OperationResponse{Operation: operation, Steps: make([]*OperationStep, 0)}
+	if operation.Steps != "" {
+		err := json.Unmarshal([]byte(operation.Steps), &response.Steps)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, step := range response.Steps {
+		if step.Status != constants.StatusWorking && step.Status != constants.StatusPending {
+			response.Finished++
+		}
+	}
+	if operation.Result != "" {
+		response.Result = json.RawMessage(operation.Result)
+	}
+	return response, nil
+}
+
+// GetOperationHandler returns the progress of an operation to its operator and platform admins.
+func (s *ProjectService) GetOperationHandler(w rest.ResponseWriter, r *rest.Request) {
+	operationId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	operation, err := s.getOperation(operationId)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if operation.Operator != operator && !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not view operation [%s]", operator, operationId)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	response, err := newOperationResponse(operation)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(response)
+	return
+}
@@ -29,7 +29,7 @@ func (s *ProjectService) GetPipelineSonarHandler(w rest.ResponseWriter, r *rest.
 	projectId := r.PathParams["id"]
 	pipelineId := r.PathParams["pid"]
 	operator := userutils.GetUserNameFromRequest(r)
-	err := s.checkProjectUserInRole(operator, projectId, AllRoleSlice)
+	err := s.checkPipelineUserInRole(operator, projectId, pipelineId, AllRoleSlice)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
@@ -73,7 +73,7 @@ func (s *ProjectService) GetMultiBranchPipelineSonarHandler(w rest.ResponseWrite
 	pipelineId := r.PathParams["pid"]
 	branchName := r.PathParams["bid"]
 	operator := userutils.GetUserNameFromRequest(r)
-	err := s.checkProjectUserInRole(operator, projectId, AllRoleSlice)
+	err := s.checkPipelineUserInRole(operator, projectId, pipelineId, AllRoleSlice)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
@@ -0,0 +1,152 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/models"
+)
+
+// checkProjectNotArchived rejects the mutations of an archived project.
+func (s *ProjectService) checkProjectNotArchived(projectId string) error {
+	project, err := s.getProject(projectId)
+	if err == db.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if project.Status == constants.StatusArchived {
+		return fmt.Errorf("project [%s] is archived, unarchive it before changing it", projectId)
+	}
+	return nil
+}
+
+// overwriteJenkinsPipelineMemberRoles creates or overwrites the jenkins roles of the pipeline members,
+// read-only roles get the reporter permissions.
+func (s *ProjectService) overwriteJenkinsPipelineMemberRoles(projectId string, readOnly bool) error {
+	pipelineMemberships, err := s.getPipelineMemberships(projectId)
+	if err != nil {
+		return err
+	}
+	jenkinsRoles := make(map[string]*gojenkins.ProjectRole)
+	for _, pipelineMembership := range pipelineMemberships {
+		roleName := GetPipelineMemberRoleName(projectId, pipelineMembership.PipelineId, pipelineMembership.Role)
+		jenkinsRole, ok := jenkinsRoles[roleName]
+		if !ok {
+			ids := JenkinsPipelinePermissionMap[ProjectReporter]
+			if !readOnly {
+				ids, err = s.getPipelineRolePermissionIds(projectId, pipelineMembership.Role)
+				if err != nil {
+					return err
+				}
+			}
+			jenkinsRole, err = s.Ds.Jenkins.AddProjectRole(roleName,
+				GetPipelineMemberRolePattern(projectId, pipelineMembership.PipelineId), ids, true)
+			if err != nil {
+				return err
+			}
+			jenkinsRoles[roleName] = jenkinsRole
+		}
+		err = jenkinsRole.AssignRole(pipelineMembership.Username)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downgradeJenkinsProjectRoles overwrites every jenkins role of a project with the reporter permissions,
+// the members keep their role assignments.
+func (s *ProjectService) downgradeJenkinsProjectRoles(projectId string) error {
+	roles := make([]string, 0)
+	roles = append(roles, AllRoleSlice...)
+	customRoles := make([]*models.ProjectRole, 0)
+	_, err := s.Ds.Db.Select(models.ProjectRoleNameColumn).
+		From(models.ProjectRoleTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		Load(&customRoles)
+	if err != nil {
+		return err
+	}
+	for _, customRole := range customRoles {
+		roles = append(roles, customRole.Name)
+	}
+	restrictedPipelines, err := s.getRestrictedPipelines(projectId)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		err = s.overwriteJenkinsProjectRole(projectId, role, GetProjectRoleName(projectId, role),
+			GetProjectRolePattern(projectId), JenkinsProjectPermissionMap[ProjectReporter])
+		if err != nil {
+			return err
+		}
+		pattern := GetRestrictedPipelineRolePattern(projectId, restrictedPipelines)
+		if role == ProjectOwner {
+			pattern = GetPipelineRolePattern(projectId)
+		}
+		err = s.overwriteJenkinsProjectRole(projectId, role, GetPipelineRoleName(projectId, role),
+			pattern, JenkinsPipelinePermissionMap[ProjectReporter])
+		if err != nil {
+			return err
+		}
+	}
+	return s.overwriteJenkinsPipelineMemberRoles(projectId, true)
+}
+
+// archiveProject freezes a project, its jobs are disabled and its members can only read it.
+func (s *ProjectService) archiveProject(project *models.Project) error {
+	err := s.disableProjectJobs(project.ProjectId, JobDisabledByArchive)
+	if err != nil {
+		return err
+	}
+	err = s.downgradeJenkinsProjectRoles(project.ProjectId)
+	if err != nil {
+		return err
+	}
+	_, err = s.Ds.Db.Update(models.ProjectTableName).
+		Set(constants.StatusColumn, constants.StatusArchived).
+		Where(db.Eq(models.ProjectIdColumn, project.ProjectId)).Exec()
+	if err != nil {
+		return err
+	}
+	project.Status = constants.StatusArchived
+	return nil
+}
+
+// unarchiveProject restores the jenkins roles of the members from their memberships and enables the jobs
+// disabled by the archive, jobs disabled before the archive stay disabled.
+func (s *ProjectService) unarchiveProject(project *models.Project) error {
+	err := s.restoreJenkinsProjectRoles(project.ProjectId)
+	if err != nil {
+		return err
+	}
+	err = s.enableProjectJobs(project.ProjectId, JobDisabledByArchive)
+	if err != nil {
+		return err
+	}
+	_, err = s.Ds.Db.Update(models.ProjectTableName).
+		Set(constants.StatusColumn, constants.StatusActive).
+		Where(db.Eq(models.ProjectIdColumn, project.ProjectId)).Exec()
+	if err != nil {
+		return err
+	}
+	project.Status = constants.StatusActive
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+func (s *ProjectService) ArchiveProjectHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	project, err := s.getProject(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if project.Status != constants.StatusActive {
+		err = fmt.Errorf("project [%s] is %s, only active projects can be archived", projectId, project.Status)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.archiveProject(project)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(project)
+	return
+}
+
+func (s *ProjectService) UnarchiveProjectHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	project, err := s.getProject(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if project.Status != constants.StatusArchived {
+		err = fmt.Errorf("project [%s] is not archived", projectId)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.unarchiveProject(project)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(project)
+	return
+}
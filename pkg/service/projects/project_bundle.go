@@ -0,0 +1,729 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/cryptoutils"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+)
+
+const (
+	ProjectBundleVersion = "v1"
+
+	BundleFormatJson = "json"
+	BundleFormatYaml = "yaml"
+
+	BundleConflictPolicyFail      = "fail"
+	BundleConflictPolicySkip      = "skip"
+	BundleConflictPolicyOverwrite = "overwrite"
+
+	BundleItemRole           = "role"
+	BundleItemMember         = "member"
+	BundleItemPipeline       = "pipeline"
+	BundleItemPipelineMember = "pipeline_member"
+	BundleItemCredential     = "credential"
+
+	BundleActionCreate    = "create"
+	BundleActionOverwrite = "overwrite"
+	BundleActionSkip      = "skip"
+	BundleActionConflict  = "conflict"
+	BundleActionError     = "error"
+)
+
+var BundleConflictPolicySlice = []string{BundleConflictPolicyFail, BundleConflictPolicySkip, BundleConflictPolicyOverwrite}
+
+// ProjectBundle is the portable setup of a project. Group bindings are not exported,
+// groups are managed by every environment.
+type ProjectBundle struct {
+	Version         string                  `json:"version"`
+	ExportTime      time.Time               `json:"export_time"`
+	Project         *BundleProject          `json:"project"`
+	Roles           []*CustomRoleRequest    `json:"roles"`
+	Members         []*BundleMember         `json:"members"`
+	Pipelines       []*JenkinsJobRequest    `json:"pipelines"`
+	PipelineMembers []*BundlePipelineMember `json:"pipeline_members"`
+	Credentials     []*BundleCredential     `json:"credentials"`
+	// Encryption is the algorithm of the credential secrets, secrets are omitted if it is empty
+	Encryption string `json:"encryption,omitempty"`
+}
+
+type BundleProject struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+	Extra       string `json:"extra"`
+}
+
+type BundleMember struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+type BundlePipelineMember struct {
+	PipelineId string `json:"pipeline_id"`
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+}
+
+// BundleCredential is the metadata of a credential, Secret is the encrypted content of the credential.
+// Passwords, passphrases and secret texts are exported as rendered by jenkins, which encrypts them
+// with the key of the exporting jenkins. Such secrets are not Portable and are skipped by imports.
+type BundleCredential struct {
+	Id          string `json:"id"`
+	Domain      string `json:"domain"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Secret      string `json:"secret,omitempty"`
+	Portable    bool   `json:"portable"`
+}
+
+// bundleCredentialContent is the decrypted credential of a planned item.
+type bundleCredentialContent struct {
+	Domain  string
+	Content interface{}
+}
+
+// BundleItemResult is the planned or applied action of an item of a bundle.
+type BundleItemResult struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Action  string `json:"action"`
+	Message string `json:"message,omitempty"`
+	entry   interface{}
+}
+
+// marshalProjectBundle encodes a bundle as json or yaml, yaml keeps the json field names and order.
+func marshalProjectBundle(bundle *ProjectBundle, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "", BundleFormatJson:
+		return data, nil
+	case BundleFormatYaml:
+		document := yaml.MapSlice{}
+		err = yaml.Unmarshal(data, &document)
+		if err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(document)
+	default:
+		return nil, fmt.Errorf("unsupported bundle format [%s], should be %s or %s", format, BundleFormatJson, BundleFormatYaml)
+	}
+}
+
+// yamlToJsonValue converts the maps decoded by yaml to maps with string keys.
+func yamlToJsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[fmt.Sprintf("%v", key)] = yamlToJsonValue(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range value {
+			value[i] = yamlToJsonValue(item)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// parseProjectBundle decodes a json or yaml bundle.
+func parseProjectBundle(data []byte) (*ProjectBundle, error) {
+	var document interface{}
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+	data, err = json.Marshal(yamlToJsonValue(document))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+	bundle := &ProjectBundle{}
+	err = json.Unmarshal(data, bundle)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+	if bundle.Version != ProjectBundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version [%s], should be %s", bundle.Version, ProjectBundleVersion)
+	}
+	if bundle.Project == nil {
+		return nil, fmt.Errorf("invalid bundle: project is required")
+	}
+	return bundle, nil
+}
+
+func jenkinsJobRequestName(request *JenkinsJobRequest) string {
+	name, _ := request.Define["name"].(string)
+	return name
+}
+
+// jenkinsCiphertextRegexp matches the secrets encrypted by jenkins as {AQAAABAAAAAQ...}.
+var jenkinsCiphertextRegexp = regexp.MustCompile(`^\{[A-Za-z0-9+/]+=*\}$`)
+
+// credentialContentPortable returns false if a secret of the content is encrypted by jenkins,
+// it can only be decrypted by the jenkins which rendered it.
+func credentialContentPortable(content interface{}) bool {
+	var secrets []string
+	switch content := content.(type) {
+	case *UsernamePasswordCredentialRequest:
+		secrets = []string{content.Password}
+	case *SshCredentialRequest:
+		secrets = []string{content.Passphrase, content.PrivateKey}
+	case *SecretTextCredentialRequest:
+		secrets = []string{content.Secret}
+	case *KubeconfigCredentialRequest:
+		secrets = []string{content.Content}
+	}
+	for _, secret := range secrets {
+		if jenkinsCiphertextRegexp.MatchString(strings.TrimSpace(secret)) {
+			return false
+		}
+	}
+	return true
+}
+
+// newCredentialContent returns the request of a credential type to decode a secret into.
+func newCredentialContent(credentialType string) (interface{}, error) {
+	switch credentialType {
+	case CredentialTypeUsernamePassword:
+		return &UsernamePasswordCredentialRequest{}, nil
+	case CredentialTypeSsh:
+		return &SshCredentialRequest{}, nil
+	case CredentialTypeSecretText:
+		return &SecretTextCredentialRequest{}, nil
+	case CredentialTypeKubeConfig:
+		return &KubeconfigCredentialRequest{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported credential type [%s]", credentialType)
+	}
+}
+
+// decryptBundleCredential returns the credential request of an exported credential.
+func decryptBundleCredential(credential *BundleCredential, passphrase string) (interface{}, error) {
+	content, err := newCredentialContent(credential.Type)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := cryptoutils.DecryptWithPassphrase(credential.Secret, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(plaintext, content)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// exportProject collects the setup of a project, the credential secrets are only exported with a passphrase.
+func (s *ProjectService) exportProject(project *models.Project, passphrase string) (*ProjectBundle, error) {
+	projectId := project.ProjectId
+	bundle := &ProjectBundle{
+		Version:    ProjectBundleVersion,
+		ExportTime: time.Now(),
+		Project: &BundleProject{
+			Name:        project.Name,
+			Description: project.Description,
+			Visibility:  project.Visibility,
+			Extra:       project.Extra,
+		},
+		Roles:           make([]*CustomRoleRequest, 0),
+		Members:         make([]*BundleMember, 0),
+		Pipelines:       make([]*JenkinsJobRequest, 0),
+		PipelineMembers: make([]*BundlePipelineMember, 0),
+		Credentials:     make([]*BundleCredential, 0),
+	}
+
+	projectRoles := make([]*models.ProjectRole, 0)
+	_, err := s.Ds.Db.Select(models.ProjectRoleColumns...).
+		From(models.ProjectRoleTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		Load(&projectRoles)
+	if err != nil {
+		return nil, err
+	}
+	for _, projectRole := range projectRoles {
+		customRole, err := newCustomRole(projectRole)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Roles = append(bundle.Roles, &CustomRoleRequest{
+			Name:               customRole.Name,
+			Description:        customRole.Description,
+			Permissions:        customRole.Permissions,
+			JenkinsPermissions: customRole.JenkinsPermissions,
+		})
+	}
+
+	memberships := make([]*models.ProjectMembership, 0)
+	_, err = s.Ds.Db.Select(models.ProjectMembershipColumns...).
+		From(models.ProjectMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+			db.Eq(constants.StatusColumn, constants.StatusActive))).
+		Load(&memberships)
+	if err != nil {
+		return nil, err
+	}
+	for _, membership := range memberships {
+		bundle.Members = append(bundle.Members, &BundleMember{Username: membership.Username, Role: membership.Role})
+	}
+
+	folder, err := s.Ds.Jenkins.GetFolder(projectId)
+	if err != nil {
+		return nil, err
+	}
+	for _, innerJob := range folder.Raw.Jobs {
+		job, err := s.Ds.Jenkins.GetJob(innerJob.Name, projectId)
+		if err != nil {
+			return nil, err
+		}
+		jobRequest, err := getJenkinsJobRequest(job, innerJob.Name)
+		if err != nil {
+			logger.Warn("job [%s/%s] is not exported, %+v", projectId, innerJob.Name, err)
+			continue
+		}
+		bundle.Pipelines = append(bundle.Pipelines, jobRequest)
+	}
+
+	pipelineMemberships, err := s.getPipelineMemberships(projectId)
+	if err != nil {
+		return nil, err
+	}
+	for _, pipelineMembership := range pipelineMemberships {
+		bundle.PipelineMembers = append(bundle.PipelineMembers, &BundlePipelineMember{
+			PipelineId: pipelineMembership.PipelineId,
+			Username:   pipelineMembership.Username,
+			Role:       pipelineMembership.Role,
+		})
+	}
+
+	jenkinsCredentials, err := s.Ds.Jenkins.GetCredentialsInFolder("", projectId)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase != "" {
+		bundle.Encryption = cryptoutils.PassphraseAlgorithm
+	}
+	for _, jenkinsCredential := range jenkinsCredentials {
+		credential := &BundleCredential{
+			Id:          jenkinsCredential.Id,
+			Domain:      jenkinsCredential.Domain,
+			Type:        jenkinsCredential.TypeName,
+			Description: jenkinsCredential.Description,
+		}
+		if credentialType, ok := CredentialTypeMap[jenkinsCredential.TypeName]; ok {
+			credential.Type = credentialType
+		}
+		bundle.Credentials = append(bundle.Credentials, credential)
+		if passphrase == "" {
+			continue
+		}
+		stringBody, err := s.Ds.Jenkins.GetCredentialContentInFolder(jenkinsCredential.Domain, jenkinsCredential.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		content, err := parseCredentialContent(credential.Type, stringBody, true)
+		if err != nil {
+			return nil, err
+		}
+		if content == nil {
+			continue
+		}
+		credential.Portable = credentialContentPortable(content)
+		plaintext, err := json.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		credential.Secret, err = cryptoutils.EncryptWithPassphrase(plaintext, passphrase)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bundle, nil
+}
+
+// resolveBundleConflicts applies the conflict policy to the planned items and returns the number of conflicts,
+// credentials are never overwritten.
+func resolveBundleConflicts(items []*BundleItemResult, policy string) int {
+	conflicts := 0
+	for _, item := range items {
+		if item.Action != BundleActionConflict {
+			continue
+		}
+		conflicts++
+		switch {
+		case policy == BundleConflictPolicySkip:
+			item.Action = BundleActionSkip
+		case policy == BundleConflictPolicyOverwrite && item.Kind == BundleItemCredential:
+			item.Action = BundleActionSkip
+			item.Message = "credentials are never overwritten, " + item.Message
+		case policy == BundleConflictPolicyOverwrite:
+			item.Action = BundleActionOverwrite
+		}
+	}
+	return conflicts
+}
+
+// planProjectImport compares a bundle with the project it is imported into,
+// projectId is empty if the bundle creates a new project.
+func (s *ProjectService) planProjectImport(projectId string, bundle *ProjectBundle,
+	operator, passphrase string) ([]*BundleItemResult, error) {
+	items := make([]*BundleItemResult, 0)
+
+	roleNames := make([]string, 0)
+	for _, role := range bundle.Roles {
+		item := &BundleItemResult{Kind: BundleItemRole, Name: role.Name, Action: BundleActionCreate, entry: role}
+		items = append(items, item)
+		err := validateCustomRole(role.Name, role.Permissions)
+		if err != nil {
+			item.Action, item.Message = BundleActionError, err.Error()
+			continue
+		}
+		roleNames = append(roleNames, role.Name)
+		if projectId == "" {
+			continue
+		}
+		_, err = s.getCustomRole(projectId, role.Name)
+		if err != nil && err != db.ErrNotFound {
+			return nil, err
+		}
+		if err == nil {
+			item.Action, item.Message = BundleActionConflict, fmt.Sprintf("role [%s] already exists", role.Name)
+		}
+	}
+
+	checkRole := func(role string) error {
+		if reflectutils.In(role, roleNames) || reflectutils.In(role, AllRoleSlice) {
+			return nil
+		}
+		if projectId == "" {
+			return fmt.Errorf("role [%s] is neither a built-in role nor a role of the bundle", role)
+		}
+		return s.checkProjectRoleExists(projectId, role)
+	}
+
+	for _, member := range bundle.Members {
+		item := &BundleItemResult{Kind: BundleItemMember, Name: member.Username, Action: BundleActionCreate, entry: member}
+		items = append(items, item)
+		if member.Username == operator {
+			item.Action, item.Message = BundleActionSkip, "the importing user keeps the current role"
+			continue
+		}
+		err := checkRole(member.Role)
+		if err != nil {
+			item.Action, item.Message = BundleActionError, err.Error()
+			continue
+		}
+		if projectId == "" {
+			continue
+		}
+		_, err = s.getProjectMembership(projectId, member.Username)
+		if err != nil && err != db.ErrNotFound {
+			return nil, err
+		}
+		if err == nil {
+			item.Action, item.Message = BundleActionConflict,
+				fmt.Sprintf("user [%s] is already a member", member.Username)
+		}
+	}
+
+	pipelineNames := make([]string, 0)
+	for _, pipeline := range bundle.Pipelines {
+		name := jenkinsJobRequestName(pipeline)
+		item := &BundleItemResult{Kind: BundleItemPipeline, Name: name, Action: BundleActionCreate, entry: pipeline}
+		items = append(items, item)
+		_, _, err := jenkinsJobConfig(projectId, pipeline)
+		if err != nil {
+			item.Action, item.Message = BundleActionError, err.Error()
+			continue
+		}
+		pipelineNames = append(pipelineNames, name)
+		if projectId == "" {
+			continue
+		}
+		job, err := s.Ds.Jenkins.GetJob(name, projectId)
+		if err != nil && stringutils.GetJenkinsStatusCode(err) != http.StatusNotFound {
+			return nil, err
+		}
+		if job != nil {
+			item.Action, item.Message = BundleActionConflict, fmt.Sprintf("job name [%s] has been used", name)
+		}
+	}
+
+	for _, pipelineMember := range bundle.PipelineMembers {
+		item := &BundleItemResult{Kind: BundleItemPipelineMember,
+			Name:   fmt.Sprintf("%s/%s", pipelineMember.PipelineId, pipelineMember.Username),
+			Action: BundleActionCreate, entry: pipelineMember}
+		items = append(items, item)
+		err := checkRole(pipelineMember.Role)
+		if err != nil {
+			item.Action, item.Message = BundleActionError, err.Error()
+			continue
+		}
+		if !reflectutils.In(pipelineMember.PipelineId, pipelineNames) {
+			item.Action, item.Message = BundleActionError,
+				fmt.Sprintf("pipeline [%s] is not imported", pipelineMember.PipelineId)
+			continue
+		}
+		if projectId == "" {
+			continue
+		}
+		_, err = s.getPipelineMembership(projectId, pipelineMember.PipelineId, pipelineMember.Username)
+		if err != nil && err != db.ErrNotFound {
+			return nil, err
+		}
+		if err == nil {
+			item.Action, item.Message = BundleActionConflict,
+				fmt.Sprintf("user [%s] has been added to pipeline", pipelineMember.Username)
+		}
+	}
+
+	for _, credential := range bundle.Credentials {
+		item := &BundleItemResult{Kind: BundleItemCredential, Name: credential.Id, Action: BundleActionCreate}
+		items = append(items, item)
+		if credential.Secret == "" {
+			item.Action, item.Message = BundleActionSkip, "secret is not exported, create the credential manually"
+			continue
+		}
+		if passphrase == "" {
+			item.Action, item.Message = BundleActionSkip, "passphrase is required to import the secret"
+			continue
+		}
+		content, err := decryptBundleCredential(credential, passphrase)
+		if err != nil {
+			item.Action, item.Message = BundleActionError, err.Error()
+			continue
+		}
+		if !credential.Portable || !credentialContentPortable(content) {
+			item.Action, item.Message = BundleActionSkip,
+				"secret is encrypted by the exporting jenkins and can not be imported, create the credential manually"
+			continue
+		}
+		item.entry = &bundleCredentialContent{Domain: credential.Domain, Content: content}
+		if projectId == "" {
+			continue
+		}
+		jenkinsCredential, err := s.Ds.Jenkins.GetCredentialInFolder(credential.Domain, credential.Id, projectId)
+		if err != nil && stringutils.GetJenkinsStatusCode(err) != http.StatusNotFound {
+			return nil, err
+		}
+		if jenkinsCredential != nil {
+			item.Action, item.Message = BundleActionConflict,
+				fmt.Sprintf("credential id [%s] has been used", credential.Id)
+		}
+	}
+	return items, nil
+}
+
+// applyProjectImport executes the planned items in order, a failed item does not stop the others.
+func (s *ProjectService) applyProjectImport(projectId string, items []*BundleItemResult, operator string) {
+	restrictedPipelines := false
+	for _, item := range items {
+		if item.Action != BundleActionCreate && item.Action != BundleActionOverwrite {
+			continue
+		}
+		var err error
+		switch entry := item.entry.(type) {
+		case *CustomRoleRequest:
+			err = s.importCustomRole(projectId, operator, entry, item.Action == BundleActionOverwrite)
+		case *BundleMember:
+			err = s.importMember(projectId, operator, entry, item.Action == BundleActionOverwrite)
+		case *JenkinsJobRequest:
+			err = s.importPipeline(projectId, entry, item.Action == BundleActionOverwrite)
+		case *BundlePipelineMember:
+			err = s.importPipelineMember(projectId, operator, entry, item.Action == BundleActionOverwrite)
+			restrictedPipelines = restrictedPipelines || err == nil
+		case *bundleCredentialContent:
+			_, err = s.importCredential(projectId, entry.Domain, operator, entry.Content)
+		}
+		if err != nil {
+			logger.Warn("failed to import %s [%s] into project [%s], %+v", item.Kind, item.Name, projectId, err)
+			item.Action, item.Message = BundleActionError, err.Error()
+		}
+	}
+	if restrictedPipelines {
+		err := s.syncJenkinsPipelineRoles(projectId)
+		if err != nil {
+			logger.Error("failed to sync pipeline roles of project [%s], %+v", projectId, err)
+		}
+	}
+}
+
+func (s *ProjectService) importCustomRole(projectId, operator string, request *CustomRoleRequest, overwrite bool) error {
+	oldRole := &CustomRole{ProjectRole: &models.ProjectRole{Creator: operator}}
+	if overwrite {
+		var err error
+		oldRole, err = s.getCustomRole(projectId, request.Name)
+		if err != nil {
+			return err
+		}
+	}
+	customRole, err := newCustomRoleFromRequest(projectId, oldRole.Creator, request)
+	if err != nil {
+		return err
+	}
+	err = s.syncJenkinsCustomRole(customRole)
+	if err != nil {
+		return err
+	}
+	if !overwrite {
+		_, err = s.Ds.Db.InsertInto(models.ProjectRoleTableName).
+			Columns(models.ProjectRoleColumns...).
+			Record(customRole.ProjectRole).Exec()
+		return err
+	}
+	_, err = s.Ds.Db.Update(models.ProjectRoleTableName).
+		Set(models.ProjectRoleDescriptionColumn, customRole.Description).
+		Set(models.ProjectRolePermissionsColumn, customRole.ProjectRole.Permissions).
+		Set(models.ProjectRoleJenkinsPermissionsColumn, customRole.ProjectRole.JenkinsPermissions).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectRoleNameColumn, request.Name))).Exec()
+	return err
+}
+
+// importMember invites a new member, the role of an existing member is changed in place.
+func (s *ProjectService) importMember(projectId, operator string, member *BundleMember, overwrite bool) error {
+	if !overwrite {
+		err := s.checkProjectQuota(projectId, QuotaMembers)
+		if err != nil {
+			return err
+		}
+		membership := models.NewProjectInvitation(member.Username, projectId, member.Role, operator,
+			time.Now().Add(ProjectInvitationTTL))
+		_, err = s.Ds.Db.InsertInto(models.ProjectMembershipTableName).
+			Columns(models.ProjectMembershipColumns...).
+			Record(membership).Exec()
+		return err
+	}
+	oldMembership, err := s.getProjectMembership(projectId, member.Username)
+	if err != nil {
+		return err
+	}
+	if oldMembership.Role == member.Role {
+		return nil
+	}
+	if oldMembership.Role == ProjectOwner {
+		err = s.checkProjectKeepsOwner(oldMembership)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = s.Ds.Db.Update(models.ProjectMembershipTableName).
+		Set(models.ProjectMembershipRoleColumn, member.Role).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+			db.Eq(models.ProjectMembershipUsernameColumn, member.Username))).Exec()
+	if err != nil {
+		return err
+	}
+	if oldMembership.Status != constants.StatusActive {
+		return nil
+	}
+	return s.reconcileJenkinsUserRoles(projectId, member.Username, []string{oldMembership.Role, member.Role})
+}
+
+func (s *ProjectService) importPipeline(projectId string, request *JenkinsJobRequest, overwrite bool) error {
+	err := s.checkRetainedBuildsQuota(projectId, request)
+	if err != nil {
+		return err
+	}
+	name, config, err := jenkinsJobConfig(projectId, request)
+	if err != nil {
+		return err
+	}
+	if !overwrite {
+		err = s.checkProjectQuota(projectId, QuotaPipelines)
+		if err != nil {
+			return err
+		}
+		_, err = s.Ds.Jenkins.CreateJobInFolder(config, name, projectId)
+		return err
+	}
+	job, err := s.Ds.Jenkins.GetJob(name, projectId)
+	if err != nil {
+		return err
+	}
+	return job.UpdateConfig(config)
+}
+
+// importPipelineMember records a pipeline membership, the jenkins role is assigned
+// if the user is already a member and otherwise when the user accepts the invitation.
+func (s *ProjectService) importPipelineMember(projectId, operator string, member *BundlePipelineMember, overwrite bool) error {
+	if overwrite {
+		oldMembership, err := s.getPipelineMembership(projectId, member.PipelineId, member.Username)
+		if err != nil {
+			return err
+		}
+		if oldMembership.Role == member.Role {
+			return nil
+		}
+		pipelineRole, err := s.Ds.Jenkins.GetProjectRole(
+			GetPipelineMemberRoleName(projectId, member.PipelineId, oldMembership.Role))
+		if err != nil {
+			return err
+		}
+		if pipelineRole != nil {
+			err = pipelineRole.UnAssignRole(member.Username)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = s.Ds.Db.Update(models.PipelineMembershipTableName).
+			Set(models.ProjectMembershipRoleColumn, member.Role).
+			Where(db.And(
+				db.Eq(models.ProjectIdColumn, projectId),
+				db.Eq(models.PipelineMembershipPipelineIdColumn, member.PipelineId),
+				db.Eq(models.ProjectMembershipUsernameColumn, member.Username))).Exec()
+		if err != nil {
+			return err
+		}
+	} else {
+		membership := models.NewPipelineMembership(projectId, member.PipelineId, member.Username, member.Role, operator)
+		_, err := s.Ds.Db.InsertInto(models.PipelineMembershipTableName).
+			Columns(models.PipelineMembershipColumns...).
+			Record(membership).Exec()
+		if err != nil {
+			return err
+		}
+	}
+	_, err := s.getProjectUserRole(member.Username, projectId)
+	if err == db.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.assignJenkinsPipelineMemberRoles(projectId, member.Username)
+}
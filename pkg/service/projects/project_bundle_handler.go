@@ -0,0 +1,204 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+// BundlePassphraseHeader carries the passphrase of the credential secrets,
+// it is a header so that the passphrase is not logged with the url.
+const BundlePassphraseHeader = "X-Bundle-Passphrase"
+
+type ImportProjectResponse struct {
+	ProjectId string              `json:"project_id,omitempty"`
+	DryRun    bool                `json:"dry_run"`
+	Items     []*BundleItemResult `json:"items"`
+}
+
+// ExportProjectHandler returns the bundle of a project as json or yaml with ?format=yaml,
+// the credential secrets are encrypted with the passphrase header and omitted without it.
+func (s *ProjectService) ExportProjectHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	format := r.URL.Query().Get("format")
+	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	project, err := s.getProject(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	bundle, err := s.exportProject(project, r.Header.Get(BundlePassphraseHeader))
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	data, err := marshalProjectBundle(bundle, format)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format == BundleFormatYaml {
+		w.Header().Set("Content-Type", "application/x-yaml")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.(http.ResponseWriter).Write(data)
+	return
+}
+
+// ImportProjectHandler creates a project from the json or yaml bundle in the body,
+// or imports it into the project of ?project_id= without changing the project itself.
+// With ?dry_run=true the planned items are returned and nothing is changed.
+// Conflicting items fail the import unless ?conflict_policy= is skip or overwrite.
+func (s *ProjectService) ImportProjectHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	projectId := r.URL.Query().Get("project_id")
+	name := r.URL.Query().Get("name")
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	conflictPolicy := r.URL.Query().Get("conflict_policy")
+	passphrase := r.Header.Get(BundlePassphraseHeader)
+	if govalidator.IsNull(conflictPolicy) {
+		conflictPolicy = BundleConflictPolicyFail
+	}
+	if !reflectutils.In(conflictPolicy, BundleConflictPolicySlice) {
+		err := fmt.Errorf("error conflict policy [%s] not in %s", conflictPolicy, BundleConflictPolicySlice)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	bundle, err := parseProjectBundle(data)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(name) {
+		name = bundle.Project.Name
+	}
+	if !govalidator.IsNull(bundle.Project.Visibility) && !reflectutils.In(bundle.Project.Visibility, VisibilitySlice) {
+		err := fmt.Errorf("error visibility [%s] not in %s", bundle.Project.Visibility, VisibilitySlice)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if govalidator.IsNull(projectId) {
+		if !s.canCreateProject(operator) {
+			err := fmt.Errorf("user [%s] can not create project", operator)
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	} else {
+		err = s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		err = s.checkProjectNotArchived(projectId)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	items, err := s.planProjectImport(projectId, bundle, operator, passphrase)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	conflicts := resolveBundleConflicts(items, conflictPolicy)
+	if dryRun {
+		w.WriteJson(&ImportProjectResponse{ProjectId: projectId, DryRun: true, Items: items})
+		return
+	}
+	for _, item := range items {
+		if item.Action == BundleActionError {
+			err := fmt.Errorf("error %s [%s] can not be imported, %s", item.Kind, item.Name, item.Message)
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if conflicts > 0 && conflictPolicy == BundleConflictPolicyFail {
+		err := fmt.Errorf("error bundle has %d conflicts, list them with dry_run or set conflict_policy to %s or %s",
+			conflicts, BundleConflictPolicySkip, BundleConflictPolicyOverwrite)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if govalidator.IsNull(projectId) {
+		project := models.NewProject(name, bundle.Project.Description, operator, bundle.Project.Extra)
+		failedStep, err := s.createProject(project, GetOperationStepRecorder(r))
+		if err != nil {
+			logger.Error("%+v", err)
+			if failedStep == "" {
+				rest.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			rest.Error(w, fmt.Sprintf("failed to create project at step [%s], %s", failedStep, err.Error()),
+				stringutils.GetJenkinsStatusCode(err))
+			return
+		}
+		projectId = project.ProjectId
+		s.applyProjectImport(projectId, items, operator)
+		if bundle.Project.Visibility == constants.VisibilityPublic {
+			_, err = s.Ds.Db.Update(models.ProjectTableName).
+				Set(models.ProjectVisibilityColumn, constants.VisibilityPublic).
+				Where(db.Eq(models.ProjectIdColumn, projectId)).Exec()
+			if err == nil {
+				err = s.SyncJenkinsPublicRole()
+			}
+			if err != nil {
+				logger.Error("failed to make project [%s] public, %+v", projectId, err)
+			}
+		}
+	} else {
+		s.applyProjectImport(projectId, items, operator)
+	}
+	w.WriteJson(&ImportProjectResponse{ProjectId: projectId, Items: items})
+	return
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"kubesphere.io/devops/pkg/utils/cryptoutils"
+)
+
+func TestProjectBundleFormats(t *testing.T) {
+	bundle := &ProjectBundle{
+		Version:    ProjectBundleVersion,
+		ExportTime: time.Date(2018, 10, 8, 12, 0, 0, 0, time.UTC),
+		Project:    &BundleProject{Name: "demo", Visibility: "private"},
+		Members:    []*BundleMember{{Username: "admin", Role: ProjectOwner}},
+		Pipelines: []*JenkinsJobRequest{{Type: JenkinsJobMultiBranchPipeline, Define: map[string]interface{}{
+			"name":   "build",
+			"source": map[string]interface{}{"type": "git", "define": map[string]interface{}{"url": "https://example.com/demo.git"}},
+		}}},
+		Credentials: []*BundleCredential{{Id: "token", Domain: "_", Type: CredentialTypeSecretText}},
+	}
+	for _, format := range []string{BundleFormatJson, BundleFormatYaml} {
+		data, err := marshalProjectBundle(bundle, format)
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+		parsed, err := parseProjectBundle(data)
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+		if !reflect.DeepEqual(parsed, bundle) {
+			expected, _ := json.Marshal(bundle)
+			got, _ := json.Marshal(parsed)
+			t.Fatalf("%s bundle should be %s, got %s", format, expected, got)
+		}
+	}
+	if _, err := marshalProjectBundle(bundle, "xml"); err == nil {
+		t.Fatalf("unsupported format should get error")
+	}
+	if _, err := parseProjectBundle([]byte("version: v0\nproject:\n  name: demo\n")); err == nil {
+		t.Fatalf("unsupported version should get error")
+	}
+}
+
+func TestDecryptBundleCredential(t *testing.T) {
+	secret, err := cryptoutils.EncryptWithPassphrase([]byte(`{"id":"token","secret":"s3cret"}`), "passphrase")
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	credential := &BundleCredential{Id: "token", Type: CredentialTypeSecretText, Secret: secret}
+	content, err := decryptBundleCredential(credential, "passphrase")
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	expected := &SecretTextCredentialRequest{Id: "token", Secret: "s3cret"}
+	if !reflect.DeepEqual(content, expected) {
+		t.Fatalf("content should be %+v, got %+v", expected, content)
+	}
+	if _, err := decryptBundleCredential(credential, "wrong"); err == nil {
+		t.Fatalf("wrong passphrase should get error")
+	}
+}
+
+func TestCredentialContentPortable(t *testing.T) {
+	portable := []interface{}{
+		&SecretTextCredentialRequest{Id: "token", Secret: "s3cret"},
+		&UsernamePasswordCredentialRequest{Id: "git", Username: "git", Password: "{not encrypted}"},
+		&KubeconfigCredentialRequest{Id: "kubeconfig", Content: "apiVersion: v1"},
+	}
+	for _, content := range portable {
+		if !credentialContentPortable(content) {
+			t.Fatalf("%+v should be portable", content)
+		}
+	}
+	encrypted := []interface{}{
+		&SecretTextCredentialRequest{Id: "token", Secret: "{AQAAABAAAAAQqU+m+mC6ZnLa0+yaanj2eBSbTk+h4P5omjKdwV17vcA=}"},
+		&SshCredentialRequest{Id: "ssh", Username: "git", PrivateKey: "{AQAAABAAAAAwlo8e5n6S0mT1Pvb2xeg==}"},
+	}
+	for _, content := range encrypted {
+		if credentialContentPortable(content) {
+			t.Fatalf("%+v should not be portable", content)
+		}
+	}
+}
+
+func TestResolveBundleConflicts(t *testing.T) {
+	newItems := func() []*BundleItemResult {
+		return []*BundleItemResult{
+			{Kind: BundleItemPipeline, Name: "build", Action: BundleActionConflict},
+			{Kind: BundleItemCredential, Name: "token", Action: BundleActionConflict},
+			{Kind: BundleItemMember, Name: "tester", Action: BundleActionCreate},
+		}
+	}
+	for _, item := range []struct {
+		policy   string
+		expected []string
+	}{
+		{BundleConflictPolicyFail, []string{BundleActionConflict, BundleActionConflict, BundleActionCreate}},
+		{BundleConflictPolicySkip, []string{BundleActionSkip, BundleActionSkip, BundleActionCreate}},
+		{BundleConflictPolicyOverwrite, []string{BundleActionOverwrite, BundleActionSkip, BundleActionCreate}},
+	} {
+		items := newItems()
+		if conflicts := resolveBundleConflicts(items, item.policy); conflicts != 2 {
+			t.Fatalf("conflicts should be 2, got %d", conflicts)
+		}
+		actions := make([]string, 0)
+		for _, result := range items {
+			actions = append(actions, result.Action)
+		}
+		if !reflect.DeepEqual(actions, item.expected) {
+			t.Fatalf("actions of policy [%s] should be %v, got %v", item.policy, item.expected, actions)
+		}
+	}
+}
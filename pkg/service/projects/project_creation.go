@@ -0,0 +1,272 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+)
+
+const (
+	CreationStepCreateFolder     = "create_folder"
+	CreationStepCreateRoles      = "create_roles"
+	CreationStepAssignOwner      = "assign_owner"
+	CreationStepCreateMembership = "create_membership"
+
+	creationStepAttempts = 3
+)
+
+var creationStepRetryInterval = time.Second
+
+// creationStep is a step of the project creation saga,
+// Compensate undoes a successful or partially applied step and is nil if there is nothing to undo.
+// It must be idempotent because a failed step may have applied nothing.
+type creationStep struct {
+	Name       string
+	Run        func() error
+	Compensate func() error
+}
+
+// creationRecorder records the status of the saga steps.
+type creationRecorder func(step, status, message string)
+
+func retryCreationStep(fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= creationStepAttempts; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		// jenkins client errors will not succeed on retry
+		if code := stringutils.GetJenkinsStatusCode(err); code >= http.StatusBadRequest && code < http.StatusInternalServerError {
+			return err
+		}
+		if attempt < creationStepAttempts {
+			time.Sleep(creationStepRetryInterval)
+		}
+	}
+	return err
+}
+
+// runCreationSaga runs the steps in order, a failed step is retried and then the failed step
+// and the successful steps are compensated in reverse order. The failed step is returned with its error.
+func runCreationSaga(steps []*creationStep, record creationRecorder) (string, error) {
+	for i, step := range steps {
+		record(step.Name, constants.StatusWorking, "")
+		err := retryCreationStep(step.Run)
+		if err == nil {
+			record(step.Name, constants.StatusSuccessful, "")
+			continue
+		}
+		record(step.Name, constants.StatusFailed, err.Error())
+		for j := i; j >= 0; j-- {
+			if steps[j].Compensate == nil {
+				continue
+			}
+			compensateErr := retryCreationStep(steps[j].Compensate)
+			if compensateErr != nil {
+				logger.Error("failed to roll back step [%s], %+v", steps[j].Name, compensateErr)
+				record(steps[j].Name, constants.StatusFailed, fmt.Sprintf("rollback failed, %s", compensateErr.Error()))
+				continue
+			}
+			// the failed step keeps its failed status
+			if j < i {
+				record(steps[j].Name, constants.StatusDeleted, "")
+			}
+		}
+		return step.Name, err
+	}
+	return "", nil
+}
+
+func (s *ProjectService) projectCreationSteps(project *models.Project) []*creationStep {
+	projectId := project.ProjectId
+	roleNames := make([]string, 0)
+	for role := range JenkinsProjectPermissionMap {
+		roleNames = append(roleNames, GetProjectRoleName(projectId, role))
+	}
+	for role := range JenkinsPipelinePermissionMap {
+		roleNames = append(roleNames, GetPipelineRoleName(projectId, role))
+	}
+	return []*creationStep{
+		{
+			Name: CreationStepCreateFolder,
+			Run: func() error {
+				_, err := s.Ds.Jenkins.CreateFolder(projectId, project.Description)
+				return err
+			},
+			Compensate: func() error {
+				_, err := s.Ds.Jenkins.DeleteJob(projectId)
+				if err != nil && stringutils.GetJenkinsStatusCode(err) == http.StatusNotFound {
+					return nil
+				}
+				return err
+			},
+		},
+		{
+			Name: CreationStepCreateRoles,
+			Run: func() error {
+				return s.createJenkinsProjectRoles(projectId)
+			},
+			Compensate: func() error {
+				return s.Ds.Jenkins.DeleteProjectRoles(roleNames...)
+			},
+		},
+		{
+			// the owner roles are deleted with the project roles
+			Name: CreationStepAssignOwner,
+			Run: func() error {
+				err := s.assignJenkinsAllUserRole(project.Creator)
+				if err != nil {
+					return err
+				}
+				return s.setJenkinsRoleAssignment(projectId, ProjectOwner, project.Creator, true)
+			},
+		},
+		{
+			Name: CreationStepCreateMembership,
+			Run: func() error {
+				projectMembership := models.NewProjectMemberShip(project.Creator, projectId, ProjectOwner, project.Creator)
+				_, err := s.Ds.Db.InsertInto(models.ProjectMembershipTableName).
+					Columns(models.ProjectMembershipColumns...).Record(projectMembership).Exec()
+				return err
+			},
+			Compensate: func() error {
+				_, err := s.Ds.Db.DeleteFrom(models.ProjectMembershipTableName).
+					Where(db.Eq(models.ProjectMembershipProjectIdColumn, projectId)).Exec()
+				return err
+			},
+		},
+	}
+}
+
+// createJenkinsProjectRoles creates the jenkins roles of the built-in project roles concurrently.
+func (s *ProjectService) createJenkinsProjectRoles(projectId string) error {
+	var addRoleCh = make(chan *ProjectRoleResponse, len(JenkinsProjectPermissionMap)+len(JenkinsPipelinePermissionMap))
+	var addRoleWg sync.WaitGroup
+	for role, permission := range JenkinsProjectPermissionMap {
+		addRoleWg.Add(1)
+		go func(role string, permission gojenkins.ProjectPermissionIds) {
+			_, err := s.Ds.Jenkins.AddProjectRole(GetProjectRoleName(projectId, role),
+				GetProjectRolePattern(projectId), permission, true)
+			addRoleCh <- &ProjectRoleResponse{nil, err}
+			addRoleWg.Done()
+		}(role, permission)
+	}
+	for role, permission := range JenkinsPipelinePermissionMap {
+		addRoleWg.Add(1)
+		go func(role string, permission gojenkins.ProjectPermissionIds) {
+			_, err := s.Ds.Jenkins.AddProjectRole(GetPipelineRoleName(projectId, role),
+				GetPipelineRolePattern(projectId), permission, true)
+			addRoleCh <- &ProjectRoleResponse{nil, err}
+			addRoleWg.Done()
+		}(role, permission)
+	}
+	addRoleWg.Wait()
+	close(addRoleCh)
+	for addRoleResponse := range addRoleCh {
+		if addRoleResponse.Err != nil {
+			return addRoleResponse.Err
+		}
+	}
+	return nil
+}
+
+func (s *ProjectService) setProjectStatus(projectId, status string) error {
+	_, err := s.Ds.Db.Update(models.ProjectTableName).
+		Set(constants.StatusColumn, status).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).Exec()
+	return err
+}
+
+// recordProjectCreationStep returns the recorder of the creation steps,
+// they are also reported to the operation if the project is created by an operation.
+func (s *ProjectService) recordProjectCreationStep(projectId string, operationRecorder OperationStepRecorder) creationRecorder {
+	return func(step, status, message string) {
+		if operationRecorder != nil {
+			operationRecorder(step, status, message)
+		}
+		_, err := s.Ds.Db.Update(models.ProjectCreationStepTableName).
+			Set(constants.StatusColumn, status).
+			Set(models.ProjectCreationStepMessageColumn, message).
+			Set(constants.StatusTimeColumn, time.Now()).
+			Where(db.And(
+				db.Eq(models.ProjectIdColumn, projectId),
+				db.Eq(models.ProjectCreationStepStepColumn, step))).Exec()
+		if err != nil {
+			logger.Error("failed to record step [%s] of project [%s], %+v", step, projectId, err)
+		}
+	}
+}
+
+// createProject inserts the pending project and its steps, then runs the creation saga.
+// The project is active once all steps succeed, otherwise it is failed and
+// its steps show where the creation stopped.
+func (s *ProjectService) createProject(project *models.Project, operationRecorder OperationStepRecorder) (string, error) {
+	project.Status = constants.StatusPending
+	_, err := s.Ds.Db.InsertInto(models.ProjectTableName).
+		Columns(models.ProjectColumns...).Record(project).Exec()
+	if err != nil {
+		return "", err
+	}
+	steps := s.projectCreationSteps(project)
+	for i, step := range steps {
+		_, err = s.Ds.Db.InsertInto(models.ProjectCreationStepTableName).
+			Columns(models.ProjectCreationStepColumns...).
+			Record(models.NewProjectCreationStep(project.ProjectId, step.Name, i)).Exec()
+		if err != nil {
+			return "", err
+		}
+	}
+	err = s.setProjectStatus(project.ProjectId, constants.StatusWorking)
+	if err != nil {
+		return "", err
+	}
+	failedStep, err := runCreationSaga(steps, s.recordProjectCreationStep(project.ProjectId, operationRecorder))
+	status := constants.StatusActive
+	if err != nil {
+		status = constants.StatusFailed
+	}
+	project.Status = status
+	statusErr := s.setProjectStatus(project.ProjectId, status)
+	if err != nil {
+		if statusErr != nil {
+			logger.Error("failed to set status of project [%s], %+v", project.ProjectId, statusErr)
+		}
+		return failedStep, err
+	}
+	return "", statusErr
+}
+
+func (s *ProjectService) getProjectCreationSteps(projectId string) ([]*models.ProjectCreationStep, error) {
+	steps := make([]*models.ProjectCreationStep, 0)
+	_, err := s.Ds.Db.Select(models.ProjectCreationStepColumns...).
+		From(models.ProjectCreationStepTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		OrderDir(models.ProjectCreationStepSeqColumn, true).
+		Load(&steps)
+	if err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
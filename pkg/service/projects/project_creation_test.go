@@ -0,0 +1,137 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"kubesphere.io/devops/pkg/constants"
+)
+
+func TestRunCreationSaga(t *testing.T) {
+	creationStepRetryInterval = 0
+	calls := make([]string, 0)
+	records := make([]string, 0)
+	record := func(step, status, message string) {
+		records = append(records, step+":"+status)
+	}
+	newStep := func(name string, failures int, compensate bool) *creationStep {
+		step := &creationStep{
+			Name: name,
+			Run: func() error {
+				calls = append(calls, "run "+name)
+				if failures > 0 {
+					failures--
+					return fmt.Errorf("step [%s] failed", name)
+				}
+				return nil
+			},
+		}
+		if compensate {
+			step.Compensate = func() error {
+				calls = append(calls, "compensate "+name)
+				return nil
+			}
+		}
+		return step
+	}
+
+	failedStep, err := runCreationSaga([]*creationStep{
+		newStep("folder", 0, true),
+		newStep("roles", 1, true),
+	}, record)
+	if err != nil || failedStep != "" {
+		t.Fatalf("saga should succeed after retry, got [%s] %+v", failedStep, err)
+	}
+	if !reflect.DeepEqual(calls, []string{"run folder", "run roles", "run roles"}) {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	calls = calls[:0]
+	records = records[:0]
+	failedStep, err = runCreationSaga([]*creationStep{
+		newStep("folder", 0, true),
+		newStep("owner", 0, false),
+		newStep("roles", creationStepAttempts, true),
+		newStep("membership", 0, true),
+	}, record)
+	if err == nil || failedStep != "roles" {
+		t.Fatalf("saga should fail at step [roles], got [%s] %+v", failedStep, err)
+	}
+	expectedCalls := []string{"run folder", "run owner", "run roles", "run roles", "run roles", "compensate roles", "compensate folder"}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Fatalf("calls should be %v, got %v", expectedCalls, calls)
+	}
+	expectedRecords := []string{
+		"folder:" + constants.StatusWorking, "folder:" + constants.StatusSuccessful,
+		"owner:" + constants.StatusWorking, "owner:" + constants.StatusSuccessful,
+		"roles:" + constants.StatusWorking, "roles:" + constants.StatusFailed,
+		"folder:" + constants.StatusDeleted,
+	}
+	if !reflect.DeepEqual(records, expectedRecords) {
+		t.Fatalf("records should be %v, got %v", expectedRecords, records)
+	}
+}
+
+func TestRunCreationSagaPartiallyFailedStep(t *testing.T) {
+	creationStepRetryInterval = 0
+	roles := make(map[string]bool)
+	folder := false
+	steps := []*creationStep{
+		{
+			Name: CreationStepCreateFolder,
+			Run: func() error {
+				folder = true
+				return nil
+			},
+			Compensate: func() error {
+				folder = false
+				return nil
+			},
+		},
+		{
+			// some roles are created before the step fails
+			Name: CreationStepCreateRoles,
+			Run: func() error {
+				roles["demo-owner"] = true
+				roles["demo-maintainer"] = true
+				return fmt.Errorf("failed to create role [demo-developer]")
+			},
+			Compensate: func() error {
+				for _, name := range []string{"demo-owner", "demo-maintainer", "demo-developer"} {
+					delete(roles, name)
+				}
+				return nil
+			},
+		},
+	}
+	statuses := make(map[string]string)
+	failedStep, err := runCreationSaga(steps, func(step, status, message string) {
+		statuses[step] = status
+	})
+	if err == nil || failedStep != CreationStepCreateRoles {
+		t.Fatalf("saga should fail at step [%s], got [%s] %+v", CreationStepCreateRoles, failedStep, err)
+	}
+	if len(roles) != 0 {
+		t.Fatalf("roles created by the failed step should be deleted, got %v", roles)
+	}
+	if folder {
+		t.Fatalf("folder should be deleted")
+	}
+	if statuses[CreationStepCreateRoles] != constants.StatusFailed || statuses[CreationStepCreateFolder] != constants.StatusDeleted {
+		t.Fatalf("unexpected step statuses %v", statuses)
+	}
+}
@@ -14,8 +14,20 @@ limitations under the License.
 package projects
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-sql-driver/mysql"
+
+	"kubesphere.io/devops/pkg/db"
 	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/logger"
 	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/kubeconfigutils"
+	"kubesphere.io/devops/pkg/utils/sshutils"
 )
 
 var CredentialTypeMap = map[string]string{
@@ -25,6 +37,211 @@ var CredentialTypeMap = map[string]string{
 	"Kubernetes configuration (kubeconfig)": CredentialTypeKubeConfig,
 }
 
+// CredentialKeyInfo is the key material metadata tracked in project_credential,
+// it never contains any secret.
+type CredentialKeyInfo struct {
+	Fingerprint string
+	Algorithm   string
+	NotAfter    *time.Time
+	Kubeconfig  *kubeconfigutils.Info
+}
+
+func (info *CredentialKeyInfo) applyTo(projectCredential *models.ProjectCredential) {
+	if info == nil {
+		return
+	}
+	projectCredential.KeyFingerprint = info.Fingerprint
+	projectCredential.KeyAlgorithm = info.Algorithm
+	projectCredential.NotAfter = info.NotAfter
+	projectCredential.KubeconfigInfo = ""
+	if info.Kubeconfig != nil {
+		kubeconfigInfo, _ := json.Marshal(info.Kubeconfig)
+		projectCredential.KubeconfigInfo = string(kubeconfigInfo)
+	}
+}
+
+func getSshKeyInfo(privateKey, passphrase string) *CredentialKeyInfo {
+	publicKey, err := sshutils.ParsePrivateKey([]byte(privateKey), passphrase)
+	if err != nil {
+		logger.Warn("failed to parse ssh private key, %+v", err)
+		return nil
+	}
+	return &CredentialKeyInfo{
+		Fingerprint: publicKey.Fingerprint(),
+		Algorithm:   publicKey.Algorithm,
+	}
+}
+
+// inspectKubeconfig validates the kubeconfig and returns its summary,
+// the client certificate which expires first is tracked as key.
+func inspectKubeconfig(content string, check bool) (*CredentialKeyInfo, error) {
+	config, err := kubeconfigutils.Load(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %v", err)
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	kubeconfigInfo, err := config.Inspect(check)
+	if err != nil {
+		return nil, err
+	}
+	info := &CredentialKeyInfo{Kubeconfig: kubeconfigInfo}
+	if certificate := kubeconfigInfo.EarliestCertificate(); certificate != nil {
+		info.Fingerprint = certificate.Fingerprint
+		info.Algorithm = certificate.Algorithm
+		info.NotAfter = &certificate.NotAfter
+	}
+	return info, nil
+}
+
+const mysqlDuplicateEntryErrorNumber = 1062
+
+func isDuplicateEntryError(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == mysqlDuplicateEntryErrorNumber
+}
+
+// insertProjectCredential inserts the project_credential row of a credential created in jenkins.
+// The credential reconcile may have backfilled the row between the jenkins creation and the insert,
+// the backfilled row is then taken over so it keeps the real creator.
+func (s *ProjectService) insertProjectCredential(projectCredential *models.ProjectCredential) error {
+	_, err := s.Ds.Db.InsertInto(models.ProjectCredentialTableName).
+		Columns(models.ProjectCredentialColumns...).
+		Record(projectCredential).Exec()
+	if !isDuplicateEntryError(err) {
+		return err
+	}
+	result, updateErr := s.Ds.Db.Update(models.ProjectCredentialTableName).
+		Set(models.ProjectCredentialCreatorColumn, projectCredential.Creator).
+		Set(models.ProjectCredentialCreateTimeColumn, projectCredential.CreateTime).
+		Set(models.ProjectCredentialKeyFingerprintColumn, projectCredential.KeyFingerprint).
+		Set(models.ProjectCredentialKeyAlgorithmColumn, projectCredential.KeyAlgorithm).
+		Set(models.ProjectCredentialNotAfterColumn, projectCredential.NotAfter).
+		Set(models.ProjectCredentialRotateTimeColumn, projectCredential.RotateTime).
+		Set(models.ProjectCredentialKubeconfigInfoColumn, projectCredential.KubeconfigInfo).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectCredential.ProjectId),
+			db.Eq(models.ProjectCredentialIdColumn, projectCredential.CredentialId),
+			db.Eq(models.ProjectCredentialDomainColumn, projectCredential.Domain),
+			db.Eq(models.ProjectCredentialCreatorColumn, CredentialSystemCreator))).Exec()
+	if updateErr != nil {
+		return updateErr
+	}
+	affected, updateErr := result.RowsAffected()
+	if updateErr != nil {
+		return updateErr
+	}
+	if affected == 0 {
+		return err
+	}
+	return nil
+}
+
+// saveCredentialKeyInfo updates the key metadata of a credential,
+// the project_credential row is created if the credential was created outside the api.
+func (s *ProjectService) saveCredentialKeyInfo(projectId, credentialId, domain, operator string,
+	info *CredentialKeyInfo, rotated bool) error {
+	projectCredential := models.NewProjectCredential(projectId, credentialId, domain, operator)
+	err := s.Ds.Db.Select(models.ProjectCredentialColumns...).
+		From(models.ProjectCredentialTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectCredentialIdColumn, credentialId),
+			db.Eq(models.ProjectCredentialDomainColumn, projectCredential.Domain))).
+		LoadOne(projectCredential)
+	if err != nil && err != db.ErrNotFound {
+		return err
+	}
+	if info == nil {
+		info = &CredentialKeyInfo{}
+	}
+	info.applyTo(projectCredential)
+	if rotated {
+		now := time.Now()
+		projectCredential.RotateTime = &now
+	}
+	if err == db.ErrNotFound {
+		return s.insertProjectCredential(projectCredential)
+	}
+	_, err = s.Ds.Db.Update(models.ProjectCredentialTableName).
+		Set(models.ProjectCredentialKeyFingerprintColumn, projectCredential.KeyFingerprint).
+		Set(models.ProjectCredentialKeyAlgorithmColumn, projectCredential.KeyAlgorithm).
+		Set(models.ProjectCredentialNotAfterColumn, projectCredential.NotAfter).
+		Set(models.ProjectCredentialRotateTimeColumn, projectCredential.RotateTime).
+		Set(models.ProjectCredentialKubeconfigInfoColumn, projectCredential.KubeconfigInfo).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectCredentialIdColumn, credentialId),
+			db.Eq(models.ProjectCredentialDomainColumn, projectCredential.Domain))).Exec()
+	return err
+}
+
+// parseCredentialContent parses the jenkins credential update page.
+// Passwords, passphrases and secret texts are rendered encrypted by jenkins,
+// they are only parsed with withSecret and must never be returned to the client.
+// Unknown credential types have no content.
+func parseCredentialContent(credentialType, body string, withSecret bool) (interface{}, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	inputValue := func(selector string) string {
+		value := ""
+		doc.Find(selector).Each(func(i int, selection *goquery.Selection) {
+			value, _ = selection.Attr("value")
+		})
+		return value
+	}
+	textareaValue := func(selector string) string {
+		value := ""
+		doc.Find(selector).Each(func(i int, selection *goquery.Selection) {
+			value = selection.Text()
+		})
+		return value
+	}
+
+	secretValue := func(selector string) string {
+		if !withSecret {
+			return ""
+		}
+		return inputValue(selector)
+	}
+
+	switch credentialType {
+	case CredentialTypeKubeConfig:
+		return &KubeconfigCredentialRequest{
+			Id:          inputValue("input[name*=id][type=text]"),
+			Content:     textareaValue("textarea[name*=content]"),
+			Description: inputValue("input[name*=description]"),
+		}, nil
+	case CredentialTypeUsernamePassword:
+		return &UsernamePasswordCredentialRequest{
+			Id:          inputValue("input[name*=id][type=text]"),
+			Username:    inputValue("input[name*=username]"),
+			Password:    secretValue("input[name*=password]"),
+			Description: inputValue("input[name*=description]"),
+		}, nil
+	case CredentialTypeSsh:
+		return &SshCredentialRequest{
+			Id:          inputValue("input[name*=id][type=text]"),
+			Username:    inputValue("input[name*=username]"),
+			Description: inputValue("input[name*=description]"),
+			Passphrase:  secretValue("input[name*=passphrase]"),
+			PrivateKey:  textareaValue("textarea[name*=privateKey]"),
+		}, nil
+	case CredentialTypeSecretText:
+		return &SecretTextCredentialRequest{
+			Id:          inputValue("input[name*=id][type=text]"),
+			Secret:      secretValue("input[name*=secret]"),
+			Description: inputValue("input[name*=description]"),
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
 func formatCredentialResponse(
 	jenkinsCredentialResponse *gojenkins.CredentialResponse,
 	dbCredentialResponse *models.ProjectCredential) *CredentialResponse {
@@ -57,6 +274,19 @@ func formatCredentialResponse(
 	if dbCredentialResponse != nil {
 		response.CreateTime = &dbCredentialResponse.CreateTime
 		response.Creator = dbCredentialResponse.Creator
+		response.KeyFingerprint = dbCredentialResponse.KeyFingerprint
+		response.KeyAlgorithm = dbCredentialResponse.KeyAlgorithm
+		response.NotAfter = dbCredentialResponse.NotAfter
+		response.RotateTime = dbCredentialResponse.RotateTime
+		if dbCredentialResponse.KubeconfigInfo != "" {
+			response.Kubeconfig = &kubeconfigutils.Info{}
+			err := json.Unmarshal([]byte(dbCredentialResponse.KubeconfigInfo), response.Kubeconfig)
+			if err != nil {
+				logger.Warn("failed to unmarshal kubeconfig info of credential [%s], %+v",
+					dbCredentialResponse.CredentialId, err)
+				response.Kubeconfig = nil
+			}
+		}
 	}
 
 	credentialType, ok := CredentialTypeMap[jenkinsCredentialResponse.TypeName]
@@ -75,7 +305,7 @@ func formatCredentialsResponse(jenkinsCredentialsResponse []*gojenkins.Credentia
 		var dbCredential *models.ProjectCredential = nil
 		for _, projectCredential := range projectCredentials {
 			if projectCredential.CredentialId == jenkinsCredential.Id &&
-				projectCredential.Domain == jenkinsCredential.Domain {
+				normalizeCredentialDomain(projectCredential.Domain) == normalizeCredentialDomain(jenkinsCredential.Domain) {
 				dbCredential = projectCredential
 			}
 		}
@@ -0,0 +1,321 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+const (
+	CredentialDomainSpecHostname  = "hostname"
+	CredentialDomainSpecUriScheme = "uri_scheme"
+)
+
+var (
+	credentialDomainNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$`)
+	uriSchemeRegexp            = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+)
+
+type CredentialDomainSpecification struct {
+	Type     string   `json:"type"`
+	Includes string   `json:"includes,omitempty"`
+	Excludes string   `json:"excludes,omitempty"`
+	Schemes  []string `json:"schemes,omitempty"`
+}
+
+type CredentialDomainRequest struct {
+	Name           string                           `json:"name"`
+	Description    string                           `json:"description"`
+	Specifications []*CredentialDomainSpecification `json:"specifications"`
+}
+
+type CredentialDomainResponse struct {
+	Name             string                           `json:"name"`
+	Description      string                           `json:"description"`
+	Global           bool                             `json:"global"`
+	CredentialsCount int                              `json:"credentials_count"`
+	Specifications   []*CredentialDomainSpecification `json:"specifications,omitempty"`
+}
+
+func (r *CredentialDomainRequest) toJenkinsDomain() (*gojenkins.CredentialDomain, error) {
+	if !credentialDomainNameRegexp.MatchString(r.Name) {
+		return nil, fmt.Errorf("error domain name [%s] should match %s", r.Name, credentialDomainNameRegexp)
+	}
+	domain := &gojenkins.CredentialDomain{
+		Name:        r.Name,
+		Description: r.Description,
+	}
+	for _, specification := range r.Specifications {
+		switch specification.Type {
+		case CredentialDomainSpecHostname:
+			if strings.TrimSpace(specification.Includes) == "" && strings.TrimSpace(specification.Excludes) == "" {
+				return nil, fmt.Errorf("error hostname specification need includes or excludes")
+			}
+			domain.Specifications.Hostnames = append(domain.Specifications.Hostnames,
+				&gojenkins.HostnameSpecification{
+					Includes: specification.Includes,
+					Excludes: specification.Excludes,
+				})
+		case CredentialDomainSpecUriScheme:
+			if len(specification.Schemes) == 0 {
+				return nil, fmt.Errorf("error uri scheme specification need schemes")
+			}
+			for _, scheme := range specification.Schemes {
+				if !uriSchemeRegexp.MatchString(scheme) {
+					return nil, fmt.Errorf("error invalid uri scheme [%s]", scheme)
+				}
+			}
+			domain.Specifications.Schemes = append(domain.Specifications.Schemes,
+				&gojenkins.SchemeSpecification{
+					Schemes: strings.Join(specification.Schemes, ", "),
+				})
+		default:
+			return nil, fmt.Errorf("error unsupport domain specification type [%s]", specification.Type)
+		}
+	}
+	return domain, nil
+}
+
+func formatCredentialDomainResponse(domain *gojenkins.CredentialDomain) *CredentialDomainResponse {
+	response := &CredentialDomainResponse{
+		Name:           domain.Name,
+		Description:    domain.Description,
+		Global:         domain.Name == gojenkins.GlobalCredentialDomain,
+		Specifications: make([]*CredentialDomainSpecification, 0),
+	}
+	for _, hostname := range domain.Specifications.Hostnames {
+		response.Specifications = append(response.Specifications, &CredentialDomainSpecification{
+			Type:     CredentialDomainSpecHostname,
+			Includes: hostname.Includes,
+			Excludes: hostname.Excludes,
+		})
+	}
+	for _, scheme := range domain.Specifications.Schemes {
+		specification := &CredentialDomainSpecification{Type: CredentialDomainSpecUriScheme}
+		for _, s := range strings.Split(scheme.Schemes, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				specification.Schemes = append(specification.Schemes, s)
+			}
+		}
+		response.Specifications = append(response.Specifications, specification)
+	}
+	return response
+}
+
+func (s *ProjectService) GetCredentialDomainsHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	domains, err := s.Ds.Jenkins.GetCredentialDomainsInFolder(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	response := make([]*CredentialDomainResponse, 0)
+	for _, domain := range domains {
+		response = append(response, &CredentialDomainResponse{
+			Name:             domain.Name,
+			Description:      domain.Description,
+			Global:           domain.Global || domain.Name == gojenkins.GlobalCredentialDomain,
+			CredentialsCount: len(domain.Credentials),
+		})
+	}
+	w.WriteJson(response)
+	return
+}
+
+func (s *ProjectService) GetCredentialDomainHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	domainName := r.PathParams["did"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	domain, err := s.Ds.Jenkins.GetCredentialDomainInFolder(domainName, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(formatCredentialDomainResponse(domain))
+	return
+}
+
+func (s *ProjectService) CreateCredentialDomainHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	request := &CredentialDomainRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	domain, err := request.toJenkinsDomain()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existDomain, err := s.Ds.Jenkins.GetCredentialDomainInFolder(domain.Name, projectId)
+	if existDomain != nil {
+		err := fmt.Errorf("domain name [%s] has been used", existDomain.Name)
+		logger.Warn("%+v", err)
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil && stringutils.GetJenkinsStatusCode(err) != http.StatusNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	_, err = s.Ds.Jenkins.CreateCredentialDomainInFolder(domain, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(formatCredentialDomainResponse(domain))
+	return
+}
+
+func (s *ProjectService) UpdateCredentialDomainHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	domainName := r.PathParams["did"]
+	operator := userutils.GetUserNameFromRequest(r)
+	request := &CredentialDomainRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if domainName == gojenkins.GlobalCredentialDomain {
+		err := fmt.Errorf("error global domain can not be changed")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// domain can not be renamed, credentials refer to it by name
+	request.Name = domainName
+	domain, err := request.toJenkinsDomain()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = s.Ds.Jenkins.GetCredentialDomainInFolder(domainName, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	_, err = s.Ds.Jenkins.UpdateCredentialDomainInFolder(domain, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(formatCredentialDomainResponse(domain))
+	return
+}
+
+// DeleteCredentialDomainHandler deletes a domain with all credentials in it.
+func (s *ProjectService) DeleteCredentialDomainHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	domainName := r.PathParams["did"]
+	operator := userutils.GetUserNameFromRequest(r)
+	if domainName == gojenkins.GlobalCredentialDomain {
+		err := fmt.Errorf("error global domain can not be deleted")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err := s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = s.Ds.Jenkins.DeleteCredentialDomainInFolder(domainName, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	_, err = s.Ds.Db.DeleteFrom(models.ProjectCredentialTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectCredentialDomainColumn, domainName))).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(struct {
+		Name string `json:"name"`
+	}{Name: domainName})
+	return
+}
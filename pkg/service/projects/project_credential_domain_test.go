@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	"kubesphere.io/devops/pkg/gojenkins"
+)
+
+func Test_CredentialDomainRequest(t *testing.T) {
+	request := &CredentialDomainRequest{
+		Name:        "github",
+		Description: "github credentials",
+		Specifications: []*CredentialDomainSpecification{
+			{Type: CredentialDomainSpecHostname, Includes: "github.com, *.github.com"},
+			{Type: CredentialDomainSpecUriScheme, Schemes: []string{"https", "ssh"}},
+		},
+	}
+	domain, err := request.toJenkinsDomain()
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	config, err := xml.Marshal(domain)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	output := &gojenkins.CredentialDomain{}
+	err = xml.Unmarshal(config, output)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	response := formatCredentialDomainResponse(output)
+	if response.Name != request.Name || !reflect.DeepEqual(response.Specifications, request.Specifications) {
+		t.Fatalf("input [%+v] output [%+v] should equal ", request, response)
+	}
+}
+
+func Test_CredentialDomainRequest_Invalid(t *testing.T) {
+	inputs := []*CredentialDomainRequest{
+		{Name: "_"},
+		{Name: "a b"},
+		{Name: "github", Specifications: []*CredentialDomainSpecification{{Type: "path"}}},
+		{Name: "github", Specifications: []*CredentialDomainSpecification{{Type: CredentialDomainSpecHostname}}},
+		{Name: "github", Specifications: []*CredentialDomainSpecification{
+			{Type: CredentialDomainSpecUriScheme, Schemes: []string{"HTTP://"}}}},
+	}
+	for _, input := range inputs {
+		_, err := input.toJenkinsDomain()
+		if err == nil {
+			t.Fatalf("input [%+v] should get error", input)
+		}
+	}
+}
@@ -20,15 +20,16 @@ import (
 	"strings"
 	"time"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/asaskevich/govalidator"
 	"github.com/gocraft/dbr"
 	"github.com/mitchellh/mapstructure"
 
 	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/gojenkins"
 	"kubesphere.io/devops/pkg/logger"
 	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/kubeconfigutils"
 	"kubesphere.io/devops/pkg/utils/stringutils"
 	"kubesphere.io/devops/pkg/utils/userutils"
 )
@@ -77,8 +78,11 @@ type DeleteCredentialRequest struct {
 	Domain string `json:"domain"`
 }
 
-type CopySshCredentialRequest struct {
-	Id string `json:"id"`
+type CopyCredentialRequest struct {
+	Id              string `json:"id"`
+	Domain          string `json:"domain"`
+	TargetProjectId string `json:"target_project_id"`
+	TargetDomain    string `json:"target_domain"`
 }
 
 type CredentialResponse struct {
@@ -103,6 +107,13 @@ type CredentialResponse struct {
 	CreateTime  *time.Time             `json:"create_time,omitempty"`
 	Creator     string                 `json:"creator,omitempty"`
 	Content     map[string]interface{} `json:"content"`
+
+	KeyFingerprint string     `json:"key_fingerprint,omitempty"`
+	KeyAlgorithm   string     `json:"key_algorithm,omitempty"`
+	NotAfter       *time.Time `json:"not_after,omitempty"`
+	RotateTime     *time.Time `json:"rotate_time,omitempty"`
+
+	Kubeconfig *kubeconfigutils.Info `json:"kubeconfig,omitempty"`
 }
 
 func (s *ProjectService) CreateCredentialHandler(w rest.ResponseWriter, r *rest.Request) {
@@ -117,12 +128,23 @@ func (s *ProjectService) CreateCredentialHandler(w rest.ResponseWriter, r *rest.
 		return
 	}
 
-	err = s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner, ProjectMaintainer})
+	err = s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectQuota(projectId, QuotaCredentials)
+	if err != nil {
+		writeProjectQuotaError(w, err)
+		return
+	}
 
 	switch request.Type {
 	case CredentialTypeUsernamePassword:
@@ -155,8 +177,7 @@ func (s *ProjectService) CreateCredentialHandler(w rest.ResponseWriter, r *rest.
 		}
 
 		projectCredential := models.NewProjectCredential(projectId, UPRequest.Id, request.Domain, operator)
-		_, err = s.Ds.Db.InsertInto(models.ProjectCredentialTableName).Columns(models.ProjectCredentialColumns...).
-			Record(projectCredential).Exec()
+		err = s.insertProjectCredential(projectCredential)
 		if err != nil {
 			logger.Error("%+v", err)
 			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
@@ -199,9 +220,8 @@ func (s *ProjectService) CreateCredentialHandler(w rest.ResponseWriter, r *rest.
 		}
 
 		projectCredential := models.NewProjectCredential(projectId, SshRequest.Id, request.Domain, operator)
-		_, err = s.Ds.Db.InsertInto(models.ProjectCredentialTableName).
-			Columns(models.ProjectCredentialColumns...).
-			Record(projectCredential).Exec()
+		getSshKeyInfo(SshRequest.PrivateKey, SshRequest.Passphrase).applyTo(projectCredential)
+		err = s.insertProjectCredential(projectCredential)
 		if err != nil {
 			logger.Error("%+v", err)
 			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
@@ -244,9 +264,7 @@ func (s *ProjectService) CreateCredentialHandler(w rest.ResponseWriter, r *rest.
 		}
 
 		projectCredential := models.NewProjectCredential(projectId, TextRequest.Id, request.Domain, operator)
-		_, err = s.Ds.Db.
-			InsertInto(models.ProjectCredentialTableName).
-			Columns(models.ProjectCredentialColumns...).Record(projectCredential).Exec()
+		err = s.insertProjectCredential(projectCredential)
 		if err != nil {
 			logger.Error("%+v", err)
 			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
@@ -265,6 +283,12 @@ func (s *ProjectService) CreateCredentialHandler(w rest.ResponseWriter, r *rest.
 			rest.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		keyInfo, err := inspectKubeconfig(KubeconfigRequest.Content, r.URL.Query().Get("check") == "true")
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		credential, err := s.Ds.Jenkins.GetCredentialInFolder(request.Domain, KubeconfigRequest.Id, projectId)
 		if credential != nil {
@@ -289,9 +313,8 @@ func (s *ProjectService) CreateCredentialHandler(w rest.ResponseWriter, r *rest.
 		}
 
 		projectCredential := models.NewProjectCredential(projectId, KubeconfigRequest.Id, request.Domain, operator)
-		_, err = s.Ds.Db.
-			InsertInto(models.ProjectCredentialTableName).
-			Columns(models.ProjectCredentialColumns...).Record(projectCredential).Exec()
+		keyInfo.applyTo(projectCredential)
+		err = s.insertProjectCredential(projectCredential)
 		if err != nil {
 			logger.Error("%+v", err)
 			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
@@ -299,8 +322,9 @@ func (s *ProjectService) CreateCredentialHandler(w rest.ResponseWriter, r *rest.
 		}
 
 		w.WriteJson(struct {
-			Id string `json:"id"`
-		}{Id: *credentialId})
+			Id         string                `json:"id"`
+			Kubeconfig *kubeconfigutils.Info `json:"kubeconfig"`
+		}{Id: *credentialId, Kubeconfig: keyInfo.Kubeconfig})
 		return
 	default:
 		err := fmt.Errorf("error unsupport  credential type")
@@ -321,12 +345,18 @@ func (s *ProjectService) DeleteCredentialHandler(w rest.ResponseWriter, r *rest.
 		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	err = s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner, ProjectMaintainer})
+	err = s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	id, err := s.Ds.Jenkins.DeleteCredentialInFolder(request.Domain, credentialId, projectId)
 	if err != nil {
 		logger.Error("%+v", err)
@@ -367,12 +397,18 @@ func (s *ProjectService) UpdateCredentialHandler(w rest.ResponseWriter, r *rest.
 		return
 	}
 
-	err = s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner, ProjectMaintainer})
+	err = s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	jenkinsCredential, err := s.Ds.Jenkins.GetCredentialInFolder(request.Domain, credentialId, projectId)
 	if err != nil {
 		logger.Error("%+v", err)
@@ -418,6 +454,15 @@ func (s *ProjectService) UpdateCredentialHandler(w rest.ResponseWriter, r *rest.
 			rest.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if !govalidator.IsNull(SshRequest.PrivateKey) {
+			err = s.saveCredentialKeyInfo(projectId, *credentialId, request.Domain, operator,
+				getSshKeyInfo(SshRequest.PrivateKey, SshRequest.Passphrase), false)
+			if err != nil {
+				logger.Error("%+v", err)
+				rest.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
 		w.WriteJson(struct {
 			Id string `json:"id"`
 		}{Id: *credentialId})
@@ -452,6 +497,16 @@ func (s *ProjectService) UpdateCredentialHandler(w rest.ResponseWriter, r *rest.
 			rest.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		// empty content keeps the current kubeconfig
+		var keyInfo *CredentialKeyInfo
+		if !govalidator.IsNull(KubeconfigRequest.Content) {
+			keyInfo, err = inspectKubeconfig(KubeconfigRequest.Content, r.URL.Query().Get("check") == "true")
+			if err != nil {
+				logger.Error("%+v", err)
+				rest.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
 		credentialId, err := s.Ds.Jenkins.UpdateKubeconfigCredentialInFolder(request.Domain, KubeconfigRequest.Id,
 			KubeconfigRequest.Content, KubeconfigRequest.Description, projectId)
 		if err != nil {
@@ -459,9 +514,22 @@ func (s *ProjectService) UpdateCredentialHandler(w rest.ResponseWriter, r *rest.
 			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
 			return
 		}
+		if keyInfo == nil {
+			w.WriteJson(struct {
+				Id string `json:"id"`
+			}{Id: *credentialId})
+			return
+		}
+		err = s.saveCredentialKeyInfo(projectId, *credentialId, request.Domain, operator, keyInfo, false)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		w.WriteJson(struct {
-			Id string `json:"id"`
-		}{Id: *credentialId})
+			Id         string                `json:"id"`
+			Kubeconfig *kubeconfigutils.Info `json:"kubeconfig"`
+		}{Id: *credentialId, Kubeconfig: keyInfo.Kubeconfig})
 		return
 
 	default:
@@ -478,7 +546,7 @@ func (s *ProjectService) GetCredentialHandler(w rest.ResponseWriter, r *rest.Req
 	operator := userutils.GetUserNameFromRequest(r)
 	credentialId := r.PathParams["cid"]
 	domain := r.URL.Query().Get("domain")
-	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner, ProjectMaintainer})
+	err := s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
@@ -512,97 +580,56 @@ func (s *ProjectService) GetCredentialHandler(w rest.ResponseWriter, r *rest.Req
 			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
 			return
 		}
-		stringReader := strings.NewReader(stringBody)
-		doc, err := goquery.NewDocumentFromReader(stringReader)
+		content, err := parseCredentialContent(response.Type, stringBody, false)
 		if err != nil {
 			logger.Error("%+v", err)
-			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		switch response.Type {
-		case CredentialTypeKubeConfig:
-			content := &KubeconfigCredentialRequest{}
-			doc.Find("textarea[name*=content]").Each(func(i int, selection *goquery.Selection) {
-				value := selection.Text()
-				content.Content = value
-			})
-
-			doc.Find("input[name*=id][type=text]").Each(func(i int, selection *goquery.Selection) {
-				value, _ := selection.Attr("value")
-				content.Id = value
-			})
-			doc.Find("input[name*=description]").Each(func(i int, selection *goquery.Selection) {
-				value, _ := selection.Attr("value")
-				content.Description = value
-			})
-			jsonBytes, _ := json.Marshal(content)
-			json.Unmarshal(jsonBytes, &response.Content)
-
-		case CredentialTypeUsernamePassword:
-			content := &UsernamePasswordCredentialRequest{}
-			doc.Find("input[name*=username]").Each(func(i int, selection *goquery.Selection) {
-				value, _ := selection.Attr("value")
-				content.Username = value
-			})
-
-			doc.Find("input[name*=id][type=text]").Each(func(i int, selection *goquery.Selection) {
-				value, _ := selection.Attr("value")
-				content.Id = value
-			})
-			doc.Find("input[name*=description]").Each(func(i int, selection *goquery.Selection) {
-				value, _ := selection.Attr("value")
-				content.Description = value
-			})
-			jsonBytes, _ := json.Marshal(content)
-			json.Unmarshal(jsonBytes, &response.Content)
-
-		case CredentialTypeSsh:
-			content := &SshCredentialRequest{}
-			doc.Find("input[name*=username]").Each(func(i int, selection *goquery.Selection) {
-				value, _ := selection.Attr("value")
-				content.Username = value
-			})
-
-			doc.Find("input[name*=id][type=text]").Each(func(i int, selection *goquery.Selection) {
-				value, _ := selection.Attr("value")
-				content.Id = value
-			})
-			doc.Find("input[name*=description]").Each(func(i int, selection *goquery.Selection) {
-				value, _ := selection.Attr("value")
-				content.Description = value
-			})
-			doc.Find("textarea[name*=privateKey]").Each(func(i int, selection *goquery.Selection) {
-				value := selection.Text()
-				content.PrivateKey = value
-			})
-			jsonBytes, _ := json.Marshal(content)
-			json.Unmarshal(jsonBytes, &response.Content)
-		}
+		jsonBytes, _ := json.Marshal(content)
+		json.Unmarshal(jsonBytes, &response.Content)
 	}
 	w.WriteJson(response)
 	return
 }
 
+// GetCredentialsHandler lists credentials across all domains,
+// the domain query filters by one or more comma separated domains.
 func (s *ProjectService) GetCredentialsHandler(w rest.ResponseWriter, r *rest.Request) {
 	projectId := r.PathParams["id"]
 	operator := userutils.GetUserNameFromRequest(r)
-	domain := r.URL.Query().Get("domain")
-	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner, ProjectMaintainer})
+	domains := make([]string, 0)
+	for _, domain := range strings.Split(r.URL.Query().Get("domain"), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	err := s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
-	jenkinsCredentialResponses, err := s.Ds.Jenkins.GetCredentialsInFolder(domain, projectId)
+	jenkinsDomain := ""
+	if len(domains) == 1 {
+		jenkinsDomain = domains[0]
+	}
+	jenkinsCredentialResponses, err := s.Ds.Jenkins.GetCredentialsInFolder(jenkinsDomain, projectId)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
 		return
 	}
 	selectCondition := db.Eq(models.ProjectIdColumn, projectId)
-	if !govalidator.IsNull(domain) {
-		selectCondition = db.And(selectCondition, db.Eq(models.ProjectCredentialDomainColumn, domain))
+	if len(domains) > 0 {
+		selectCondition = db.And(selectCondition, db.Eq(models.ProjectCredentialDomainColumn, domains))
+		filteredResponses := make([]*gojenkins.CredentialResponse, 0)
+		for _, jenkinsCredential := range jenkinsCredentialResponses {
+			if stringutils.StringIn(jenkinsCredential.Domain, domains) {
+				filteredResponses = append(filteredResponses, jenkinsCredential)
+			}
+		}
+		jenkinsCredentialResponses = filteredResponses
 	}
 	projectCredentials := make([]*models.ProjectCredential, 0)
 	_, err = s.Ds.Db.Select(models.ProjectCredentialColumns...).
@@ -618,3 +645,132 @@ func (s *ProjectService) GetCredentialsHandler(w rest.ResponseWriter, r *rest.Re
 	w.WriteJson(response)
 	return
 }
+
+// CopyCredentialHandler copies a credential into another project,
+// the secret is read and written by the server and never returned to the client.
+func (s *ProjectService) CopyCredentialHandler(w rest.ResponseWriter, r *rest.Request) {
+	request := &CopyCredentialRequest{}
+	projectId := r.PathParams["id"]
+	credentialId := r.PathParams["cid"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(request.TargetProjectId) {
+		err := fmt.Errorf("error need target_project_id")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(request.Id) {
+		request.Id = credentialId
+	}
+	if request.TargetProjectId == projectId && request.TargetDomain == request.Domain && request.Id == credentialId {
+		err := fmt.Errorf("error can not copy credential [%s] to itself", credentialId)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, id := range []string{projectId, request.TargetProjectId} {
+		err = s.checkProjectUserPermission(operator, id, PermissionManageCredentials)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	err = s.checkProjectNotArchived(request.TargetProjectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectQuota(request.TargetProjectId, QuotaCredentials)
+	if err != nil {
+		writeProjectQuotaError(w, err)
+		return
+	}
+
+	jenkinsCredential, err := s.Ds.Jenkins.GetCredentialInFolder(request.Domain, credentialId, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	targetCredential, err := s.Ds.Jenkins.GetCredentialInFolder(request.TargetDomain, request.Id, request.TargetProjectId)
+	if targetCredential != nil {
+		err := fmt.Errorf("credential id [%s] has been used", targetCredential.Id)
+		logger.Warn("%+v", err)
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil && stringutils.GetJenkinsStatusCode(err) != http.StatusNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+
+	stringBody, err := s.Ds.Jenkins.GetCredentialContentInFolder(request.Domain, credentialId, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	content, err := parseCredentialContent(CredentialTypeMap[jenkinsCredential.TypeName], stringBody, true)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var keyInfo *CredentialKeyInfo
+	var id *string
+	switch content := content.(type) {
+	case *UsernamePasswordCredentialRequest:
+		id, err = s.Ds.Jenkins.CreateUsernamePasswordCredentialInFolder(request.TargetDomain, request.Id,
+			content.Username, content.Password, content.Description, request.TargetProjectId)
+	case *SshCredentialRequest:
+		keyInfo = getSshKeyInfo(content.PrivateKey, content.Passphrase)
+		id, err = s.Ds.Jenkins.CreateSshCredentialInFolder(request.TargetDomain, request.Id,
+			content.Username, content.Passphrase, content.PrivateKey, content.Description, request.TargetProjectId)
+	case *SecretTextCredentialRequest:
+		id, err = s.Ds.Jenkins.CreateSecretTextCredentialInFolder(request.TargetDomain, request.Id,
+			content.Secret, content.Description, request.TargetProjectId)
+	case *KubeconfigCredentialRequest:
+		// the source credential is already in use, copy it even if it does not pass the validation
+		keyInfo, err = inspectKubeconfig(content.Content, false)
+		if err != nil {
+			logger.Warn("failed to inspect kubeconfig of credential [%s], %+v", request.Id, err)
+		}
+		id, err = s.Ds.Jenkins.CreateKubeconfigCredentialInFolder(request.TargetDomain, request.Id,
+			content.Content, content.Description, request.TargetProjectId)
+	default:
+		err := fmt.Errorf("error unsupport credential type %s", jenkinsCredential.TypeName)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+
+	projectCredential := models.NewProjectCredential(request.TargetProjectId, request.Id, request.TargetDomain, operator)
+	keyInfo.applyTo(projectCredential)
+	err = s.insertProjectCredential(projectCredential)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(struct {
+		Id        string `json:"id"`
+		ProjectId string `json:"project_id"`
+		Domain    string `json:"domain"`
+	}{Id: *id, ProjectId: request.TargetProjectId, Domain: projectCredential.Domain})
+	return
+}
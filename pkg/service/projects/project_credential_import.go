@@ -0,0 +1,236 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	SecretTypeOpaque           = "Opaque"
+	SecretTypeBasicAuth        = "kubernetes.io/basic-auth"
+	SecretTypeSshAuth          = "kubernetes.io/ssh-auth"
+	SecretTypeDockerConfigJson = "kubernetes.io/dockerconfigjson"
+
+	SecretDescriptionAnnotation = "kubesphere.io/description"
+	// SecretSshUsernameAnnotation sets the username of an ssh-auth secret without a username key
+	SecretSshUsernameAnnotation = "kubesphere.io/ssh-username"
+	// DefaultSshUsername is the username of ssh-auth secrets without username key and annotation,
+	// it is the user of the common git servers
+	DefaultSshUsername = "git"
+
+	secretUsernameKey         = "username"
+	secretPasswordKey         = "password"
+	secretSshPrivateKeyKey    = "ssh-privatekey"
+	secretSshPassphraseKey    = "passphrase"
+	secretDockerConfigJsonKey = ".dockerconfigjson"
+)
+
+var secretKubeconfigKeys = []string{"kubeconfig", "config"}
+
+// Secret is the subset of a kubernetes v1 Secret used to import credentials.
+type Secret struct {
+	ApiVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name        string            `yaml:"name"`
+		Namespace   string            `yaml:"namespace"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+	StringData map[string]string `yaml:"stringData"`
+}
+
+type dockerConfigJson struct {
+	Auths map[string]struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	} `json:"auths"`
+}
+
+// parseSecretManifest splits a multi-document manifest into secrets,
+// empty documents are skipped.
+func parseSecretManifest(manifest string) ([]*Secret, error) {
+	secrets := make([]*Secret, 0)
+	decoder := yaml.NewDecoder(strings.NewReader(manifest))
+	for {
+		secret := &Secret{}
+		err := decoder.Decode(secret)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest document %d: %v", len(secrets)+1, err)
+		}
+		if secret.Kind == "" && secret.Metadata.Name == "" && len(secret.Data) == 0 && len(secret.StringData) == 0 {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// values merges data and stringData like the api server does, stringData wins.
+func (secret *Secret) values() (map[string]string, error) {
+	values := make(map[string]string)
+	for key, value := range secret.Data {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 data of key [%s]", key)
+		}
+		values[key] = string(decoded)
+	}
+	for key, value := range secret.StringData {
+		values[key] = value
+	}
+	return values, nil
+}
+
+// toCredential maps a secret to the credential type and request of the same content,
+// the secret name is used as credential id.
+func (secret *Secret) toCredential() (string, interface{}, error) {
+	if secret.Kind != "Secret" {
+		return "", nil, fmt.Errorf("unsupported kind [%s], only Secret can be imported", secret.Kind)
+	}
+	if secret.Metadata.Name == "" {
+		return "", nil, fmt.Errorf("secret name is required")
+	}
+	values, err := secret.values()
+	if err != nil {
+		return "", nil, err
+	}
+	id := secret.Metadata.Name
+	description := secret.Metadata.Annotations[SecretDescriptionAnnotation]
+
+	secretType := secret.Type
+	if secretType == "" {
+		secretType = SecretTypeOpaque
+	}
+	switch secretType {
+	case SecretTypeBasicAuth:
+		return usernamePasswordFromSecret(id, description, values)
+	case SecretTypeSshAuth:
+		return sshFromSecret(id, description, values, secret.Metadata.Annotations)
+	case SecretTypeDockerConfigJson:
+		config := &dockerConfigJson{}
+		err := json.Unmarshal([]byte(values[secretDockerConfigJsonKey]), config)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid %s: %v", secretDockerConfigJsonKey, err)
+		}
+		if len(config.Auths) != 1 {
+			return "", nil, fmt.Errorf("%s should contain exactly one registry, got %d",
+				secretDockerConfigJsonKey, len(config.Auths))
+		}
+		for registry, auth := range config.Auths {
+			if auth.Username == "" && auth.Auth != "" {
+				decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+				if err != nil {
+					return "", nil, fmt.Errorf("invalid auth of registry [%s]", registry)
+				}
+				parts := strings.SplitN(string(decoded), ":", 2)
+				if len(parts) != 2 {
+					return "", nil, fmt.Errorf("invalid auth of registry [%s]", registry)
+				}
+				auth.Username, auth.Password = parts[0], parts[1]
+			}
+			if description == "" {
+				description = registry
+			}
+			return usernamePasswordFromSecret(id, description, map[string]string{
+				secretUsernameKey: auth.Username,
+				secretPasswordKey: auth.Password,
+			})
+		}
+	case SecretTypeOpaque:
+		if _, ok := values[secretSshPrivateKeyKey]; ok {
+			return sshFromSecret(id, description, values, secret.Metadata.Annotations)
+		}
+		// opaque secrets need both keys, a single key is imported as secret text
+		_, hasUsername := values[secretUsernameKey]
+		_, hasPassword := values[secretPasswordKey]
+		if hasUsername && hasPassword {
+			return usernamePasswordFromSecret(id, description, values)
+		}
+		for _, key := range secretKubeconfigKeys {
+			if content, ok := values[key]; ok {
+				return CredentialTypeKubeConfig, &KubeconfigCredentialRequest{
+					Id:          id,
+					Content:     content,
+					Description: description,
+				}, nil
+			}
+		}
+		if len(values) == 1 {
+			for _, value := range values {
+				return CredentialTypeSecretText, &SecretTextCredentialRequest{
+					Id:          id,
+					Secret:      value,
+					Description: description,
+				}, nil
+			}
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return "", nil, fmt.Errorf("can not map opaque secret with keys [%s] to a credential",
+			strings.Join(keys, ", "))
+	}
+	return "", nil, fmt.Errorf("unsupported secret type [%s]", secretType)
+}
+
+// usernamePasswordFromSecret maps a basic-auth secret, like kubernetes it needs the username or the password.
+func usernamePasswordFromSecret(id, description string, values map[string]string) (string, interface{}, error) {
+	if values[secretUsernameKey] == "" && values[secretPasswordKey] == "" {
+		return "", nil, fmt.Errorf("secret need %s or %s", secretUsernameKey, secretPasswordKey)
+	}
+	return CredentialTypeUsernamePassword, &UsernamePasswordCredentialRequest{
+		Id:          id,
+		Username:    values[secretUsernameKey],
+		Password:    values[secretPasswordKey],
+		Description: description,
+	}, nil
+}
+
+// sshFromSecret maps an ssh-auth secret. The standard secret only has the private key, the username
+// is taken from the username key, the ssh username annotation or DefaultSshUsername.
+func sshFromSecret(id, description string, values, annotations map[string]string) (string, interface{}, error) {
+	if values[secretSshPrivateKeyKey] == "" {
+		return "", nil, fmt.Errorf("secret need %s", secretSshPrivateKeyKey)
+	}
+	username := values[secretUsernameKey]
+	if username == "" {
+		username = annotations[SecretSshUsernameAnnotation]
+	}
+	if username == "" {
+		username = DefaultSshUsername
+	}
+	return CredentialTypeSsh, &SshCredentialRequest{
+		Id:          id,
+		Username:    username,
+		Passphrase:  values[secretSshPassphraseKey],
+		PrivateKey:  values[secretSshPrivateKeyKey],
+		Description: description,
+	}, nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+const (
+	ImportCredentialStatusSuccess  = "success"
+	ImportCredentialStatusConflict = "conflict"
+	ImportCredentialStatusError    = "error"
+)
+
+type ImportCredentialsRequest struct {
+	Domain   string `json:"domain"`
+	Manifest string `json:"manifest"`
+}
+
+type ImportCredentialResult struct {
+	Name       string `json:"name"`
+	SecretType string `json:"secret_type"`
+	Type       string `json:"type,omitempty"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+}
+
+// ImportCredentialsHandler creates a credential for every Secret in a multi-document manifest,
+// a failed secret does not stop the import of the others.
+func (s *ProjectService) ImportCredentialsHandler(w rest.ResponseWriter, r *rest.Request) {
+	request := &ImportCredentialsRequest{}
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(request.Manifest) {
+		err := fmt.Errorf("error need manifest to import credentials")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	secrets, err := parseSecretManifest(request.Manifest)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results := make([]*ImportCredentialResult, 0)
+	for _, secret := range secrets {
+		result := &ImportCredentialResult{
+			Name:       secret.Metadata.Name,
+			SecretType: secret.Type,
+		}
+		results = append(results, result)
+
+		credentialType, content, err := secret.toCredential()
+		if err != nil {
+			result.Status = ImportCredentialStatusError
+			result.Message = err.Error()
+			continue
+		}
+		result.Type = credentialType
+		status, err := s.importCredential(projectId, request.Domain, operator, content)
+		result.Status = status
+		if err != nil {
+			logger.Warn("failed to import secret [%s] into project [%s], %+v", secret.Metadata.Name, projectId, err)
+			result.Message = err.Error()
+		}
+	}
+	w.WriteJson(results)
+	return
+}
+
+// importCredential creates one credential, the returned status is set even if there is an error.
+func (s *ProjectService) importCredential(projectId, domain, operator string, content interface{}) (string, error) {
+	var id string
+	var keyInfo *CredentialKeyInfo
+	var err error
+	switch content := content.(type) {
+	case *UsernamePasswordCredentialRequest:
+		id = content.Id
+	case *SshCredentialRequest:
+		id = content.Id
+		keyInfo = getSshKeyInfo(content.PrivateKey, content.Passphrase)
+	case *SecretTextCredentialRequest:
+		id = content.Id
+	case *KubeconfigCredentialRequest:
+		id = content.Id
+		keyInfo, err = inspectKubeconfig(content.Content, false)
+		if err != nil {
+			return ImportCredentialStatusError, err
+		}
+	}
+
+	credential, err := s.Ds.Jenkins.GetCredentialInFolder(domain, id, projectId)
+	if credential != nil {
+		return ImportCredentialStatusConflict, fmt.Errorf("credential id [%s] has been used", credential.Id)
+	}
+	if err != nil && stringutils.GetJenkinsStatusCode(err) != http.StatusNotFound {
+		return ImportCredentialStatusError, err
+	}
+	err = s.checkProjectQuota(projectId, QuotaCredentials)
+	if err != nil {
+		return ImportCredentialStatusError, err
+	}
+
+	switch content := content.(type) {
+	case *UsernamePasswordCredentialRequest:
+		_, err = s.Ds.Jenkins.CreateUsernamePasswordCredentialInFolder(domain, content.Id,
+			content.Username, content.Password, content.Description, projectId)
+	case *SshCredentialRequest:
+		_, err = s.Ds.Jenkins.CreateSshCredentialInFolder(domain, content.Id,
+			content.Username, content.Passphrase, content.PrivateKey, content.Description, projectId)
+	case *SecretTextCredentialRequest:
+		_, err = s.Ds.Jenkins.CreateSecretTextCredentialInFolder(domain, content.Id,
+			content.Secret, content.Description, projectId)
+	case *KubeconfigCredentialRequest:
+		_, err = s.Ds.Jenkins.CreateKubeconfigCredentialInFolder(domain, content.Id,
+			content.Content, content.Description, projectId)
+	}
+	if err != nil {
+		return ImportCredentialStatusError, err
+	}
+
+	projectCredential := models.NewProjectCredential(projectId, id, domain, operator)
+	keyInfo.applyTo(projectCredential)
+	err = s.insertProjectCredential(projectCredential)
+	if err != nil {
+		return ImportCredentialStatusError, err
+	}
+	return ImportCredentialStatusSuccess, nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSecretManifest = `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: github
+  annotations:
+    kubesphere.io/description: github account
+type: kubernetes.io/basic-auth
+data:
+  username: YWRtaW4=
+  password: cGFzc3dvcmQ=
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: deploy-key
+type: kubernetes.io/ssh-auth
+stringData:
+  ssh-privatekey: key
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: harbor
+type: kubernetes.io/dockerconfigjson
+stringData:
+  .dockerconfigjson: '{"auths":{"harbor.example.com":{"auth":"cm9ib3Q6dG9rZW4="}}}'
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: sonar-token
+stringData:
+  token: abc
+---
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: unknown
+stringData:
+  a: "1"
+  b: "2"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`
+
+func TestSecret_ToCredential(t *testing.T) {
+	secrets, err := parseSecretManifest(testSecretManifest)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if len(secrets) != 6 {
+		t.Fatalf("should get 6 secrets, got %d", len(secrets))
+	}
+	expected := []struct {
+		credentialType string
+		content        interface{}
+	}{
+		{CredentialTypeUsernamePassword, &UsernamePasswordCredentialRequest{
+			Id: "github", Username: "admin", Password: "password", Description: "github account"}},
+		{CredentialTypeSsh, &SshCredentialRequest{
+			Id: "deploy-key", Username: "git", PrivateKey: "key"}},
+		{CredentialTypeUsernamePassword, &UsernamePasswordCredentialRequest{
+			Id: "harbor", Username: "robot", Password: "token", Description: "harbor.example.com"}},
+		{CredentialTypeSecretText, &SecretTextCredentialRequest{
+			Id: "sonar-token", Secret: "abc"}},
+		{"", nil},
+		{"", nil},
+	}
+	for i, secret := range secrets {
+		credentialType, content, err := secret.toCredential()
+		if expected[i].content == nil {
+			if err == nil {
+				t.Fatalf("secret [%s] should get error", secret.Metadata.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+		if credentialType != expected[i].credentialType {
+			t.Fatalf("secret [%s] type should be %s, got %s",
+				secret.Metadata.Name, expected[i].credentialType, credentialType)
+		}
+		if !reflect.DeepEqual(content, expected[i].content) {
+			t.Fatalf("secret [%s] content should be %+v, got %+v", secret.Metadata.Name, expected[i].content, content)
+		}
+	}
+}
+
+func TestSecret_ToCredentialOptionalKeys(t *testing.T) {
+	secrets, err := parseSecretManifest(`apiVersion: v1
+kind: Secret
+metadata:
+  name: gitlab-key
+  annotations:
+    kubesphere.io/ssh-username: deployer
+type: kubernetes.io/ssh-auth
+data:
+  ssh-privatekey: a2V5
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: registry-token
+type: kubernetes.io/basic-auth
+stringData:
+  password: token
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: empty-auth
+type: kubernetes.io/basic-auth
+stringData:
+  username: ""
+`)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	_, content, err := secrets[0].toCredential()
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	expectedSsh := &SshCredentialRequest{Id: "gitlab-key", Username: "deployer", PrivateKey: "key"}
+	if !reflect.DeepEqual(content, expectedSsh) {
+		t.Fatalf("content should be %+v, got %+v", expectedSsh, content)
+	}
+	_, content, err = secrets[1].toCredential()
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	expectedPassword := &UsernamePasswordCredentialRequest{Id: "registry-token", Password: "token"}
+	if !reflect.DeepEqual(content, expectedPassword) {
+		t.Fatalf("content should be %+v, got %+v", expectedPassword, content)
+	}
+	if _, _, err = secrets[2].toCredential(); err == nil {
+		t.Fatalf("basic-auth secret without username and password should get error")
+	}
+}
+
+func TestParseSecretManifest_Invalid(t *testing.T) {
+	_, err := parseSecretManifest("kind: [Secret")
+	if err == nil {
+		t.Fatalf("should get error with invalid yaml")
+	}
+}
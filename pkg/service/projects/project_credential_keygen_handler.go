@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/sshutils"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type GenerateSshCredentialRequest struct {
+	Id          string `json:"id"`
+	Domain      string `json:"domain"`
+	Username    string `json:"username"`
+	Description string `json:"description"`
+	KeyType     string `json:"key_type"`
+	Bits        int    `json:"bits"`
+	Comment     string `json:"comment"`
+}
+
+type GenerateSshCredentialResponse struct {
+	Id          string `json:"id"`
+	Domain      string `json:"domain"`
+	PublicKey   string `json:"public_key"`
+	Fingerprint string `json:"fingerprint"`
+	Algorithm   string `json:"algorithm"`
+}
+
+// GenerateSshCredentialHandler generates a key pair and stores the private key as ssh credential,
+// only the public key is returned and the private key never leaves the server.
+func (s *ProjectService) GenerateSshCredentialHandler(w rest.ResponseWriter, r *rest.Request) {
+	request := &GenerateSshCredentialRequest{}
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(request.Id) || govalidator.IsNull(request.Username) {
+		err := fmt.Errorf("error need id and username to generate ssh credential")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(request.KeyType) {
+		request.KeyType = sshutils.KeyTypeED25519
+	}
+	err = s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectQuota(projectId, QuotaCredentials)
+	if err != nil {
+		writeProjectQuotaError(w, err)
+		return
+	}
+
+	credential, err := s.Ds.Jenkins.GetCredentialInFolder(request.Domain, request.Id, projectId)
+	if credential != nil {
+		err := fmt.Errorf("credential id [%s] has been used", credential.Id)
+		logger.Warn("%+v", err)
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil && stringutils.GetJenkinsStatusCode(err) != http.StatusNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := sshutils.GenerateKey(request.KeyType, request.Bits)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	privateKey, err := sshutils.MarshalPrivateKey(key, request.Comment)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	publicKey, err := sshutils.NewPublicKey(key.Public())
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	credentialId, err := s.Ds.Jenkins.CreateSshCredentialInFolder(request.Domain, request.Id,
+		request.Username, "", string(privateKey), request.Description, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+
+	projectCredential := models.NewProjectCredential(projectId, request.Id, request.Domain, operator)
+	projectCredential.KeyFingerprint = publicKey.Fingerprint()
+	projectCredential.KeyAlgorithm = publicKey.Algorithm
+	err = s.insertProjectCredential(projectCredential)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteJson(&GenerateSshCredentialResponse{
+		Id:          *credentialId,
+		Domain:      request.Domain,
+		PublicKey:   publicKey.AuthorizedKey(request.Comment),
+		Fingerprint: publicKey.Fingerprint(),
+		Algorithm:   publicKey.Algorithm,
+	})
+	return
+}
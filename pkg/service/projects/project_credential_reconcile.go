@@ -0,0 +1,223 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+const (
+	// CredentialSystemCreator is the creator of credentials found in jenkins but not created by the api.
+	CredentialSystemCreator     = "system"
+	CredentialReconcileInterval = time.Hour
+)
+
+type CredentialReconcileItem struct {
+	CredentialId string `json:"credential_id"`
+	Domain       string `json:"domain"`
+}
+
+type CredentialReconcileReport struct {
+	ProjectId  string                     `json:"project_id"`
+	Backfilled []*CredentialReconcileItem `json:"backfilled"`
+	Removed    []*CredentialReconcileItem `json:"removed"`
+	Error      string                     `json:"error,omitempty"`
+}
+
+// requests without domain are stored in the global domain by jenkins
+func normalizeCredentialDomain(domain string) string {
+	if domain == "" {
+		return gojenkins.GlobalCredentialDomain
+	}
+	return domain
+}
+
+// diffProjectCredentials returns the jenkins credentials without project_credential rows
+// and the rows whose credential does not exist in jenkins.
+func diffProjectCredentials(jenkinsCredentials []*gojenkins.CredentialResponse,
+	projectCredentials []*models.ProjectCredential) ([]*CredentialReconcileItem, []*models.ProjectCredential) {
+	key := func(domain, id string) string {
+		return normalizeCredentialDomain(domain) + "/" + id
+	}
+	rows := make(map[string]bool)
+	for _, projectCredential := range projectCredentials {
+		rows[key(projectCredential.Domain, projectCredential.CredentialId)] = true
+	}
+	credentials := make(map[string]bool)
+	missing := make([]*CredentialReconcileItem, 0)
+	for _, jenkinsCredential := range jenkinsCredentials {
+		credentials[key(jenkinsCredential.Domain, jenkinsCredential.Id)] = true
+		if !rows[key(jenkinsCredential.Domain, jenkinsCredential.Id)] {
+			missing = append(missing, &CredentialReconcileItem{
+				CredentialId: jenkinsCredential.Id,
+				Domain:       jenkinsCredential.Domain,
+			})
+		}
+	}
+	orphans := make([]*models.ProjectCredential, 0)
+	for _, projectCredential := range projectCredentials {
+		if !credentials[key(projectCredential.Domain, projectCredential.CredentialId)] {
+			orphans = append(orphans, projectCredential)
+		}
+	}
+	return missing, orphans
+}
+
+// listReconcileCredentials loads the project_credential rows before listing the jenkins credentials.
+// A credential created by a handler in between is then found in jenkins without its row and backfilled,
+// the handler takes over the backfilled row. In the reverse order its row would be removed as orphan.
+func listReconcileCredentials(loadRows func() ([]*models.ProjectCredential, error),
+	listJenkins func() ([]*gojenkins.CredentialResponse, error)) (
+	[]*models.ProjectCredential, []*gojenkins.CredentialResponse, error) {
+	projectCredentials, err := loadRows()
+	if err != nil {
+		return nil, nil, err
+	}
+	jenkinsCredentials, err := listJenkins()
+	if err != nil {
+		return nil, nil, err
+	}
+	return projectCredentials, jenkinsCredentials, nil
+}
+
+// reconcileProjectCredentials backfills missing project_credential rows and removes orphans of a project,
+// with dryRun the mismatches are only reported.
+func (s *ProjectService) reconcileProjectCredentials(projectId string, dryRun bool) (*CredentialReconcileReport, error) {
+	report := &CredentialReconcileReport{
+		ProjectId:  projectId,
+		Backfilled: make([]*CredentialReconcileItem, 0),
+		Removed:    make([]*CredentialReconcileItem, 0),
+	}
+	projectCredentials, jenkinsCredentials, err := listReconcileCredentials(
+		func() ([]*models.ProjectCredential, error) {
+			projectCredentials := make([]*models.ProjectCredential, 0)
+			_, err := s.Ds.Db.Select(models.ProjectCredentialColumns...).
+				From(models.ProjectCredentialTableName).
+				Where(db.Eq(models.ProjectIdColumn, projectId)).
+				Load(&projectCredentials)
+			return projectCredentials, err
+		},
+		func() ([]*gojenkins.CredentialResponse, error) {
+			return s.Ds.Jenkins.GetCredentialsInFolder("", projectId)
+		})
+	if err != nil {
+		return report, err
+	}
+
+	missing, orphans := diffProjectCredentials(jenkinsCredentials, projectCredentials)
+	for _, item := range missing {
+		if !dryRun {
+			projectCredential := models.NewProjectCredential(projectId, item.CredentialId, item.Domain,
+				CredentialSystemCreator)
+			_, err := s.Ds.Db.InsertInto(models.ProjectCredentialTableName).
+				Columns(models.ProjectCredentialColumns...).
+				Record(projectCredential).Exec()
+			// the row was inserted by the handler creating the credential meanwhile
+			if isDuplicateEntryError(err) {
+				continue
+			}
+			if err != nil {
+				return report, err
+			}
+		}
+		report.Backfilled = append(report.Backfilled, item)
+	}
+	for _, orphan := range orphans {
+		if !dryRun {
+			_, err := s.Ds.Db.DeleteFrom(models.ProjectCredentialTableName).
+				Where(db.And(
+					db.Eq(models.ProjectIdColumn, projectId),
+					db.Eq(models.ProjectCredentialIdColumn, orphan.CredentialId),
+					db.Eq(models.ProjectCredentialDomainColumn, orphan.Domain))).Exec()
+			if err != nil {
+				return report, err
+			}
+		}
+		report.Removed = append(report.Removed, &CredentialReconcileItem{
+			CredentialId: orphan.CredentialId,
+			Domain:       orphan.Domain,
+		})
+	}
+	return report, nil
+}
+
+// ReconcileCredentials reconciles the given projects, or all active projects if none is given.
+// Only projects with mismatches or errors are reported.
+func (s *ProjectService) ReconcileCredentials(projectIds []string, dryRun bool) ([]*CredentialReconcileReport, error) {
+	if len(projectIds) == 0 {
+		projects := make([]*models.Project, 0)
+		_, err := s.Ds.Db.Select(models.ProjectIdColumn).
+			From(models.ProjectTableName).
+			Where(db.Eq(constants.StatusColumn, constants.StatusActive)).
+			Load(&projects)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			projectIds = append(projectIds, project.ProjectId)
+		}
+	}
+	reports := make([]*CredentialReconcileReport, 0)
+	for _, projectId := range projectIds {
+		report, err := s.reconcileProjectCredentials(projectId, dryRun)
+		if err != nil {
+			logger.Error("failed to reconcile credentials of project [%s], %+v", projectId, err)
+			report.Error = err.Error()
+		}
+		if report.Error != "" || len(report.Backfilled) > 0 || len(report.Removed) > 0 {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+func (s *ProjectService) ReconcileCredentialsHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not reconcile credentials", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	projectIds := make([]string, 0)
+	for _, projectId := range strings.Split(r.URL.Query().Get("project_id"), ",") {
+		if projectId = strings.TrimSpace(projectId); projectId != "" {
+			projectIds = append(projectIds, projectId)
+		}
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	reports, err := s.ReconcileCredentials(projectIds, dryRun)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(struct {
+		DryRun  bool                         `json:"dry_run"`
+		Reports []*CredentialReconcileReport `json:"reports"`
+	}{DryRun: dryRun, Reports: reports})
+	return
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+
+	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/models"
+)
+
+func TestDiffProjectCredentials(t *testing.T) {
+	jenkinsCredentials := []*gojenkins.CredentialResponse{
+		{Id: "github", Domain: "_"},
+		{Id: "harbor", Domain: "_"},
+		{Id: "github", Domain: "internal"},
+	}
+	projectCredentials := []*models.ProjectCredential{
+		// created without domain
+		{CredentialId: "github", Domain: ""},
+		{CredentialId: "github", Domain: "internal"},
+		{CredentialId: "deleted", Domain: "_"},
+	}
+	missing, orphans := diffProjectCredentials(jenkinsCredentials, projectCredentials)
+	if len(missing) != 1 || missing[0].CredentialId != "harbor" || missing[0].Domain != "_" {
+		t.Fatalf("should only miss harbor in global domain, got %+v", missing)
+	}
+	if len(orphans) != 1 || orphans[0].CredentialId != "deleted" {
+		t.Fatalf("should only get orphan deleted, got %+v", orphans)
+	}
+}
+
+func TestIsDuplicateEntryError(t *testing.T) {
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'project-1-github-_' for key 'PRIMARY'"}
+	if !isDuplicateEntryError(duplicate) {
+		t.Fatalf("%v should be a duplicate entry error", duplicate)
+	}
+	for _, err := range []error{nil, fmt.Errorf("duplicate"), &mysql.MySQLError{Number: 1146}} {
+		if isDuplicateEntryError(err) {
+			t.Fatalf("%v should not be a duplicate entry error", err)
+		}
+	}
+}
+
+func TestListReconcileCredentialsOrder(t *testing.T) {
+	rows := []*models.ProjectCredential{{CredentialId: "github", Domain: "_"}}
+	jenkinsCredentials := []*gojenkins.CredentialResponse{{Id: "github", Domain: "_"}}
+	// a handler creates harbor in jenkins and inserts its row between the two reads
+	projectCredentials, listed, err := listReconcileCredentials(
+		func() ([]*models.ProjectCredential, error) {
+			return append([]*models.ProjectCredential{}, rows...), nil
+		},
+		func() ([]*gojenkins.CredentialResponse, error) {
+			jenkinsCredentials = append(jenkinsCredentials, &gojenkins.CredentialResponse{Id: "harbor", Domain: "_"})
+			rows = append(rows, &models.ProjectCredential{CredentialId: "harbor", Domain: "_", Creator: "alice"})
+			return jenkinsCredentials, nil
+		})
+	if err != nil {
+		t.Fatalf("unexpected error %+v", err)
+	}
+	missing, orphans := diffProjectCredentials(listed, projectCredentials)
+	if len(orphans) != 0 {
+		t.Fatalf("credential created meanwhile should not be removed, got %+v", orphans)
+	}
+	if len(missing) != 1 || missing[0].CredentialId != "harbor" {
+		t.Fatalf("credential created meanwhile should be backfilled, got %+v", missing)
+	}
+}
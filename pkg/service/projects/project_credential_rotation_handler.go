@@ -0,0 +1,235 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+	"github.com/mitchellh/mapstructure"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+const DefaultCredentialExpiringDays = 30
+
+type RotateCredentialRequest struct {
+	Domain  string                 `json:"domain"`
+	Content map[string]interface{} `json:"content"`
+}
+
+type ExpiringCredentialResponse struct {
+	CredentialId   string     `json:"credential_id"`
+	Domain         string     `json:"domain"`
+	Creator        string     `json:"creator"`
+	KeyFingerprint string     `json:"key_fingerprint"`
+	KeyAlgorithm   string     `json:"key_algorithm"`
+	NotAfter       *time.Time `json:"not_after"`
+	RotateTime     *time.Time `json:"rotate_time,omitempty"`
+	Expired        bool       `json:"expired"`
+	DaysRemaining  int        `json:"days_remaining"`
+}
+
+func (s *ProjectService) GetExpiringCredentialsHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	days := DefaultCredentialExpiringDays
+	if daysParam := r.URL.Query().Get("days"); !govalidator.IsNull(daysParam) {
+		var err error
+		days, err = strconv.Atoi(daysParam)
+		if err != nil || days < 0 {
+			err := fmt.Errorf("error days [%s] should be a positive integer", daysParam)
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	err := s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	now := time.Now()
+	projectCredentials := make([]*models.ProjectCredential, 0)
+	_, err = s.Ds.Db.Select(models.ProjectCredentialColumns...).
+		From(models.ProjectCredentialTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Neq(models.ProjectCredentialNotAfterColumn, nil),
+			db.Lte(models.ProjectCredentialNotAfterColumn, now.AddDate(0, 0, days)))).
+		OrderDir(models.ProjectCredentialNotAfterColumn, true).
+		Load(&projectCredentials)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]*ExpiringCredentialResponse, 0)
+	for _, projectCredential := range projectCredentials {
+		response = append(response, &ExpiringCredentialResponse{
+			CredentialId:   projectCredential.CredentialId,
+			Domain:         projectCredential.Domain,
+			Creator:        projectCredential.Creator,
+			KeyFingerprint: projectCredential.KeyFingerprint,
+			KeyAlgorithm:   projectCredential.KeyAlgorithm,
+			NotAfter:       projectCredential.NotAfter,
+			RotateTime:     projectCredential.RotateTime,
+			Expired:        projectCredential.NotAfter.Before(now),
+			DaysRemaining:  int(projectCredential.NotAfter.Sub(now).Hours() / 24),
+		})
+	}
+	w.WriteJson(response)
+	return
+}
+
+// RotateCredentialHandler replaces the secret of a credential in a single jenkins update,
+// the credential id, username and description are kept.
+func (s *ProjectService) RotateCredentialHandler(w rest.ResponseWriter, r *rest.Request) {
+	request := &RotateCredentialRequest{}
+	projectId := r.PathParams["id"]
+	credentialId := r.PathParams["cid"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectUserPermission(operator, projectId, PermissionManageCredentials)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	jenkinsCredential, err := s.Ds.Jenkins.GetCredentialInFolder(request.Domain, credentialId, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	stringBody, err := s.Ds.Jenkins.GetCredentialContentInFolder(request.Domain, credentialId, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	credentialType := CredentialTypeMap[jenkinsCredential.TypeName]
+	current, err := parseCredentialContent(credentialType, stringBody, false)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if current == nil {
+		err := fmt.Errorf("error unsupport credential type %s", jenkinsCredential.TypeName)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// only the fields in request content override the current credential
+	err = mapstructure.Decode(request.Content, current)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = checkRotateSecret(current, request.Content)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var keyInfo *CredentialKeyInfo
+	if content, ok := current.(*KubeconfigCredentialRequest); ok {
+		keyInfo, err = inspectKubeconfig(content.Content, false)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var id *string
+	switch content := current.(type) {
+	case *UsernamePasswordCredentialRequest:
+		id, err = s.Ds.Jenkins.UpdateUsernamePasswordCredentialInFolder(request.Domain, credentialId,
+			content.Username, content.Password, content.Description, projectId)
+	case *SshCredentialRequest:
+		keyInfo = getSshKeyInfo(content.PrivateKey, content.Passphrase)
+		id, err = s.Ds.Jenkins.UpdateSshCredentialInFolder(request.Domain, credentialId,
+			content.Username, content.Passphrase, content.PrivateKey, content.Description, projectId)
+	case *SecretTextCredentialRequest:
+		id, err = s.Ds.Jenkins.UpdateSecretTextCredentialInFolder(request.Domain, credentialId,
+			content.Secret, content.Description, projectId)
+	case *KubeconfigCredentialRequest:
+		id, err = s.Ds.Jenkins.UpdateKubeconfigCredentialInFolder(request.Domain, credentialId,
+			content.Content, content.Description, projectId)
+	}
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+
+	err = s.saveCredentialKeyInfo(projectId, credentialId, request.Domain, operator, keyInfo, true)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(struct {
+		Id string `json:"id"`
+	}{Id: *id})
+	return
+}
+
+// checkRotateSecret makes sure the request carries a new secret,
+// the current credential may already contain the old private key or kubeconfig.
+func checkRotateSecret(current interface{}, content map[string]interface{}) error {
+	field := ""
+	switch current.(type) {
+	case *UsernamePasswordCredentialRequest:
+		field = "password"
+	case *SshCredentialRequest:
+		field = "private_key"
+	case *SecretTextCredentialRequest:
+		field = "secret"
+	case *KubeconfigCredentialRequest:
+		field = "content"
+	}
+	secret, _ := content[field].(string)
+	if govalidator.IsNull(secret) {
+		return fmt.Errorf("error need %s to rotate credential", field)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testUsernamePasswordContent = `<html><body><form>
+<input name="_.username" type="text" value="admin">
+<input name="_.password" type="password" value="{AQAAABAAAAAQ}">
+<input name="_.id" type="text" value="github">
+<input name="_.description" type="text" value="github account">
+</form></body></html>`
+
+func Test_ParseCredentialContent(t *testing.T) {
+	content, err := parseCredentialContent(CredentialTypeUsernamePassword, testUsernamePasswordContent, false)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	expected := &UsernamePasswordCredentialRequest{
+		Id:          "github",
+		Username:    "admin",
+		Description: "github account",
+	}
+	if !reflect.DeepEqual(content, expected) {
+		t.Fatalf("content [%+v] should equal [%+v]", content, expected)
+	}
+
+	content, err = parseCredentialContent(CredentialTypeUsernamePassword, testUsernamePasswordContent, true)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	expected.Password = "{AQAAABAAAAAQ}"
+	if !reflect.DeepEqual(content, expected) {
+		t.Fatalf("content [%+v] should equal [%+v]", content, expected)
+	}
+
+	content, err = parseCredentialContent("unknown", testUsernamePasswordContent, true)
+	if err != nil || content != nil {
+		t.Fatalf("unknown credential type should have no content")
+	}
+}
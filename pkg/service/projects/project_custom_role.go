@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+)
+
+const (
+	PermissionManageMembers     = "manage_members"
+	PermissionManageCredentials = "manage_credentials"
+	PermissionRunPipelines      = "run_pipelines"
+	PermissionApproveInputs     = "approve_inputs"
+)
+
+var AllPermissionSlice = []string{
+	PermissionManageMembers, PermissionManageCredentials, PermissionRunPipelines, PermissionApproveInputs}
+
+// DefaultRolePermissions are the service permissions of the built-in roles.
+var DefaultRolePermissions = map[string][]string{
+	ProjectOwner: {
+		PermissionManageMembers, PermissionManageCredentials, PermissionRunPipelines, PermissionApproveInputs},
+	ProjectMaintainer: {PermissionManageCredentials, PermissionRunPipelines, PermissionApproveInputs},
+	ProjectDeveloper:  {PermissionRunPipelines, PermissionApproveInputs},
+	ProjectReporter:   {},
+}
+
+// custom role names are part of jenkins role names
+var customRoleNameRegexp = regexp.MustCompile("^[a-z0-9]([-a-z0-9]{0,30}[a-z0-9])?$")
+
+type CustomRole struct {
+	*models.ProjectRole
+	Permissions        []string                       `json:"permissions"`
+	JenkinsPermissions gojenkins.ProjectPermissionIds `json:"jenkins_permissions"`
+}
+
+func newCustomRole(projectRole *models.ProjectRole) (*CustomRole, error) {
+	customRole := &CustomRole{ProjectRole: projectRole, Permissions: make([]string, 0)}
+	err := json.Unmarshal([]byte(projectRole.Permissions), &customRole.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(projectRole.JenkinsPermissions), &customRole.JenkinsPermissions)
+	if err != nil {
+		return nil, err
+	}
+	return customRole, nil
+}
+
+func validateCustomRole(name string, permissions []string) error {
+	if !customRoleNameRegexp.MatchString(name) {
+		return fmt.Errorf("error role name [%s] should match %s", name, customRoleNameRegexp.String())
+	}
+	if reflectutils.In(name, AllRoleSlice) {
+		return fmt.Errorf("error role name [%s] is a built-in role", name)
+	}
+	for _, permission := range permissions {
+		if !reflectutils.In(permission, AllPermissionSlice) {
+			return fmt.Errorf("error permission [%s] not in %s", permission, AllPermissionSlice)
+		}
+	}
+	return nil
+}
+
+// customRoleJenkinsPermissionIds returns the permissions of the jenkins project and pipeline roles,
+// the service permissions add the jenkins permissions they need and every member can read the project.
+// Configuring, deleting and moving the project folder are reserved for project owners.
+func customRoleJenkinsPermissionIds(permissions []string,
+	ids gojenkins.ProjectPermissionIds) (gojenkins.ProjectPermissionIds, gojenkins.ProjectPermissionIds) {
+	ids.ItemRead = true
+	ids.ItemDiscover = true
+	if reflectutils.In(PermissionManageCredentials, permissions) {
+		ids.CredentialCreate = true
+		ids.CredentialUpdate = true
+		ids.CredentialView = true
+		ids.CredentialDelete = true
+		ids.CredentialManageDomains = true
+	}
+	if reflectutils.In(PermissionRunPipelines, permissions) {
+		ids.ItemBuild = true
+		ids.ItemCancel = true
+		ids.ItemWorkspace = true
+		ids.RunReplay = true
+		ids.RunUpdate = true
+	}
+	// submitting an input step without submitter requires the build permission of the job
+	if reflectutils.In(PermissionApproveInputs, permissions) {
+		ids.ItemBuild = true
+	}
+	projectIds := ids
+	projectIds.ItemConfigure = false
+	projectIds.ItemDelete = false
+	projectIds.ItemMove = false
+	return projectIds, ids
+}
+
+func (s *ProjectService) getCustomRole(projectId, name string) (*CustomRole, error) {
+	projectRole := &models.ProjectRole{}
+	err := s.Ds.Db.Select(models.ProjectRoleColumns...).
+		From(models.ProjectRoleTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectRoleNameColumn, name))).LoadOne(projectRole)
+	if err != nil {
+		return nil, err
+	}
+	return newCustomRole(projectRole)
+}
+
+// checkProjectRoleExists checks that the role is a built-in role or a custom role of the project.
+func (s *ProjectService) checkProjectRoleExists(projectId, role string) error {
+	if reflectutils.In(role, AllRoleSlice) {
+		return nil
+	}
+	_, err := s.getCustomRole(projectId, role)
+	if err == db.ErrNotFound {
+		return fmt.Errorf("err role [%s] not in [%s] and not a custom role of project [%s]",
+			role, AllRoleSlice, projectId)
+	}
+	return err
+}
+
+func (s *ProjectService) getProjectRolePermissions(projectId, role string) ([]string, error) {
+	if permissions, ok := DefaultRolePermissions[role]; ok {
+		return permissions, nil
+	}
+	customRole, err := s.getCustomRole(projectId, role)
+	if err != nil {
+		return nil, err
+	}
+	return customRole.Permissions, nil
+}
+
+// syncJenkinsCustomRole creates or overwrites the jenkins roles of a custom role,
+// its pipeline role does not match the restricted pipelines.
+func (s *ProjectService) syncJenkinsCustomRole(customRole *CustomRole) error {
+	projectId := customRole.ProjectId
+	projectIds, pipelineIds := customRoleJenkinsPermissionIds(customRole.Permissions, customRole.JenkinsPermissions)
+	restrictedPipelines, err := s.getRestrictedPipelines(projectId)
+	if err != nil {
+		return err
+	}
+	err = s.overwriteJenkinsProjectRole(projectId, customRole.Name, GetProjectRoleName(projectId, customRole.Name),
+		GetProjectRolePattern(projectId), projectIds)
+	if err != nil {
+		return err
+	}
+	return s.overwriteJenkinsProjectRole(projectId, customRole.Name, GetPipelineRoleName(projectId, customRole.Name),
+		GetRestrictedPipelineRolePattern(projectId, restrictedPipelines), pipelineIds)
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type CustomRoleRequest struct {
+	Name               string                         `json:"name"`
+	Description        string                         `json:"description"`
+	Permissions        []string                       `json:"permissions"`
+	JenkinsPermissions gojenkins.ProjectPermissionIds `json:"jenkins_permissions"`
+}
+
+func (s *ProjectService) GetCustomRolesHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkProjectUserInRole(operator, projectId, AllRoleSlice)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	projectRoles := make([]*models.ProjectRole, 0)
+	_, err = s.Ds.Db.Select(models.ProjectRoleColumns...).
+		From(models.ProjectRoleTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		Load(&projectRoles)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	customRoles := make([]*CustomRole, 0)
+	for _, projectRole := range projectRoles {
+		customRole, err := newCustomRole(projectRole)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		customRoles = append(customRoles, customRole)
+	}
+	w.WriteJson(customRoles)
+	return
+}
+
+func (s *ProjectService) CreateCustomRoleHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	request := &CustomRoleRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = validateCustomRole(request.Name, request.Permissions)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = s.getCustomRole(projectId, request.Name)
+	if err != nil && err != db.ErrNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != db.ErrNotFound {
+		err = fmt.Errorf("role [%s] already exists in project [%s]", request.Name, projectId)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	customRole, err := newCustomRoleFromRequest(projectId, operator, request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.syncJenkinsCustomRole(customRole)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	_, err = s.Ds.Db.InsertInto(models.ProjectRoleTableName).
+		Columns(models.ProjectRoleColumns...).
+		Record(customRole.ProjectRole).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(customRole)
+	return
+}
+
+func (s *ProjectService) UpdateCustomRoleHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	name := r.PathParams["rid"]
+	operator := userutils.GetUserNameFromRequest(r)
+	request := &CustomRoleRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	request.Name = name
+	err = validateCustomRole(request.Name, request.Permissions)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	oldRole, err := s.getCustomRole(projectId, name)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	customRole, err := newCustomRoleFromRequest(projectId, oldRole.Creator, request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	customRole.CreateTime = oldRole.CreateTime
+	err = s.syncJenkinsCustomRole(customRole)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	_, err = s.Ds.Db.Update(models.ProjectRoleTableName).
+		Set(models.ProjectRoleDescriptionColumn, customRole.Description).
+		Set(models.ProjectRolePermissionsColumn, customRole.ProjectRole.Permissions).
+		Set(models.ProjectRoleJenkinsPermissionsColumn, customRole.ProjectRole.JenkinsPermissions).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectRoleNameColumn, name))).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(customRole)
+	return
+}
+
+// DeleteCustomRoleHandler deletes a custom role which is not held by any member or group.
+func (s *ProjectService) DeleteCustomRoleHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	name := r.PathParams["rid"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = s.getCustomRole(projectId, name)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	count, err := s.Ds.Db.Select(models.ProjectMembershipUsernameColumn).
+		From(models.ProjectMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+			db.Eq(models.ProjectMembershipRoleColumn, name))).Count()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	groupCount, err := s.Ds.Db.Select(models.GroupIdColumn).
+		From(models.ProjectGroupBindingTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectGroupBindingRoleColumn, name))).Count()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pipelineCount, err := s.Ds.Db.Select(models.ProjectMembershipUsernameColumn).
+		From(models.PipelineMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectMembershipRoleColumn, name))).Count()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if count > 0 || groupCount > 0 || pipelineCount > 0 {
+		err = fmt.Errorf("role [%s] is still held by %d members, %d groups and %d pipeline members",
+			name, count, groupCount, pipelineCount)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.Ds.Jenkins.DeleteProjectRoles(GetProjectRoleName(projectId, name), GetPipelineRoleName(projectId, name))
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	_, err = s.Ds.Db.DeleteFrom(models.ProjectRoleTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectRoleNameColumn, name))).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(struct {
+		Name string `json:"name"`
+	}{Name: name})
+	return
+}
+
+func newCustomRoleFromRequest(projectId, creator string, request *CustomRoleRequest) (*CustomRole, error) {
+	if request.Permissions == nil {
+		request.Permissions = make([]string, 0)
+	}
+	permissions, err := json.Marshal(request.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	jenkinsPermissions, err := json.Marshal(request.JenkinsPermissions)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomRole{
+		ProjectRole: models.NewProjectRole(projectId, request.Name, request.Description,
+			string(permissions), string(jenkinsPermissions), creator),
+		Permissions:        request.Permissions,
+		JenkinsPermissions: request.JenkinsPermissions,
+	}, nil
+}
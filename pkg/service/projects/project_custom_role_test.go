@@ -0,0 +1,55 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"testing"
+
+	"kubesphere.io/devops/pkg/gojenkins"
+)
+
+func TestValidateCustomRole(t *testing.T) {
+	err := validateCustomRole("release-manager", []string{PermissionRunPipelines, PermissionApproveInputs})
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	for _, test := range []struct {
+		name        string
+		permissions []string
+	}{
+		{ProjectOwner, nil},
+		{"Release", nil},
+		{"release-", nil},
+		{"release-manager", []string{"delete_project"}},
+	} {
+		if err := validateCustomRole(test.name, test.permissions); err == nil {
+			t.Fatalf("role %s with permissions %s should be invalid", test.name, test.permissions)
+		}
+	}
+}
+
+func TestCustomRoleJenkinsPermissionIds(t *testing.T) {
+	projectIds, pipelineIds := customRoleJenkinsPermissionIds(
+		[]string{PermissionManageCredentials, PermissionApproveInputs},
+		gojenkins.ProjectPermissionIds{ItemConfigure: true, ItemDelete: true})
+	if !projectIds.ItemRead || !projectIds.CredentialCreate || !pipelineIds.ItemBuild {
+		t.Fatalf("service permissions should add jenkins permissions, got %+v", pipelineIds)
+	}
+	if pipelineIds.ItemCancel || pipelineIds.RunReplay {
+		t.Fatalf("approving inputs should not allow to run pipelines, got %+v", pipelineIds)
+	}
+	if projectIds.ItemConfigure || projectIds.ItemDelete || !pipelineIds.ItemConfigure || !pipelineIds.ItemDelete {
+		t.Fatalf("project folder should not be configured or deleted, got %+v %+v", projectIds, pipelineIds)
+	}
+}
@@ -14,9 +14,9 @@ limitations under the License.
 package projects
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
-	"sync"
 
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/asaskevich/govalidator"
@@ -27,6 +27,7 @@ import (
 	"kubesphere.io/devops/pkg/gojenkins"
 	"kubesphere.io/devops/pkg/logger"
 	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
 	"kubesphere.io/devops/pkg/utils/stringutils"
 	"kubesphere.io/devops/pkg/utils/userutils"
 )
@@ -35,11 +36,31 @@ type CreateProjectRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Extra       string `json:"extra"`
+	// TemplateId is the optional template applied to the project with Variables.
+	TemplateId  string            `json:"template_id"`
+	Variables   map[string]string `json:"variables"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
 }
 
+type CreateProjectResponse struct {
+	*ProjectResponse
+	TemplateItems []*BundleItemResult `json:"template_items,omitempty"`
+}
+
+// UpdateProjectRequest replaces the labels and the annotations if they are not null.
 type UpdateProjectRequest struct {
-	Description string `json:"description"`
-	Extra       string `json:"extra"`
+	Description string            `json:"description"`
+	Extra       string            `json:"extra"`
+	Visibility  string            `json:"visibility"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type ProjectResponse struct {
+	*models.Project
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
 }
 
 type AddProjectMemberRequest struct {
@@ -81,18 +102,35 @@ func (s *ProjectService) GetProjectHandler(w rest.ResponseWriter, r *rest.Reques
 		rest.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
-	w.WriteJson(project)
+	responses, err := s.newProjectResponses([]*models.Project{project})
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(responses[0])
 	return
 }
 
 func (s *ProjectService) GetProjectsHandler(w rest.ResponseWriter, r *rest.Request) {
 	operator := userutils.GetUserNameFromRequest(r)
 	id := r.URL.Query().Get("id")
+	requirements, err := parseLabelSelector(r.URL.Query().Get(LabelSelectorParam))
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	query := s.Ds.Db.Select(models.ProjectColumns...).
 		From(models.ProjectTableName)
-	var conditions []dbr.Builder
-	switch operator {
-	case constants.KS_ADMIN:
+	conditions, err := s.labelSelectorConditions(requirements)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	switch {
+	case s.hasGlobalRole(operator, GlobalRolePlatformAdmin, GlobalRoleAuditor):
 		if !govalidator.IsNull(id) {
 			ids := strings.Split(id, ",")
 			conditions = append(conditions, db.Eq(models.ProjectIdColumn, ids))
@@ -119,19 +157,47 @@ func (s *ProjectService) GetProjectsHandler(w rest.ResponseWriter, r *rest.Reque
 		for _, projectMembership := range projectMemberships {
 			projectIdArray = append(projectIdArray, projectMembership.ProjectId)
 		}
-		conditions = append(conditions, db.Eq(models.ProjectIdColumn, projectIdArray))
+		groupBindings, err := s.getUserGroupBindings(operator, "")
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, groupBinding := range groupBindings {
+			if !reflectutils.In(groupBinding.ProjectId, projectIdArray) {
+				projectIdArray = append(projectIdArray, groupBinding.ProjectId)
+			}
+		}
+		// public projects are listed for every user
+		// trashed projects are listed in the trash
+		conditions = append(conditions, db.Or(
+			db.And(
+				db.Eq(models.ProjectIdColumn, projectIdArray),
+				db.Neq(constants.StatusColumn, constants.StatusTrashed)),
+			db.And(
+				db.Eq(models.ProjectVisibilityColumn, constants.VisibilityPublic),
+				db.Eq(constants.StatusColumn, []string{constants.StatusActive, constants.StatusArchived}))))
+		if !govalidator.IsNull(id) {
+			conditions = append(conditions, db.Eq(models.ProjectIdColumn, strings.Split(id, ",")))
+		}
 	}
 	projects := make([]*models.Project, 0)
 	if len(conditions) > 0 {
 		query.Where(db.And(conditions...))
 	}
-	_, err := query.Load(&projects)
+	_, err = query.Load(&projects)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	w.WriteJson(projects)
+	responses, err := s.newProjectResponses(projects)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(responses)
 	return
 }
 
@@ -144,165 +210,192 @@ func (s *ProjectService) CreateProjectHandler(w rest.ResponseWriter, r *rest.Req
 		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	project := models.NewProject(request.Name, request.Description, creator, request.Extra)
-	_, err = s.Ds.Jenkins.CreateFolder(project.ProjectId, project.Description)
-	if err != nil {
+	if !s.canCreateProject(creator) {
+		err := fmt.Errorf("user [%s] can not create project", creator)
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
-
-	var addRoleCh = make(chan *ProjectRoleResponse, 8)
-	var addRoleWg sync.WaitGroup
-	for role, permission := range JenkinsProjectPermissionMap {
-		addRoleWg.Add(1)
-		go func(role string, permission gojenkins.ProjectPermissionIds) {
-			_, err := s.Ds.Jenkins.AddProjectRole(GetProjectRoleName(project.ProjectId, role),
-				GetProjectRolePattern(project.ProjectId), permission, true)
-			addRoleCh <- &ProjectRoleResponse{nil, err}
-			addRoleWg.Done()
-		}(role, permission)
-	}
-	for role, permission := range JenkinsPipelinePermissionMap {
-		addRoleWg.Add(1)
-		go func(role string, permission gojenkins.ProjectPermissionIds) {
-			_, err := s.Ds.Jenkins.AddProjectRole(GetPipelineRoleName(project.ProjectId, role),
-				GetPipelineRolePattern(project.ProjectId), permission, true)
-			addRoleCh <- &ProjectRoleResponse{nil, err}
-			addRoleWg.Done()
-		}(role, permission)
-	}
-	addRoleWg.Wait()
-	close(addRoleCh)
-	for addRoleResponse := range addRoleCh {
-		if addRoleResponse.Err != nil {
-			logger.Error("%+v", addRoleResponse.Err)
-			rest.Error(w, addRoleResponse.Err.Error(), stringutils.GetJenkinsStatusCode(addRoleResponse.Err))
-			return
-		}
+	err = validateProjectLabels(request.Labels)
+	if err == nil {
+		err = validateProjectAnnotations(request.Annotations)
 	}
-
-	globalRole, err := s.Ds.Jenkins.GetGlobalRole(constants.JenkinsAllUserRoleName)
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if globalRole == nil {
-		_, err := s.Ds.Jenkins.AddGlobalRole(constants.JenkinsAllUserRoleName, gojenkins.GlobalPermissionIds{
-			GlobalRead: true,
-		}, true)
+	var templateItems []*BundleItemResult
+	if request.TemplateId != "" {
+		template, err := s.getProjectTemplate(request.TemplateId)
 		if err != nil {
-			logger.Critical("failed to create jenkins global role")
-			panic(err)
+			logger.Error("%+v", err)
+			if err == db.ErrNotFound {
+				rest.Error(w, fmt.Sprintf("template [%s] not found", request.TemplateId), http.StatusNotFound)
+				return
+			}
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		content, err := parseProjectTemplateContent(template)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		content, err = renderProjectTemplate(content, request.Name, request.Variables)
+		if err == nil {
+			templateItems, err = templateImportItems(content, creator)
+		}
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 	}
-	err = globalRole.AssignRole(creator)
+	project := models.NewProject(request.Name, request.Description, creator, request.Extra)
+	failedStep, err := s.createProject(project, GetOperationStepRecorder(r))
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		if failedStep == "" {
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rest.Error(w, fmt.Sprintf("failed to create project at step [%s], %s", failedStep, err.Error()),
+			stringutils.GetJenkinsStatusCode(err))
 		return
 	}
-
-	projectRole, err := s.Ds.Jenkins.GetProjectRole(GetProjectRoleName(project.ProjectId, ProjectOwner))
+	err = s.setProjectMetadata(project.ProjectId, request.Labels, request.Annotations)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	err = projectRole.AssignRole(creator)
+	// the items of the template are applied one by one, a failed item is reported without rolling back the project
+	s.applyProjectImport(project.ProjectId, templateItems, creator)
+	responses, err := s.newProjectResponses([]*models.Project{project})
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.WriteJson(&CreateProjectResponse{ProjectResponse: responses[0], TemplateItems: templateItems})
+	return
+}
 
-	pipelineRole, err := s.Ds.Jenkins.GetProjectRole(GetPipelineRoleName(project.ProjectId, ProjectOwner))
+func (s *ProjectService) DeleteProjectHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
-	err = pipelineRole.AssignRole(creator)
+	err = s.checkProjectNotArchived(projectId)
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	_, err = s.Ds.Db.InsertInto(models.ProjectTableName).
-		Columns(models.ProjectColumns...).Record(project).Exec()
+	project, err := s.getProject(projectId)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	projectMembership := models.NewProjectMemberShip(creator, project.ProjectId, ProjectOwner, creator)
-	_, err = s.Ds.Db.InsertInto(models.ProjectMembershipTableName).
-		Columns(models.ProjectMembershipColumns...).Record(projectMembership).Exec()
+	err = s.trashProject(project)
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
 		return
 	}
 	w.WriteJson(project)
 	return
 }
 
-func (s *ProjectService) DeleteProjectHandler(w rest.ResponseWriter, r *rest.Request) {
+// RestoreProjectHandler moves a project out of the trash, only platform admins and owners are allowed.
+func (s *ProjectService) RestoreProjectHandler(w rest.ResponseWriter, r *rest.Request) {
 	projectId := r.PathParams["id"]
 	operator := userutils.GetUserNameFromRequest(r)
-	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	if !s.isPlatformAdmin(operator) {
+		// trashed projects have no roles, so the owner is looked up in the memberships
+		role, _, err := s.getMemberProjectRoles(operator, projectId)
+		if err != nil || role != ProjectOwner {
+			err = fmt.Errorf("user [%s] is not the owner of project [%s]", operator, projectId)
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	project, err := s.getProject(projectId)
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), http.StatusForbidden)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_, err = s.Ds.Jenkins.DeleteJob(projectId)
-
-	if err != nil && stringutils.GetJenkinsStatusCode(err) != http.StatusNotFound {
+	if project.Status != constants.StatusTrashed {
+		err = fmt.Errorf("project [%s] is not in the trash", projectId)
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	roleNames := make([]string, 0)
-	for role := range JenkinsProjectPermissionMap {
-		roleNames = append(roleNames, GetProjectRoleName(projectId, role))
-		roleNames = append(roleNames, GetPipelineRoleName(projectId, role))
-	}
-	err = s.Ds.Jenkins.DeleteProjectRoles(roleNames...)
-	if err != nil {
+	err = s.restoreProject(project)
+	if err == errProjectStatusChanged {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		rest.Error(w, err.Error(), http.StatusConflict)
 		return
 	}
-	_, err = s.Ds.Db.DeleteFrom(models.ProjectMembershipTableName).
-		Where(db.Eq(models.ProjectMembershipProjectIdColumn, projectId)).Exec()
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
 		return
 	}
-	_, err = s.Ds.Db.Update(models.ProjectTableName).
-		Set(constants.StatusColumn, constants.StatusDeleted).
-		Where(db.Eq(models.ProjectIdColumn, projectId)).Exec()
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	w.WriteJson(project)
+	return
+}
+
+// GetTrashHandler lists the trashed projects, platform admins see all of them and others see the ones they own.
+func (s *ProjectService) GetTrashHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	query := s.Ds.Db.Select(models.ProjectColumns...).
+		From(models.ProjectTableName)
+	if s.isPlatformAdmin(operator) {
+		query = query.Where(db.Eq(constants.StatusColumn, constants.StatusTrashed))
+	} else {
+		memberships := make([]*models.ProjectMembership, 0)
+		_, err := s.Ds.Db.Select(models.ProjectMembershipProjectIdColumn).
+			From(models.ProjectMembershipTableName).
+			Where(db.And(
+				db.Eq(models.ProjectMembershipUsernameColumn, operator),
+				db.Eq(models.ProjectMembershipRoleColumn, ProjectOwner),
+				db.Eq(constants.StatusColumn, constants.StatusActive))).
+			Load(&memberships)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		projectIds := make([]string, 0)
+		for _, membership := range memberships {
+			projectIds = append(projectIds, membership.ProjectId)
+		}
+		if len(projectIds) == 0 {
+			w.WriteJson([]*models.Project{})
+			return
+		}
+		query = query.Where(db.And(
+			db.Eq(constants.StatusColumn, constants.StatusTrashed),
+			db.Eq(models.ProjectIdColumn, projectIds)))
 	}
-	project := &models.Project{}
-	err = s.Ds.Db.Select(models.ProjectColumns...).
-		From(models.ProjectTableName).
-		Where(db.Eq(models.ProjectIdColumn, projectId)).
-		LoadOne(project)
+	projects := make([]*models.Project, 0)
+	_, err := query.OrderDir(models.ProjectDeleteTimeColumn, false).Load(&projects)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	w.WriteJson(project)
+	w.WriteJson(projects)
 	return
 }
 
@@ -322,6 +415,27 @@ func (s *ProjectService) UpdateProjectHandler(w rest.ResponseWriter, r *rest.Req
 		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !govalidator.IsNull(request.Visibility) && !reflectutils.In(request.Visibility, VisibilitySlice) {
+		err := fmt.Errorf("error visibility [%s] not in %s", request.Visibility, VisibilitySlice)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = validateProjectLabels(request.Labels)
+	if err == nil {
+		err = validateProjectAnnotations(request.Annotations)
+	}
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	query := s.Ds.Db.Update(models.ProjectTableName)
 	if !govalidator.IsNull(request.Description) {
 		query.Set(models.ProjectDescriptionColumn, request.Description)
@@ -329,8 +443,12 @@ func (s *ProjectService) UpdateProjectHandler(w rest.ResponseWriter, r *rest.Req
 	if !govalidator.IsNull(request.Extra) {
 		query.Set(models.ProjectExtraColumn, request.Extra)
 	}
-	if !govalidator.IsNull(request.Description) || !govalidator.IsNull(request.Extra) {
-		query.
+	if !govalidator.IsNull(request.Visibility) {
+		query.Set(models.ProjectVisibilityColumn, request.Visibility)
+	}
+	if !govalidator.IsNull(request.Description) || !govalidator.IsNull(request.Extra) ||
+		!govalidator.IsNull(request.Visibility) {
+		_, err = query.
 			Where(db.Eq(models.ProjectIdColumn, projectId)).Exec()
 		if err != nil {
 			logger.Error("%+v", err)
@@ -338,6 +456,20 @@ func (s *ProjectService) UpdateProjectHandler(w rest.ResponseWriter, r *rest.Req
 			return
 		}
 	}
+	if !govalidator.IsNull(request.Visibility) {
+		err = s.SyncJenkinsPublicRole()
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+			return
+		}
+	}
+	err = s.setProjectMetadata(projectId, request.Labels, request.Annotations)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	project := &models.Project{}
 	err = s.Ds.Db.Select(models.ProjectColumns...).
 		From(models.ProjectTableName).
@@ -348,6 +480,54 @@ func (s *ProjectService) UpdateProjectHandler(w rest.ResponseWriter, r *rest.Req
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	w.WriteJson(project)
+	responses, err := s.newProjectResponses([]*models.Project{project})
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(responses[0])
+	return
+}
+
+// GetProjectCreationStepsHandler shows the creation steps of a project,
+// the creator can view them after a failed creation left the project without members.
+func (s *ProjectService) GetProjectCreationStepsHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	project := &models.Project{}
+	err := s.Ds.Db.Select(models.ProjectColumns...).
+		From(models.ProjectTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		LoadOne(project)
+	if err != nil && err != db.ErrNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err == db.ErrNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if project.Creator != operator {
+		err = s.checkProjectUserInRole(operator, projectId, AllRoleSlice)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	steps, err := s.getProjectCreationSteps(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(struct {
+		ProjectId string                        `json:"project_id"`
+		Status    string                        `json:"status"`
+		Steps     []*models.ProjectCreationStep `json:"steps"`
+	}{ProjectId: projectId, Status: project.Status, Steps: steps})
 	return
 }
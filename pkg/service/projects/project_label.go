@@ -0,0 +1,283 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gocraft/dbr"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/models"
+)
+
+const (
+	LabelSelectorParam = "labelSelector"
+
+	LabelOperatorEquals    = "="
+	LabelOperatorNotEquals = "!="
+	LabelOperatorExists    = "exists"
+	LabelOperatorNotExists = "!"
+
+	labelNameMaxLength   = 63
+	labelPrefixMaxLength = 253
+	// annotationsMaxSize is the max total size of the keys and values of the annotations of a project
+	annotationsMaxSize = 256 * 1024
+)
+
+var (
+	labelNameRegexp   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	labelPrefixRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// LabelRequirement is a requirement of a label selector, Value is only used by = and !=.
+type LabelRequirement struct {
+	Key      string
+	Operator string
+	Value    string
+}
+
+// validateLabelKey checks a key of labels and annotations, the key is a name with an optional dns subdomain prefix
+// as "example.com/team".
+func validateLabelKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if len(prefix) > labelPrefixMaxLength || !labelPrefixRegexp.MatchString(prefix) {
+			return fmt.Errorf("invalid prefix of key [%s], it must be a dns subdomain of at most %d characters",
+				key, labelPrefixMaxLength)
+		}
+	}
+	if len(name) > labelNameMaxLength || !labelNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid name of key [%s], it must be at most %d alphanumeric characters, "+
+			"'-', '_' or '.' and start and end with an alphanumeric character", key, labelNameMaxLength)
+	}
+	return nil
+}
+
+// validateLabelValue checks the value of a label, an empty value is allowed.
+func validateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > labelNameMaxLength || !labelNameRegexp.MatchString(value) {
+		return fmt.Errorf("invalid label value [%s], it must be at most %d alphanumeric characters, "+
+			"'-', '_' or '.' and start and end with an alphanumeric character", value, labelNameMaxLength)
+	}
+	return nil
+}
+
+func validateProjectLabels(labels map[string]string) error {
+	for key, value := range labels {
+		err := validateLabelKey(key)
+		if err != nil {
+			return err
+		}
+		err = validateLabelValue(value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateProjectAnnotations(annotations map[string]string) error {
+	size := 0
+	for key, value := range annotations {
+		err := validateLabelKey(key)
+		if err != nil {
+			return err
+		}
+		size += len(key) + len(value)
+	}
+	if size > annotationsMaxSize {
+		return fmt.Errorf("annotations are too long, the total size must be at most %d bytes", annotationsMaxSize)
+	}
+	return nil
+}
+
+// parseLabelSelector parses comma separated requirements as "team=payments,env!=prod,tier,!legacy",
+// "==" is the same as "=".
+func parseLabelSelector(selector string) ([]*LabelRequirement, error) {
+	requirements := make([]*LabelRequirement, 0)
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		requirement := &LabelRequirement{}
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			requirement.Key, requirement.Operator, requirement.Value = parts[0], LabelOperatorNotEquals, parts[1]
+		case strings.Contains(term, "=="):
+			parts := strings.SplitN(term, "==", 2)
+			requirement.Key, requirement.Operator, requirement.Value = parts[0], LabelOperatorEquals, parts[1]
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			requirement.Key, requirement.Operator, requirement.Value = parts[0], LabelOperatorEquals, parts[1]
+		case strings.HasPrefix(term, "!"):
+			requirement.Key, requirement.Operator = term[1:], LabelOperatorNotExists
+		default:
+			requirement.Key, requirement.Operator = term, LabelOperatorExists
+		}
+		requirement.Key = strings.TrimSpace(requirement.Key)
+		requirement.Value = strings.TrimSpace(requirement.Value)
+		err := validateLabelKey(requirement.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector [%s], %s", selector, err.Error())
+		}
+		err = validateLabelValue(requirement.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector [%s], %s", selector, err.Error())
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements, nil
+}
+
+// labelSelectorConditions returns the conditions of the projects selected by the requirements,
+// the ids of the projects with a label are found by the index of labels.
+func (s *ProjectService) labelSelectorConditions(requirements []*LabelRequirement) ([]dbr.Builder, error) {
+	conditions := make([]dbr.Builder, 0)
+	for _, requirement := range requirements {
+		labelConditions := []dbr.Builder{db.Eq(models.ProjectLabelKeyColumn, requirement.Key)}
+		if requirement.Operator == LabelOperatorEquals || requirement.Operator == LabelOperatorNotEquals {
+			labelConditions = append(labelConditions, db.Eq(models.ProjectLabelValueColumn, requirement.Value))
+		}
+		labels := make([]*models.ProjectLabel, 0)
+		_, err := s.Ds.Db.Select(models.ProjectLabelColumns...).
+			From(models.ProjectLabelTableName).
+			Where(db.And(labelConditions...)).
+			Load(&labels)
+		if err != nil {
+			return nil, err
+		}
+		projectIds := make([]string, 0)
+		for _, label := range labels {
+			projectIds = append(projectIds, label.ProjectId)
+		}
+		switch requirement.Operator {
+		case LabelOperatorEquals, LabelOperatorExists:
+			conditions = append(conditions, db.Eq(models.ProjectIdColumn, projectIds))
+		default:
+			conditions = append(conditions, db.Neq(models.ProjectIdColumn, projectIds))
+		}
+	}
+	return conditions, nil
+}
+
+// getProjectsMetadata returns the labels and the annotations of projects by project id.
+func (s *ProjectService) getProjectsMetadata(projectIds []string) (map[string]map[string]string,
+	map[string]map[string]string, error) {
+	labels := make(map[string]map[string]string)
+	annotations := make(map[string]map[string]string)
+	for _, projectId := range projectIds {
+		labels[projectId] = make(map[string]string)
+		annotations[projectId] = make(map[string]string)
+	}
+	if len(projectIds) == 0 {
+		return labels, annotations, nil
+	}
+	projectLabels := make([]*models.ProjectLabel, 0)
+	_, err := s.Ds.Db.Select(models.ProjectLabelColumns...).
+		From(models.ProjectLabelTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectIds)).
+		Load(&projectLabels)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, label := range projectLabels {
+		labels[label.ProjectId][label.LabelKey] = label.LabelValue
+	}
+	projectAnnotations := make([]*models.ProjectAnnotation, 0)
+	_, err = s.Ds.Db.Select(models.ProjectAnnotationColumns...).
+		From(models.ProjectAnnotationTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectIds)).
+		Load(&projectAnnotations)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, annotation := range projectAnnotations {
+		annotations[annotation.ProjectId][annotation.AnnotationKey] = annotation.AnnotationValue
+	}
+	return labels, annotations, nil
+}
+
+// setProjectMetadata replaces the labels and the annotations of a project, nil maps are not changed.
+func (s *ProjectService) setProjectMetadata(projectId string, labels, annotations map[string]string) error {
+	if labels == nil && annotations == nil {
+		return nil
+	}
+	tx, err := s.Ds.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.RollbackUnlessCommitted()
+	if labels != nil {
+		_, err = tx.DeleteFrom(models.ProjectLabelTableName).
+			Where(db.Eq(models.ProjectIdColumn, projectId)).Exec()
+		if err != nil {
+			return err
+		}
+		for key, value := range labels {
+			_, err = tx.InsertInto(models.ProjectLabelTableName).
+				Columns(models.ProjectLabelColumns...).
+				Record(models.NewProjectLabel(projectId, key, value)).Exec()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if annotations != nil {
+		_, err = tx.DeleteFrom(models.ProjectAnnotationTableName).
+			Where(db.Eq(models.ProjectIdColumn, projectId)).Exec()
+		if err != nil {
+			return err
+		}
+		for key, value := range annotations {
+			_, err = tx.InsertInto(models.ProjectAnnotationTableName).
+				Columns(models.ProjectAnnotationColumns...).
+				Record(models.NewProjectAnnotation(projectId, key, value)).Exec()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// newProjectResponses adds the labels and the annotations to projects.
+func (s *ProjectService) newProjectResponses(projects []*models.Project) ([]*ProjectResponse, error) {
+	projectIds := make([]string, 0)
+	for _, project := range projects {
+		projectIds = append(projectIds, project.ProjectId)
+	}
+	labels, annotations, err := s.getProjectsMetadata(projectIds)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*ProjectResponse, 0)
+	for _, project := range projects {
+		responses = append(responses, &ProjectResponse{
+			Project:     project,
+			Labels:      labels[project.ProjectId],
+			Annotations: annotations[project.ProjectId],
+		})
+	}
+	return responses, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateProjectLabels(t *testing.T) {
+	valid := map[string]string{
+		"team":                  "payments",
+		"env":                   "",
+		"kubesphere.io/tier":    "back_end.v1",
+		"a":                     "B-2",
+		strings.Repeat("k", 63): strings.Repeat("v", 63),
+	}
+	if err := validateProjectLabels(valid); err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	invalid := []map[string]string{
+		{"": "payments"},
+		{"-team": "payments"},
+		{"team.": "payments"},
+		{"Kubesphere.io/team": "payments"},
+		{"/team": "payments"},
+		{"kubesphere.io/": "payments"},
+		{strings.Repeat("k", 64): "payments"},
+		{"team": "pay ments"},
+		{"team": "payments-"},
+		{"team": strings.Repeat("v", 64)},
+	}
+	for _, labels := range invalid {
+		if err := validateProjectLabels(labels); err == nil {
+			t.Fatalf("labels %v should get error", labels)
+		}
+	}
+}
+
+func TestValidateProjectAnnotations(t *testing.T) {
+	if err := validateProjectAnnotations(map[string]string{"kubesphere.io/description": "any text, with spaces"}); err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if err := validateProjectAnnotations(map[string]string{"bad key": "value"}); err == nil {
+		t.Fatalf("invalid key should get error")
+	}
+	if err := validateProjectAnnotations(map[string]string{"note": strings.Repeat("x", annotationsMaxSize)}); err == nil {
+		t.Fatalf("too long annotations should get error")
+	}
+}
+
+func TestParseLabelSelector(t *testing.T) {
+	requirements, err := parseLabelSelector("team=payments, env!=prod,tier==backend,owner,!legacy")
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	expected := []*LabelRequirement{
+		{Key: "team", Operator: LabelOperatorEquals, Value: "payments"},
+		{Key: "env", Operator: LabelOperatorNotEquals, Value: "prod"},
+		{Key: "tier", Operator: LabelOperatorEquals, Value: "backend"},
+		{Key: "owner", Operator: LabelOperatorExists},
+		{Key: "legacy", Operator: LabelOperatorNotExists},
+	}
+	if !reflect.DeepEqual(requirements, expected) {
+		t.Fatalf("requirements should be %+v, got %+v", expected, requirements)
+	}
+
+	requirements, err = parseLabelSelector("")
+	if err != nil || len(requirements) != 0 {
+		t.Fatalf("empty selector should select everything, got %+v %+v", requirements, err)
+	}
+
+	for _, selector := range []string{"=payments", "team=pay ments", "team in (a,b)", "!"} {
+		if _, err := parseLabelSelector(selector); err == nil {
+			t.Fatalf("selector [%s] should get error", selector)
+		}
+	}
+}
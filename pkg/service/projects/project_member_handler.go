@@ -16,16 +16,15 @@ package projects
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/asaskevich/govalidator"
 
 	"kubesphere.io/devops/pkg/constants"
 	"kubesphere.io/devops/pkg/db"
-	"kubesphere.io/devops/pkg/gojenkins"
 	"kubesphere.io/devops/pkg/logger"
 	"kubesphere.io/devops/pkg/models"
-	"kubesphere.io/devops/pkg/utils/reflectutils"
 	"kubesphere.io/devops/pkg/utils/stringutils"
 	"kubesphere.io/devops/pkg/utils/userutils"
 )
@@ -121,83 +120,57 @@ func (s *ProjectService) AddProjectMemberHandler(w rest.ResponseWriter, r *rest.
 		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if !reflectutils.In(request.Role, AllRoleSlice) {
-		err := fmt.Errorf("err role [%s] not in [%s]", request.Role,
-			AllRoleSlice)
+	err = s.checkProjectMemberManager(operator, projectId, request.Role)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	err = s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	err = s.checkProjectRoleExists(projectId, request.Role)
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), http.StatusForbidden)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	membership := &models.ProjectMembership{}
-	err = s.Ds.Db.Select(models.ProjectMembershipColumns...).
-		From(models.ProjectMembershipTableName).
-		Where(db.And(
-			db.Eq(models.ProjectMembershipUsernameColumn, request.Username),
-			db.Eq(models.ProjectMembershipProjectIdColumn, projectId))).LoadOne(membership)
+	membership, err := s.getProjectMembership(projectId, request.Username)
 	if err != nil && err != db.ErrNotFound {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err != db.ErrNotFound {
+	if err != db.ErrNotFound && !invitationExpired(membership, time.Now()) {
 		err = fmt.Errorf("user [%s] have been added to project", request.Username)
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	globalRole, err := s.Ds.Jenkins.GetGlobalRole(constants.JenkinsAllUserRoleName)
+	err = s.checkProjectQuota(projectId, QuotaMembers)
 	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		writeProjectQuotaError(w, err)
 		return
 	}
-	if globalRole == nil {
-		_, err := s.Ds.Jenkins.AddGlobalRole(constants.JenkinsAllUserRoleName, gojenkins.GlobalPermissionIds{
-			GlobalRead: true,
-		}, true)
+	if err != db.ErrNotFound {
+		_, err = s.Ds.Db.DeleteFrom(models.ProjectMembershipTableName).
+			Where(db.And(
+				db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+				db.Eq(models.ProjectMembershipUsernameColumn, request.Username),
+			)).Exec()
 		if err != nil {
-			logger.Critical("failed to create jenkins global role")
-			panic(err)
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 	}
-	err = globalRole.AssignRole(request.Username)
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	projectRole, err := s.Ds.Jenkins.GetProjectRole(GetProjectRoleName(projectId, request.Role))
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	err = projectRole.AssignRole(request.Username)
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	pipelineRole, err := s.Ds.Jenkins.GetProjectRole(GetPipelineRoleName(projectId, request.Role))
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	err = pipelineRole.AssignRole(request.Username)
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	projectMembership := models.NewProjectMemberShip(request.Username, projectId, request.Role, operator)
+
+	// jenkins roles are assigned when the invitee accepts the invitation
+	projectMembership := models.NewProjectInvitation(request.Username, projectId, request.Role, operator,
+		time.Now().Add(ProjectInvitationTTL))
 	_, err = s.Ds.Db.
 		InsertInto(models.ProjectMembershipTableName).
 		Columns(models.ProjectMembershipColumns...).
@@ -228,16 +201,22 @@ func (s *ProjectService) UpdateMemberHandler(w rest.ResponseWriter, r *rest.Requ
 		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if !reflectutils.In(request.Role, AllRoleSlice) {
-		err := fmt.Errorf("err role [%s] not in [%s]", request.Role, AllRoleSlice)
+	err = s.checkProjectMemberManager(operator, projectId, request.Role)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	err = s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	err = s.checkProjectRoleExists(projectId, request.Role)
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), http.StatusForbidden)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	oldMembership := &models.ProjectMembership{}
@@ -252,57 +231,22 @@ func (s *ProjectService) UpdateMemberHandler(w rest.ResponseWriter, r *rest.Requ
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	oldProjectRole, err := s.Ds.Jenkins.GetProjectRole(GetProjectRoleName(projectId, oldMembership.Role))
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	err = oldProjectRole.UnAssignRole(username)
+	err = s.checkProjectMemberManager(operator, projectId, oldMembership.Role)
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
-	oldPipelineRole, err := s.Ds.Jenkins.GetProjectRole(GetPipelineRoleName(projectId, oldMembership.Role))
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	err = oldPipelineRole.UnAssignRole(username)
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
+	if request.Role != ProjectOwner {
+		err = s.checkProjectKeepsOwner(oldMembership)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
-	projectRole, err := s.Ds.Jenkins.GetProjectRole(GetProjectRoleName(projectId, request.Role))
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	err = projectRole.AssignRole(username)
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	pipelineRole, err := s.Ds.Jenkins.GetProjectRole(GetPipelineRoleName(projectId, request.Role))
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	err = pipelineRole.AssignRole(username)
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
 	_, err = s.Ds.Db.Update(models.ProjectMembershipTableName).
 		Set(models.ProjectMembershipRoleColumn, request.Role).
 		Where(db.And(
@@ -314,6 +258,16 @@ func (s *ProjectService) UpdateMemberHandler(w rest.ResponseWriter, r *rest.Requ
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// pending members get their jenkins roles on acceptance,
+	// the old role may still be held by group bindings
+	if oldMembership.Status == constants.StatusActive {
+		err = s.reconcileJenkinsUserRoles(projectId, username, []string{oldMembership.Role, request.Role})
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+			return
+		}
+	}
 
 	responseMembership := &models.ProjectMembership{}
 	err = s.Ds.Db.Select(models.ProjectMembershipColumns...).
@@ -337,12 +291,18 @@ func (s *ProjectService) DeleteMemberHandler(w rest.ResponseWriter, r *rest.Requ
 	username := r.PathParams["uid"]
 	operator := userutils.GetUserNameFromRequest(r)
 
-	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	err := s.checkProjectUserPermission(operator, projectId, PermissionManageMembers)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	oldMembership := &models.ProjectMembership{}
 	err = s.Ds.Db.Select(models.ProjectMembershipColumns...).
@@ -364,50 +324,26 @@ func (s *ProjectService) DeleteMemberHandler(w rest.ResponseWriter, r *rest.Requ
 		return
 	}
 	if oldMembership.Role == ProjectOwner {
-		count, err := s.Ds.Db.Select(models.ProjectIdColumn).
-			From(models.ProjectMembershipTableName).
-			Where(db.And(
-				db.Eq(models.ProjectIdColumn, projectId),
-				db.Eq(models.ProjectMembershipRoleColumn, ProjectOwner))).Count()
+		err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
 		if err != nil {
 			logger.Error("%+v", err)
-			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			rest.Error(w, err.Error(), http.StatusForbidden)
 			return
 		}
-		if count == 1 {
-			err = fmt.Errorf("project must has at least one admin")
+		err = s.checkProjectKeepsOwner(oldMembership)
+		if err != nil {
 			logger.Error("%+v", err)
 			rest.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 	}
 
-	oldProjectRole, err := s.Ds.Jenkins.GetProjectRole(GetProjectRoleName(projectId, oldMembership.Role))
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	err = oldProjectRole.UnAssignRole(username)
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-
-	oldPipelineRole, err := s.Ds.Jenkins.GetProjectRole(GetPipelineRoleName(projectId, oldMembership.Role))
+	err = s.deleteUserPipelineMemberships(projectId, username)
 	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-		return
-	}
-	err = oldPipelineRole.UnAssignRole(username)
-	if err != nil {
-		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
 	_, err = s.Ds.Db.DeleteFrom(models.ProjectMembershipTableName).
 		Where(db.And(
 			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
@@ -418,12 +354,30 @@ func (s *ProjectService) DeleteMemberHandler(w rest.ResponseWriter, r *rest.Requ
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// the old role may still be held by group bindings
+	if oldMembership.Status == constants.StatusActive {
+		err = s.reconcileJenkinsUserRoles(projectId, username, []string{oldMembership.Role})
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+			return
+		}
+	}
 	w.WriteJson(struct {
 		Username string `json:"username"`
 	}{Username: username})
 	return
 }
 
+// checkProjectMemberManager checks that the operator can grant or revoke the role,
+// only owners can manage the owner role.
+func (s *ProjectService) checkProjectMemberManager(operator, projectId, role string) error {
+	if role == ProjectOwner {
+		return s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner})
+	}
+	return s.checkProjectUserPermission(operator, projectId, PermissionManageMembers)
+}
+
 func (s *ProjectService) GetProjectDefaultRolesHandler(w rest.ResponseWriter, r *rest.Request) {
 	w.WriteJson(DefaultRoles)
 	return
@@ -0,0 +1,124 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"time"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/models"
+)
+
+const ProjectInvitationTTL = 7 * 24 * time.Hour
+
+func invitationExpired(membership *models.ProjectMembership, now time.Time) bool {
+	return membership.Status == constants.StatusPending &&
+		membership.ExpireTime != nil && membership.ExpireTime.Before(now)
+}
+
+func (s *ProjectService) getProjectMembership(projectId, username string) (*models.ProjectMembership, error) {
+	membership := &models.ProjectMembership{}
+	err := s.Ds.Db.Select(models.ProjectMembershipColumns...).
+		From(models.ProjectMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipUsernameColumn, username),
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId))).LoadOne(membership)
+	if err != nil {
+		return nil, err
+	}
+	return membership, nil
+}
+
+// assignJenkinsMemberRoles grants jenkins roles to a member who accepted the invitation.
+func (s *ProjectService) assignJenkinsMemberRoles(projectId, role, username string) error {
+	err := s.assignJenkinsAllUserRole(username)
+	if err != nil {
+		return err
+	}
+	err = s.reconcileJenkinsUserRoles(projectId, username, []string{role})
+	if err != nil {
+		return err
+	}
+	// pipeline memberships of imported projects are recorded before the invitation is accepted
+	return s.assignJenkinsPipelineMemberRoles(projectId, username)
+}
+
+// assignJenkinsAllUserRole grants the global role of all project members, it is created if not exists.
+func (s *ProjectService) assignJenkinsAllUserRole(username string) error {
+	globalRole, err := s.Ds.Jenkins.GetGlobalRole(constants.JenkinsAllUserRoleName)
+	if err != nil {
+		return err
+	}
+	if globalRole == nil {
+		globalRole, err = s.Ds.Jenkins.AddGlobalRole(constants.JenkinsAllUserRoleName, gojenkins.GlobalPermissionIds{
+			GlobalRead: true,
+		}, true)
+		if err != nil {
+			return err
+		}
+	}
+	return globalRole.AssignRole(username)
+}
+
+// checkProjectKeepsOwner checks that a project still has an owner
+// after the membership of the given user loses the owner role.
+func (s *ProjectService) checkProjectKeepsOwner(membership *models.ProjectMembership) error {
+	if membership.Role != ProjectOwner || membership.Status != constants.StatusActive {
+		return nil
+	}
+	count, err := s.Ds.Db.Select(models.ProjectIdColumn).
+		From(models.ProjectMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, membership.ProjectId),
+			db.Eq(models.ProjectMembershipRoleColumn, ProjectOwner),
+			db.Eq(constants.StatusColumn, constants.StatusActive))).Count()
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return fmt.Errorf("project must has at least one owner")
+	}
+	return nil
+}
+
+// transferProjectOwnership promotes the new owner and demotes the old owner to maintainer in one transaction.
+func (s *ProjectService) transferProjectOwnership(projectId, oldOwner, newOwner string) error {
+	tx, err := s.Ds.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.RollbackUnlessCommitted()
+	_, err = tx.Update(models.ProjectMembershipTableName).
+		Set(models.ProjectMembershipRoleColumn, ProjectOwner).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+			db.Eq(models.ProjectMembershipUsernameColumn, newOwner),
+			db.Eq(constants.StatusColumn, constants.StatusActive))).Exec()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Update(models.ProjectMembershipTableName).
+		Set(models.ProjectMembershipRoleColumn, ProjectMaintainer).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+			db.Eq(models.ProjectMembershipUsernameColumn, oldOwner),
+			db.Eq(models.ProjectMembershipRoleColumn, ProjectOwner))).Exec()
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type TransferOwnershipRequest struct {
+	Username string `json:"username"`
+}
+
+// GetInvitationsHandler returns the pending invitations of the operator.
+func (s *ProjectService) GetInvitationsHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	memberships := make([]*models.ProjectMembership, 0)
+	_, err := s.Ds.Db.Select(models.ProjectMembershipColumns...).
+		From(models.ProjectMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipUsernameColumn, operator),
+			db.Eq(constants.StatusColumn, constants.StatusPending))).
+		Load(&memberships)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	invitations := make([]*models.ProjectMembership, 0)
+	for _, membership := range memberships {
+		if !invitationExpired(membership, now) {
+			invitations = append(invitations, membership)
+		}
+	}
+	w.WriteJson(invitations)
+	return
+}
+
+// getPendingInvitation writes the error response and returns false if the operator has no valid invitation.
+func (s *ProjectService) getPendingInvitation(w rest.ResponseWriter, projectId, operator string) (*models.ProjectMembership, bool) {
+	membership, err := s.getProjectMembership(projectId, operator)
+	if err != nil && err != db.ErrNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if err == db.ErrNotFound || membership.Status != constants.StatusPending {
+		err = fmt.Errorf("user [%s] has no invitation of project [%s]", operator, projectId)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if invitationExpired(membership, time.Now()) {
+		err = fmt.Errorf("invitation of project [%s] has expired", projectId)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusGone)
+		return nil, false
+	}
+	return membership, true
+}
+
+func (s *ProjectService) AcceptInvitationHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	membership, ok := s.getPendingInvitation(w, projectId, operator)
+	if !ok {
+		return
+	}
+	err := s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = s.Ds.Db.Update(models.ProjectMembershipTableName).
+		Set(constants.StatusColumn, constants.StatusActive).
+		Set(models.ProjectMembershipExpireTimeColumn, nil).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+			db.Eq(models.ProjectMembershipUsernameColumn, operator),
+		)).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.assignJenkinsMemberRoles(projectId, membership.Role, operator)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	membership.Status = constants.StatusActive
+	membership.ExpireTime = nil
+	w.WriteJson(membership)
+	return
+}
+
+func (s *ProjectService) DeclineInvitationHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	_, ok := s.getPendingInvitation(w, projectId, operator)
+	if !ok {
+		return
+	}
+	_, err := s.Ds.Db.DeleteFrom(models.ProjectMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+			db.Eq(models.ProjectMembershipUsernameColumn, operator),
+			db.Eq(constants.StatusColumn, constants.StatusPending),
+		)).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(struct {
+		ProjectId string `json:"project_id"`
+	}{ProjectId: projectId})
+	return
+}
+
+// TransferOwnershipHandler makes an active member the owner of the project
+// and demotes the operator to maintainer.
+func (s *ProjectService) TransferOwnershipHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	request := &TransferOwnershipRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(request.Username) {
+		err := fmt.Errorf("error need username")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	operatorMembership, err := s.getProjectMembership(projectId, operator)
+	if err != nil && err != db.ErrNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err == db.ErrNotFound || operatorMembership.Role != ProjectOwner ||
+		operatorMembership.Status != constants.StatusActive {
+		err = fmt.Errorf("user [%s] is not the owner of project [%s]", operator, projectId)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	membership, err := s.getProjectMembership(projectId, request.Username)
+	if err != nil && err != db.ErrNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err == db.ErrNotFound || membership.Status != constants.StatusActive {
+		err = fmt.Errorf("user [%s] is not an active member of project [%s]", request.Username, projectId)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if membership.Role == ProjectOwner {
+		err = fmt.Errorf("user [%s] is already an owner of project [%s]", request.Username, projectId)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.transferProjectOwnership(projectId, operator, request.Username)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.reconcileJenkinsUserRoles(projectId, request.Username, []string{membership.Role, ProjectOwner})
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	err = s.reconcileJenkinsUserRoles(projectId, operator, []string{ProjectOwner, ProjectMaintainer})
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	membership.Role = ProjectOwner
+	operatorMembership.Role = ProjectMaintainer
+	w.WriteJson([]*models.ProjectMembership{membership, operatorMembership})
+	return
+}
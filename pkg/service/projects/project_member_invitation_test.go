@@ -0,0 +1,37 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"testing"
+	"time"
+
+	"kubesphere.io/devops/pkg/models"
+)
+
+func TestInvitationExpired(t *testing.T) {
+	now := time.Now()
+	for _, item := range []struct {
+		membership *models.ProjectMembership
+		expected   bool
+	}{
+		{models.NewProjectInvitation("alice", "project-abc", ProjectDeveloper, "admin", now.Add(time.Hour)), false},
+		{models.NewProjectInvitation("alice", "project-abc", ProjectDeveloper, "admin", now.Add(-time.Hour)), true},
+		{models.NewProjectMemberShip("alice", "project-abc", ProjectDeveloper, "admin"), false},
+	} {
+		if expired := invitationExpired(item.membership, now); expired != item.expected {
+			t.Fatalf("membership %+v expired should be %t", item.membership, item.expected)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+)
+
+const (
+	ActionViewPipelines      = "view_pipelines"
+	ActionRunPipelines       = "run_pipelines"
+	ActionConfigurePipelines = "configure_pipelines"
+	ActionDeletePipelines    = "delete_pipelines"
+	ActionManageCredentials  = "manage_credentials"
+	ActionManageMembers      = "manage_members"
+)
+
+// projectAction is an operation of the api, it is allowed by either the roles
+// passed to checkProjectUserInRole or the permission passed to checkProjectUserPermission.
+type projectAction struct {
+	Name       string
+	Roles      []string
+	Permission string
+}
+
+// projectActions must be kept in sync with the checks of the handlers.
+var projectActions = []projectAction{
+	{Name: ActionViewPipelines, Roles: AllRoleSlice},
+	{Name: ActionRunPipelines, Permission: PermissionRunPipelines},
+	{Name: ActionConfigurePipelines, Roles: []string{ProjectOwner, ProjectMaintainer}},
+	{Name: ActionDeletePipelines, Roles: []string{ProjectOwner, ProjectMaintainer}},
+	{Name: ActionManageCredentials, Permission: PermissionManageCredentials},
+	{Name: ActionManageMembers, Permission: PermissionManageMembers},
+}
+
+type ProjectPermissions struct {
+	Username      string   `json:"username"`
+	ProjectId     string   `json:"project_id"`
+	Role          string   `json:"role,omitempty"`
+	PlatformAdmin bool     `json:"platform_admin"`
+	Auditor       bool     `json:"auditor"`
+	Public        bool     `json:"public"`
+	Archived      bool     `json:"archived"`
+	Actions       []string `json:"actions"`
+}
+
+// allowedProjectActions applies the rules of checkProjectUserInRole and checkProjectUserPermission
+// to every action, role is empty if the user is not a member of the project.
+// Auditors and the users of public projects have read access.
+func allowedProjectActions(role string, permissions []string, platformAdmin, readAll bool) []string {
+	actions := make([]string, 0)
+	for _, action := range projectActions {
+		allowed := platformAdmin
+		if action.Permission != "" {
+			allowed = allowed || reflectutils.In(action.Permission, permissions)
+		} else {
+			allowed = allowed || (readAll && reflectutils.In(ProjectReporter, action.Roles)) ||
+				(role != "" && roleInRoles(role, action.Roles))
+		}
+		if allowed {
+			actions = append(actions, action.Name)
+		}
+	}
+	return actions
+}
+
+// readOnlyProjectActions keeps the actions allowed to reporters, archived projects reject the others.
+func readOnlyProjectActions(actions []string) []string {
+	readOnlyActions := make([]string, 0)
+	for _, action := range projectActions {
+		if reflectutils.In(action.Name, actions) && reflectutils.In(ProjectReporter, action.Roles) {
+			readOnlyActions = append(readOnlyActions, action.Name)
+		}
+	}
+	return readOnlyActions
+}
+
+func (s *ProjectService) getProjectUserPermissions(username, projectId string) (*ProjectPermissions, error) {
+	projectPermissions := &ProjectPermissions{
+		Username:      username,
+		ProjectId:     projectId,
+		PlatformAdmin: s.isPlatformAdmin(username),
+		Auditor:       s.hasGlobalRole(username, GlobalRoleAuditor),
+		Public:        s.isPublicProject(projectId),
+	}
+	role, err := s.getProjectUserRole(username, projectId)
+	if err != nil && err != db.ErrNotFound {
+		return nil, err
+	}
+	permissions := make([]string, 0)
+	if err == nil {
+		projectPermissions.Role = role
+		permissions, err = s.getProjectRolePermissions(projectId, role)
+		if err != nil {
+			return nil, err
+		}
+	}
+	projectPermissions.Actions = allowedProjectActions(projectPermissions.Role, permissions,
+		projectPermissions.PlatformAdmin, projectPermissions.Auditor || projectPermissions.Public)
+	project, err := s.getProject(projectId)
+	if err != nil && err != db.ErrNotFound {
+		return nil, err
+	}
+	if err == nil && project.Status == constants.StatusArchived {
+		projectPermissions.Archived = true
+		projectPermissions.Actions = readOnlyProjectActions(projectPermissions.Actions)
+	}
+	return projectPermissions, nil
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+// GetMyPermissionsHandler returns the effective role and the allowed actions of the caller in a project.
+func (s *ProjectService) GetMyPermissionsHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	permissions, err := s.getProjectUserPermissions(operator, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(permissions)
+	return
+}
+
+// GetUserPermissionsHandler evaluates the permissions of any user, it is only allowed for platform admins.
+func (s *ProjectService) GetUserPermissionsHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	username := r.PathParams["uid"]
+	operator := userutils.GetUserNameFromRequest(r)
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not view permissions of other users", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	permissions, err := s.getProjectUserPermissions(username, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(permissions)
+	return
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllowedProjectActions(t *testing.T) {
+	allActions := []string{ActionViewPipelines, ActionRunPipelines, ActionConfigurePipelines,
+		ActionDeletePipelines, ActionManageCredentials, ActionManageMembers}
+	for _, item := range []struct {
+		role          string
+		permissions   []string
+		platformAdmin bool
+		readAll       bool
+		expected      []string
+	}{
+		{ProjectOwner, DefaultRolePermissions[ProjectOwner], false, false, allActions},
+		{ProjectMaintainer, DefaultRolePermissions[ProjectMaintainer], false, false, []string{ActionViewPipelines,
+			ActionRunPipelines, ActionConfigurePipelines, ActionDeletePipelines, ActionManageCredentials}},
+		{ProjectDeveloper, DefaultRolePermissions[ProjectDeveloper], false, false,
+			[]string{ActionViewPipelines, ActionRunPipelines}},
+		{ProjectReporter, DefaultRolePermissions[ProjectReporter], false, false, []string{ActionViewPipelines}},
+		{"release-manager", []string{PermissionRunPipelines, PermissionManageMembers}, false, false,
+			[]string{ActionViewPipelines, ActionRunPipelines, ActionManageMembers}},
+		{"", nil, false, false, []string{}},
+		{"", nil, false, true, []string{ActionViewPipelines}},
+		{"", nil, true, false, allActions},
+	} {
+		actions := allowedProjectActions(item.role, item.permissions, item.platformAdmin, item.readAll)
+		if !reflect.DeepEqual(actions, item.expected) {
+			t.Fatalf("actions of role [%s] should be %v, got %v", item.role, item.expected, actions)
+		}
+	}
+}
+
+func TestReadOnlyProjectActions(t *testing.T) {
+	actions := readOnlyProjectActions([]string{ActionViewPipelines, ActionRunPipelines, ActionManageMembers})
+	if !reflect.DeepEqual(actions, []string{ActionViewPipelines}) {
+		t.Fatalf("read-only actions should be %v, got %v", []string{ActionViewPipelines}, actions)
+	}
+	if actions := readOnlyProjectActions([]string{ActionManageCredentials}); len(actions) != 0 {
+		t.Fatalf("read-only actions should be empty, got %v", actions)
+	}
+}
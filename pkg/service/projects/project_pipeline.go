@@ -22,6 +22,8 @@ import (
 
 	"github.com/beevik/etree"
 	"github.com/mitchellh/mapstructure"
+
+	"kubesphere.io/devops/pkg/gojenkins"
 )
 
 const (
@@ -141,6 +143,73 @@ type RemoteTrigger struct {
 	Token string `json:"token"`
 }
 
+// getJenkinsJobRequest converts the config of a job to the request which creates the same job.
+func getJenkinsJobRequest(job *gojenkins.Job, name string) (*JenkinsJobRequest, error) {
+	jobRequest := &JenkinsJobRequest{}
+	var define interface{}
+	switch job.Raw.Class {
+	case "org.jenkinsci.plugins.workflow.job.WorkflowJob":
+		config, err := job.GetConfig()
+		if err != nil {
+			return nil, err
+		}
+		pipeline, err := parsePipelineConfigXml(config)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Name = name
+		jobRequest.Type = JenkinsJobPipeline
+		define = pipeline
+	case "org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject":
+		config, err := job.GetConfig()
+		if err != nil {
+			return nil, err
+		}
+		pipeline, err := parseMultiBranchPipelineConfigXml(config)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Name = name
+		jobRequest.Type = JenkinsJobMultiBranchPipeline
+		define = pipeline
+	default:
+		return nil, fmt.Errorf("error unsupport job type")
+	}
+	jsonByte, err := json.Marshal(define)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(jsonByte, &jobRequest.Define)
+	if err != nil {
+		return nil, err
+	}
+	return jobRequest, nil
+}
+
+// jenkinsJobConfig returns the name and config of the job created by a request.
+func jenkinsJobConfig(projectId string, request *JenkinsJobRequest) (string, string, error) {
+	switch request.Type {
+	case JenkinsJobPipeline:
+		pipeline := &Pipeline{}
+		err := mapstructure.Decode(request.Define, pipeline)
+		if err != nil {
+			return "", "", err
+		}
+		config, err := createPipelineConfigXml(pipeline)
+		return pipeline.Name, config, err
+	case JenkinsJobMultiBranchPipeline:
+		pipeline := &MultiBranchPipeline{}
+		err := mapstructure.Decode(request.Define, pipeline)
+		if err != nil {
+			return "", "", err
+		}
+		config, err := createMultiBranchPipelineConfigXml(projectId, pipeline)
+		return pipeline.Name, config, err
+	default:
+		return "", "", fmt.Errorf("error unsupport job type")
+	}
+}
+
 func createPipelineConfigXml(pipeline *Pipeline) (string, error) {
 	doc := etree.NewDocument()
 	xmlString := `<?xml version='1.0' encoding='UTF-8'?>
@@ -14,7 +14,6 @@ limitations under the License.
 package projects
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -44,6 +43,22 @@ func (s *ProjectService) CreatePipelineHandler(w rest.ResponseWriter, r *rest.Re
 		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectQuota(projectId, QuotaPipelines)
+	if err != nil {
+		writeProjectQuotaError(w, err)
+		return
+	}
+	err = s.checkRetainedBuildsQuota(projectId, request)
+	if err != nil {
+		writeProjectQuotaError(w, err)
+		return
+	}
 
 	switch request.Type {
 	case JenkinsJobPipeline:
@@ -137,18 +152,30 @@ func (s *ProjectService) DeletePipelineHandler(w rest.ResponseWriter, r *rest.Re
 	projectId := r.PathParams["id"]
 	operator := userutils.GetUserNameFromRequest(r)
 	pipelineId := r.PathParams["pid"]
-	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner, ProjectMaintainer})
+	err := s.checkPipelineUserInRole(operator, projectId, pipelineId, []string{ProjectOwner, ProjectMaintainer})
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	_, err = s.Ds.Jenkins.DeleteJob(pipelineId, projectId)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
 		return
 	}
+	err = s.deletePipelineMemberships(projectId, pipelineId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	w.WriteJson(struct {
 		Name string `json:"name"`
 	}{Name: pipelineId})
@@ -167,12 +194,23 @@ func (s *ProjectService) UpdatePipelineHandler(w rest.ResponseWriter, r *rest.Re
 		return
 	}
 
-	err = s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner, ProjectMaintainer})
+	err = s.checkPipelineUserInRole(operator, projectId, pipelineId, []string{ProjectOwner, ProjectMaintainer})
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkRetainedBuildsQuota(projectId, request)
+	if err != nil {
+		writeProjectQuotaError(w, err)
+		return
+	}
 
 	switch request.Type {
 	case JenkinsJobPipeline:
@@ -248,7 +286,7 @@ func (s *ProjectService) GetPipelineHandler(w rest.ResponseWriter, r *rest.Reque
 	projectId := r.PathParams["id"]
 	pipelineId := r.PathParams["pid"]
 	operator := userutils.GetUserNameFromRequest(r)
-	err := s.checkProjectUserInRole(operator, projectId, []string{ProjectOwner, ProjectMaintainer})
+	err := s.checkPipelineUserInRole(operator, projectId, pipelineId, []string{ProjectOwner, ProjectMaintainer})
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
@@ -260,84 +298,21 @@ func (s *ProjectService) GetPipelineHandler(w rest.ResponseWriter, r *rest.Reque
 		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
 		return
 	}
-	switch job.Raw.Class {
-	case "org.jenkinsci.plugins.workflow.job.WorkflowJob":
-		config, err := job.GetConfig()
-		if err != nil {
-			logger.Error("%+v", err)
-			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-			return
-		}
-		pipeline, err := parsePipelineConfigXml(config)
-		if err != nil {
-			logger.Error("%+v", err)
-			rest.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		pipeline.Name = pipelineId
-		jobRequest := JenkinsJobRequest{
-			Type: "pipeline",
-		}
-		jsonByte, err := json.Marshal(pipeline)
-		if err != nil {
-			logger.Error("%+v", err)
-			rest.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		err = json.Unmarshal(jsonByte, &jobRequest.Define)
-		if err != nil {
-			logger.Error("%+v", err)
-			rest.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		w.WriteJson(jobRequest)
-		return
-		return
-	case "org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject":
-		config, err := job.GetConfig()
-		if err != nil {
-			logger.Error("%+v", err)
-			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
-			return
-		}
-		pipeline, err := parseMultiBranchPipelineConfigXml(config)
-		if err != nil {
-			logger.Error("%+v", err)
-			rest.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		pipeline.Name = pipelineId
-		jobRequest := JenkinsJobRequest{
-			Type: "multi-branch-pipeline",
-		}
-		jsonByte, err := json.Marshal(pipeline)
-		if err != nil {
-			logger.Error("%+v", err)
-			rest.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		err = json.Unmarshal(jsonByte, &jobRequest.Define)
-		if err != nil {
-			logger.Error("%+v", err)
-			rest.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		w.WriteJson(jobRequest)
-		return
-
-	default:
-		err := fmt.Errorf("error unsupport job type")
+	jobRequest, err := getJenkinsJobRequest(job, pipelineId)
+	if err != nil {
 		logger.Error("%+v", err)
-		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
 		return
 	}
+	w.WriteJson(jobRequest)
+	return
 }
 
 func (s *ProjectService) GetPipelineScmHandler(w rest.ResponseWriter, r *rest.Request) {
 	projectId := r.PathParams["id"]
 	pipelineId := r.PathParams["pid"]
 	operator := userutils.GetUserNameFromRequest(r)
-	err := s.checkProjectUserInRole(operator, projectId, AllRoleSlice)
+	err := s.checkPipelineUserInRole(operator, projectId, pipelineId, AllRoleSlice)
 	if err != nil {
 		logger.Error("%+v", err)
 		rest.Error(w, err.Error(), http.StatusForbidden)
@@ -374,3 +349,59 @@ func (s *ProjectService) GetPipelineScmHandler(w rest.ResponseWriter, r *rest.Re
 	}
 
 }
+
+type RunPipelineRequest struct {
+	Branch     string            `json:"branch"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+// RunPipelineHandler triggers a pipeline, the branch is required by multi-branch pipelines.
+func (s *ProjectService) RunPipelineHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	pipelineId := r.PathParams["pid"]
+	operator := userutils.GetUserNameFromRequest(r)
+	request := &RunPipelineRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil && err != rest.ErrJsonPayloadEmpty {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkPipelineUserPermission(operator, projectId, pipelineId, PermissionRunPipelines)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectQuota(projectId, QuotaConcurrentRuns)
+	if err != nil {
+		writeProjectQuotaError(w, err)
+		return
+	}
+	job, err := s.Ds.Jenkins.GetJob(pipelineId, projectId)
+	if request.Branch != "" {
+		job, err = s.Ds.Jenkins.GetJob(request.Branch, projectId, pipelineId)
+	}
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	queueId, err := job.InvokeSimple(request.Parameters)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(struct {
+		Name    string `json:"name"`
+		QueueId int64  `json:"queue_id"`
+	}{Name: pipelineId, QueueId: queueId})
+	return
+}
@@ -0,0 +1,346 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/gojenkins"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+)
+
+func GetPipelineMemberRoleName(projectId, pipelineId, role string) string {
+	return fmt.Sprintf("%s-%s-%s-pipeline-binding", projectId, pipelineId, role)
+}
+
+func GetPipelineMemberRolePattern(projectId, pipelineId string) string {
+	return fmt.Sprintf("^%s/%s(/.*)?$", projectId, regexp.QuoteMeta(pipelineId))
+}
+
+// GetRestrictedPipelineRolePattern matches the pipelines of a project except the restricted ones.
+// The pattern is evaluated by jenkins, which supports negative lookahead.
+func GetRestrictedPipelineRolePattern(projectId string, restrictedPipelines []string) string {
+	if len(restrictedPipelines) == 0 {
+		return GetPipelineRolePattern(projectId)
+	}
+	quoted := make([]string, 0, len(restrictedPipelines))
+	for _, pipelineId := range restrictedPipelines {
+		quoted = append(quoted, regexp.QuoteMeta(pipelineId))
+	}
+	return fmt.Sprintf("^%s/(?!(%s)(/.*)?$).*", projectId, strings.Join(quoted, "|"))
+}
+
+func (s *ProjectService) getPipelineMemberships(projectId string) ([]*models.PipelineMembership, error) {
+	memberships := make([]*models.PipelineMembership, 0)
+	_, err := s.Ds.Db.Select(models.PipelineMembershipColumns...).
+		From(models.PipelineMembershipTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		Load(&memberships)
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// getRestrictedPipelines returns the pipelines with members, they can only be accessed by
+// their members and the project owners.
+func (s *ProjectService) getRestrictedPipelines(projectId string) ([]string, error) {
+	memberships, err := s.getPipelineMemberships(projectId)
+	if err != nil {
+		return nil, err
+	}
+	pipelines := make([]string, 0)
+	for _, membership := range memberships {
+		if !reflectutils.In(membership.PipelineId, pipelines) {
+			pipelines = append(pipelines, membership.PipelineId)
+		}
+	}
+	sort.Strings(pipelines)
+	return pipelines, nil
+}
+
+func (s *ProjectService) getPipelineMembership(projectId, pipelineId, username string) (*models.PipelineMembership, error) {
+	membership := &models.PipelineMembership{}
+	err := s.Ds.Db.Select(models.PipelineMembershipColumns...).
+		From(models.PipelineMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.PipelineMembershipPipelineIdColumn, pipelineId),
+			db.Eq(models.ProjectMembershipUsernameColumn, username))).LoadOne(membership)
+	if err != nil {
+		return nil, err
+	}
+	return membership, nil
+}
+
+// getPipelineUserRole returns the role of a user in a pipeline, members of restricted pipelines
+// use their pipeline role and the other project members except owners have no access.
+func (s *ProjectService) getPipelineUserRole(username, projectId, pipelineId string) (string, error) {
+	role, err := s.getProjectUserRole(username, projectId)
+	if err != nil {
+		return "", err
+	}
+	if role == ProjectOwner {
+		return role, nil
+	}
+	restrictedPipelines, err := s.getRestrictedPipelines(projectId)
+	if err != nil {
+		return "", err
+	}
+	if !reflectutils.In(pipelineId, restrictedPipelines) {
+		return role, nil
+	}
+	member, ceiling := username, ""
+	if IsApiTokenUsername(username) {
+		token, err := s.getActiveApiToken(strings.TrimPrefix(username, ApiTokenUsernamePrefix))
+		if err != nil {
+			return "", err
+		}
+		member, ceiling = token.Owner, token.Role
+	}
+	membership, err := s.getPipelineMembership(projectId, pipelineId, member)
+	if err == db.ErrNotFound {
+		return "", fmt.Errorf("user [%s] is not a member of restricted pipeline [%s/%s]", username, projectId, pipelineId)
+	}
+	if err != nil {
+		return "", err
+	}
+	return lowerProjectRole(membership.Role, ceiling), nil
+}
+
+func (s *ProjectService) checkPipelineUserInRole(username, projectId, pipelineId string, roles []string) error {
+	if s.isPlatformAdmin(username) {
+		return nil
+	}
+	if reflectutils.In(ProjectReporter, roles) && s.hasGlobalRole(username, GlobalRoleAuditor) {
+		return nil
+	}
+	role, err := s.getPipelineUserRole(username, projectId, pipelineId)
+	if err == nil && roleInRoles(role, roles) {
+		return nil
+	}
+	if reflectutils.In(ProjectReporter, roles) && s.isPublicPipeline(projectId, pipelineId) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("user [%s] in pipeline [%s/%s] role is not in %s", username, projectId, pipelineId, roles)
+}
+
+func (s *ProjectService) checkPipelineUserPermission(username, projectId, pipelineId, permission string) error {
+	if s.isPlatformAdmin(username) {
+		return nil
+	}
+	role, err := s.getPipelineUserRole(username, projectId, pipelineId)
+	if err != nil {
+		return err
+	}
+	permissions, err := s.getProjectRolePermissions(projectId, role)
+	if err != nil {
+		return err
+	}
+	if !reflectutils.In(permission, permissions) {
+		return fmt.Errorf("user [%s] in pipeline [%s/%s] does not have permission [%s]",
+			username, projectId, pipelineId, permission)
+	}
+	return nil
+}
+
+// getPipelineRolePermissionIds returns the jenkins pipeline permissions of a built-in or custom role.
+func (s *ProjectService) getPipelineRolePermissionIds(projectId, role string) (gojenkins.ProjectPermissionIds, error) {
+	if ids, ok := JenkinsPipelinePermissionMap[role]; ok {
+		return ids, nil
+	}
+	customRole, err := s.getCustomRole(projectId, role)
+	if err != nil {
+		return gojenkins.ProjectPermissionIds{}, err
+	}
+	_, ids := customRoleJenkinsPermissionIds(customRole.Permissions, customRole.JenkinsPermissions)
+	return ids, nil
+}
+
+// assignJenkinsPipelineMemberRoles grants the jenkins roles of the pipeline memberships of a user,
+// the roles are created if not exists.
+func (s *ProjectService) assignJenkinsPipelineMemberRoles(projectId, username string) error {
+	pipelineMemberships, err := s.getPipelineMemberships(projectId)
+	if err != nil {
+		return err
+	}
+	for _, pipelineMembership := range pipelineMemberships {
+		if pipelineMembership.Username != username {
+			continue
+		}
+		roleName := GetPipelineMemberRoleName(projectId, pipelineMembership.PipelineId, pipelineMembership.Role)
+		pipelineRole, err := s.Ds.Jenkins.GetProjectRole(roleName)
+		if err != nil {
+			return err
+		}
+		if pipelineRole == nil {
+			ids, err := s.getPipelineRolePermissionIds(projectId, pipelineMembership.Role)
+			if err != nil {
+				return err
+			}
+			pipelineRole, err = s.Ds.Jenkins.AddProjectRole(roleName,
+				GetPipelineMemberRolePattern(projectId, pipelineMembership.PipelineId), ids, true)
+			if err != nil {
+				return err
+			}
+		}
+		err = pipelineRole.AssignRole(username)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overwriteJenkinsProjectRole creates or overwrites a jenkins role of a project role.
+// Overwriting a role drops its assigned users, so the members of the role are assigned again.
+func (s *ProjectService) overwriteJenkinsProjectRole(projectId, role, roleName, pattern string,
+	ids gojenkins.ProjectPermissionIds) error {
+	jenkinsRole, err := s.Ds.Jenkins.AddProjectRole(roleName, pattern, ids, true)
+	if err != nil {
+		return err
+	}
+	usernames, err := s.getProjectRoleUsernames(projectId, role)
+	if err != nil {
+		return err
+	}
+	for _, username := range usernames {
+		err = jenkinsRole.AssignRole(username)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncJenkinsPipelineRoles excludes the restricted pipelines from the pipeline roles of a project,
+// except from the owner role.
+func (s *ProjectService) syncJenkinsPipelineRoles(projectId string) error {
+	restrictedPipelines, err := s.getRestrictedPipelines(projectId)
+	if err != nil {
+		return err
+	}
+	pattern := GetRestrictedPipelineRolePattern(projectId, restrictedPipelines)
+	roles := make([]string, 0)
+	for _, role := range AllRoleSlice {
+		if role != ProjectOwner {
+			roles = append(roles, role)
+		}
+	}
+	customRoles := make([]*models.ProjectRole, 0)
+	_, err = s.Ds.Db.Select(models.ProjectRoleNameColumn).
+		From(models.ProjectRoleTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		Load(&customRoles)
+	if err != nil {
+		return err
+	}
+	for _, customRole := range customRoles {
+		roles = append(roles, customRole.Name)
+	}
+	for _, role := range roles {
+		ids, err := s.getPipelineRolePermissionIds(projectId, role)
+		if err != nil {
+			return err
+		}
+		err = s.overwriteJenkinsProjectRole(projectId, role, GetPipelineRoleName(projectId, role), pattern, ids)
+		if err != nil {
+			return err
+		}
+	}
+	if s.isPublicProject(projectId) {
+		return s.SyncJenkinsPublicRole()
+	}
+	return nil
+}
+
+// deletePipelineMemberships removes the members of a pipeline and their jenkins roles.
+func (s *ProjectService) deletePipelineMemberships(projectId, pipelineId string) error {
+	memberships, err := s.getPipelineMemberships(projectId)
+	if err != nil {
+		return err
+	}
+	roleNames := make([]string, 0)
+	for _, membership := range memberships {
+		roleName := GetPipelineMemberRoleName(projectId, pipelineId, membership.Role)
+		if membership.PipelineId == pipelineId && !reflectutils.In(roleName, roleNames) {
+			roleNames = append(roleNames, roleName)
+		}
+	}
+	if len(roleNames) == 0 {
+		return nil
+	}
+	err = s.Ds.Jenkins.DeleteProjectRoles(roleNames...)
+	if err != nil {
+		return err
+	}
+	_, err = s.Ds.Db.DeleteFrom(models.PipelineMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.PipelineMembershipPipelineIdColumn, pipelineId))).Exec()
+	if err != nil {
+		return err
+	}
+	return s.syncJenkinsPipelineRoles(projectId)
+}
+
+// deleteUserPipelineMemberships removes a user from the pipelines of a project.
+func (s *ProjectService) deleteUserPipelineMemberships(projectId, username string) error {
+	memberships, err := s.getPipelineMemberships(projectId)
+	if err != nil {
+		return err
+	}
+	restricted := make(map[string]int)
+	for _, membership := range memberships {
+		restricted[membership.PipelineId]++
+	}
+	resync := false
+	for _, membership := range memberships {
+		if membership.Username != username {
+			continue
+		}
+		pipelineRole, err := s.Ds.Jenkins.GetProjectRole(
+			GetPipelineMemberRoleName(projectId, membership.PipelineId, membership.Role))
+		if err != nil {
+			return err
+		}
+		if pipelineRole != nil {
+			err = pipelineRole.UnAssignRole(username)
+			if err != nil {
+				return err
+			}
+		}
+		restricted[membership.PipelineId]--
+		resync = resync || restricted[membership.PipelineId] == 0
+	}
+	_, err = s.Ds.Db.DeleteFrom(models.PipelineMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectMembershipUsernameColumn, username))).Exec()
+	if err != nil {
+		return err
+	}
+	if resync {
+		return s.syncJenkinsPipelineRoles(projectId)
+	}
+	return nil
+}
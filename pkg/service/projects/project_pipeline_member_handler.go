@@ -0,0 +1,281 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type AddPipelineMemberRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func (s *ProjectService) GetPipelineMembersHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	pipelineId := r.PathParams["pid"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkPipelineUserInRole(operator, projectId, pipelineId, AllRoleSlice)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	memberships := make([]*models.PipelineMembership, 0)
+	_, err = s.Ds.Db.Select(models.PipelineMembershipColumns...).
+		From(models.PipelineMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.PipelineMembershipPipelineIdColumn, pipelineId))).
+		Load(&memberships)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(struct {
+		Restricted bool                         `json:"restricted"`
+		Members    []*models.PipelineMembership `json:"members"`
+	}{Restricted: len(memberships) > 0, Members: memberships})
+	return
+}
+
+// AddPipelineMemberHandler binds a project member to a role of a pipeline,
+// the first member restricts the pipeline to its members and the project owners.
+func (s *ProjectService) AddPipelineMemberHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	pipelineId := r.PathParams["pid"]
+	operator := userutils.GetUserNameFromRequest(r)
+	request := &AddPipelineMemberRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if govalidator.IsNull(request.Username) {
+		err := fmt.Errorf("error need username")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectMemberManager(operator, projectId, request.Role)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectRoleExists(projectId, request.Role)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = s.getProjectUserRole(request.Username, projectId)
+	if err != nil {
+		if err == db.ErrNotFound {
+			err = fmt.Errorf("user [%s] is not a member of project [%s]", request.Username, projectId)
+		}
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = s.Ds.Jenkins.GetJob(pipelineId, projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	_, err = s.getPipelineMembership(projectId, pipelineId, request.Username)
+	if err != nil && err != db.ErrNotFound {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != db.ErrNotFound {
+		err = fmt.Errorf("user [%s] have been added to pipeline", request.Username)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	restrictedPipelines, err := s.getRestrictedPipelines(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	roleName := GetPipelineMemberRoleName(projectId, pipelineId, request.Role)
+	pipelineRole, err := s.Ds.Jenkins.GetProjectRole(roleName)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	if pipelineRole == nil {
+		ids, err := s.getPipelineRolePermissionIds(projectId, request.Role)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		pipelineRole, err = s.Ds.Jenkins.AddProjectRole(roleName,
+			GetPipelineMemberRolePattern(projectId, pipelineId), ids, true)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+			return
+		}
+	}
+	err = pipelineRole.AssignRole(request.Username)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+
+	membership := models.NewPipelineMembership(projectId, pipelineId, request.Username, request.Role, operator)
+	_, err = s.Ds.Db.InsertInto(models.PipelineMembershipTableName).
+		Columns(models.PipelineMembershipColumns...).
+		Record(membership).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !reflectutils.In(pipelineId, restrictedPipelines) {
+		err = s.syncJenkinsPipelineRoles(projectId)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+			return
+		}
+	}
+	w.WriteJson(membership)
+	return
+}
+
+// DeletePipelineMemberHandler removes a member of a pipeline,
+// the pipeline is accessible by all project members again when its last member is removed.
+func (s *ProjectService) DeletePipelineMemberHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	pipelineId := r.PathParams["pid"]
+	username := r.PathParams["uid"]
+	operator := userutils.GetUserNameFromRequest(r)
+	err := s.checkProjectUserPermission(operator, projectId, PermissionManageMembers)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	membership, err := s.getPipelineMembership(projectId, pipelineId, username)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.checkProjectMemberManager(operator, projectId, membership.Role)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	roleName := GetPipelineMemberRoleName(projectId, pipelineId, membership.Role)
+	pipelineRole, err := s.Ds.Jenkins.GetProjectRole(roleName)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	if pipelineRole != nil {
+		err = pipelineRole.UnAssignRole(username)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+			return
+		}
+	}
+	_, err = s.Ds.Db.DeleteFrom(models.PipelineMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.PipelineMembershipPipelineIdColumn, pipelineId),
+			db.Eq(models.ProjectMembershipUsernameColumn, username))).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	memberships, err := s.getPipelineMemberships(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	roleInUse, restricted := false, false
+	for _, other := range memberships {
+		if other.PipelineId == pipelineId {
+			restricted = true
+			roleInUse = roleInUse || other.Role == membership.Role
+		}
+	}
+	if !roleInUse && pipelineRole != nil {
+		err = s.Ds.Jenkins.DeleteProjectRoles(roleName)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+			return
+		}
+	}
+	if !restricted {
+		err = s.syncJenkinsPipelineRoles(projectId)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+			return
+		}
+	}
+	w.WriteJson(struct {
+		Username string `json:"username"`
+	}{Username: username})
+	return
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGetPipelineMemberRolePattern(t *testing.T) {
+	pattern := regexp.MustCompile(GetPipelineMemberRolePattern("project-abc", "deploy.prod"))
+	for item, expected := range map[string]bool{
+		"project-abc/deploy.prod":             true,
+		"project-abc/deploy.prod/master":      true,
+		"project-abc/deploy.production":       false,
+		"project-abc/deploy-prod":             false,
+		"project-abc/deploy.prod-hotfix/main": false,
+	} {
+		if pattern.MatchString(item) != expected {
+			t.Fatalf("pattern %s matching %s should be %t", pattern.String(), item, expected)
+		}
+	}
+}
+
+func TestGetRestrictedPipelineRolePattern(t *testing.T) {
+	if pattern := GetRestrictedPipelineRolePattern("project-abc", nil); pattern != GetPipelineRolePattern("project-abc") {
+		t.Fatalf("pattern without restricted pipelines should be %s, got %s", GetPipelineRolePattern("project-abc"), pattern)
+	}
+	pattern := GetRestrictedPipelineRolePattern("project-abc", []string{"deploy", "release.1"})
+	expected := `^project-abc/(?!(deploy|release\.1)(/.*)?$).*`
+	if pattern != expected {
+		t.Fatalf("pattern should be %s, got %s", expected, pattern)
+	}
+}
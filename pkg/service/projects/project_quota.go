@@ -0,0 +1,313 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+)
+
+const (
+	QuotaPipelines      = "pipelines"
+	QuotaCredentials    = "credentials"
+	QuotaMembers        = "members"
+	QuotaConcurrentRuns = "concurrent_runs"
+	QuotaRetainedBuilds = "retained_builds"
+)
+
+var AllQuotaSlice = []string{QuotaPipelines, QuotaCredentials, QuotaMembers, QuotaConcurrentRuns, QuotaRetainedBuilds}
+
+// ProjectQuotaError is returned when a change would exceed a quota of a project.
+type ProjectQuotaError struct {
+	ProjectId string `json:"project_id"`
+	Quota     string `json:"quota"`
+	Limit     int    `json:"limit"`
+	Usage     int    `json:"usage"`
+}
+
+func (e *ProjectQuotaError) Error() string {
+	return fmt.Sprintf("project [%s] exceeds the quota of %s, limit [%d], usage [%d]",
+		e.ProjectId, e.Quota, e.Limit, e.Usage)
+}
+
+// ProjectQuotaItem is the usage of a quota, a limit of 0 means unlimited.
+type ProjectQuotaItem struct {
+	Quota string `json:"quota"`
+	Limit int    `json:"limit"`
+	Usage int    `json:"usage"`
+}
+
+func projectQuotaLimit(quota *models.ProjectQuota, name string) int {
+	switch name {
+	case QuotaPipelines:
+		return quota.MaxPipelines
+	case QuotaCredentials:
+		return quota.MaxCredentials
+	case QuotaMembers:
+		return quota.MaxMembers
+	case QuotaConcurrentRuns:
+		return quota.MaxConcurrentRuns
+	case QuotaRetainedBuilds:
+		return quota.MaxRetainedBuilds
+	default:
+		return 0
+	}
+}
+
+// applyRetainedBuildsQuota keeps the builds of a pipeline under the limit,
+// a pipeline without a number of builds to keep gets the limit. The discarder of multi-branch pipelines
+// removes branches instead of builds, so they are not changed.
+func applyRetainedBuildsQuota(projectId string, limit int, request *JenkinsJobRequest) error {
+	if limit <= 0 || request.Type != JenkinsJobPipeline || request.Define == nil {
+		return nil
+	}
+	discarder, ok := request.Define["discarder"].(map[string]interface{})
+	if !ok || discarder == nil {
+		discarder = map[string]interface{}{"days_to_keep": "-1"}
+		request.Define["discarder"] = discarder
+	}
+	numToKeep, _ := discarder["num_to_keep"].(string)
+	if numToKeep == "" {
+		discarder["num_to_keep"] = strconv.Itoa(limit)
+		return nil
+	}
+	num, err := strconv.Atoi(numToKeep)
+	if err != nil {
+		return fmt.Errorf("invalid number of builds to keep [%s]", numToKeep)
+	}
+	if num <= 0 || num > limit {
+		return &ProjectQuotaError{ProjectId: projectId, Quota: QuotaRetainedBuilds, Limit: limit, Usage: num}
+	}
+	return nil
+}
+
+// getProjectQuota returns the quota of a project, projects without a quota are unlimited.
+func (s *ProjectService) getProjectQuota(projectId string) (*models.ProjectQuota, error) {
+	quota := &models.ProjectQuota{}
+	err := s.Ds.Db.Select(models.ProjectQuotaColumns...).
+		From(models.ProjectQuotaTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		LoadOne(quota)
+	if err == db.ErrNotFound {
+		return &models.ProjectQuota{ProjectId: projectId}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return quota, nil
+}
+
+func (s *ProjectService) setProjectQuota(quota *models.ProjectQuota) error {
+	tx, err := s.Ds.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.RollbackUnlessCommitted()
+	_, err = tx.DeleteFrom(models.ProjectQuotaTableName).
+		Where(db.Eq(models.ProjectIdColumn, quota.ProjectId)).Exec()
+	if err != nil {
+		return err
+	}
+	_, err = tx.InsertInto(models.ProjectQuotaTableName).
+		Columns(models.ProjectQuotaColumns...).
+		Record(quota).Exec()
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// countProjectMembers counts the members and the invitations which have not expired.
+func (s *ProjectService) countProjectMembers(projectId string) (int, error) {
+	memberships := make([]*models.ProjectMembership, 0)
+	_, err := s.Ds.Db.Select(models.ProjectMembershipColumns...).
+		From(models.ProjectMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipProjectIdColumn, projectId),
+			db.Eq(constants.StatusColumn, []string{constants.StatusActive, constants.StatusPending}))).
+		Load(&memberships)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	now := time.Now()
+	for _, membership := range memberships {
+		if !invitationExpired(membership, now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// jobUrlProject returns the top level folder of a jenkins job or build url, which is the project,
+// jenkins may be served under a context path so the first job segment of the path is used.
+func jobUrlProject(rawUrl string) string {
+	jobUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(jobUrl.EscapedPath(), "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i] == "job" {
+			project, err := url.PathUnescape(segments[i+1])
+			if err != nil {
+				return ""
+			}
+			return project
+		}
+	}
+	return ""
+}
+
+// countProjectRuns counts the running and queued builds of the pipelines of a project.
+func (s *ProjectService) countProjectRuns(projectId string) (int, error) {
+	count := 0
+	urls, err := s.Ds.Jenkins.GetRunningBuildUrls()
+	if err != nil {
+		return 0, err
+	}
+	for _, buildUrl := range urls {
+		if jobUrlProject(buildUrl) == projectId {
+			count++
+		}
+	}
+	queue, err := s.Ds.Jenkins.GetQueue()
+	if err != nil {
+		return 0, err
+	}
+	for _, item := range queue.Raw.Items {
+		if jobUrlProject(item.Task.URL) == projectId {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// countProjectRetainedBuilds returns the most builds kept by a pipeline or a branch of the project.
+func (s *ProjectService) countProjectRetainedBuilds(projectId string) (int, error) {
+	folder, err := s.Ds.Jenkins.GetFolder(projectId)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, innerJob := range folder.Raw.Jobs {
+		// multi-branch pipelines are folders of branches, they have no color
+		if innerJob.Color == "" {
+			branches, err := s.Ds.Jenkins.GetFolder(innerJob.Name, projectId)
+			if err != nil {
+				return 0, err
+			}
+			for _, branch := range branches.Raw.Jobs {
+				branchJob, err := s.Ds.Jenkins.GetJob(branch.Name, projectId, innerJob.Name)
+				if err != nil {
+					return 0, err
+				}
+				if len(branchJob.Raw.Builds) > count {
+					count = len(branchJob.Raw.Builds)
+				}
+			}
+			continue
+		}
+		job, err := s.Ds.Jenkins.GetJob(innerJob.Name, projectId)
+		if err != nil {
+			return 0, err
+		}
+		if len(job.Raw.Builds) > count {
+			count = len(job.Raw.Builds)
+		}
+	}
+	return count, nil
+}
+
+func (s *ProjectService) getProjectQuotaUsage(projectId, name string) (int, error) {
+	switch name {
+	case QuotaPipelines:
+		folder, err := s.Ds.Jenkins.GetFolder(projectId)
+		if err != nil {
+			return 0, err
+		}
+		return len(folder.Raw.Jobs), nil
+	case QuotaCredentials:
+		credentials, err := s.Ds.Jenkins.GetCredentialsInFolder("", projectId)
+		if err != nil {
+			return 0, err
+		}
+		return len(credentials), nil
+	case QuotaMembers:
+		return s.countProjectMembers(projectId)
+	case QuotaConcurrentRuns:
+		return s.countProjectRuns(projectId)
+	case QuotaRetainedBuilds:
+		return s.countProjectRetainedBuilds(projectId)
+	default:
+		return 0, fmt.Errorf("unknown quota [%s]", name)
+	}
+}
+
+func (s *ProjectService) getProjectQuotaItems(projectId string) ([]*ProjectQuotaItem, error) {
+	quota, err := s.getProjectQuota(projectId)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*ProjectQuotaItem, 0)
+	for _, name := range AllQuotaSlice {
+		usage, err := s.getProjectQuotaUsage(projectId, name)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &ProjectQuotaItem{Quota: name, Limit: projectQuotaLimit(quota, name), Usage: usage})
+	}
+	return items, nil
+}
+
+// checkProjectQuota returns a *ProjectQuotaError if one more resource would exceed the quota.
+func (s *ProjectService) checkProjectQuota(projectId, name string) error {
+	quota, err := s.getProjectQuota(projectId)
+	if err != nil {
+		return err
+	}
+	limit := projectQuotaLimit(quota, name)
+	if limit <= 0 {
+		return nil
+	}
+	usage, err := s.getProjectQuotaUsage(projectId, name)
+	if err != nil {
+		if stringutils.GetJenkinsStatusCode(err) == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	if usage >= limit {
+		return &ProjectQuotaError{ProjectId: projectId, Quota: name, Limit: limit, Usage: usage}
+	}
+	return nil
+}
+
+// checkRetainedBuildsQuota applies the retained builds quota to the discarder of a pipeline.
+func (s *ProjectService) checkRetainedBuildsQuota(projectId string, request *JenkinsJobRequest) error {
+	quota, err := s.getProjectQuota(projectId)
+	if err != nil {
+		return err
+	}
+	return applyRetainedBuildsQuota(projectId, quota.MaxRetainedBuilds, request)
+}
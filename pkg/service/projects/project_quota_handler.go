@@ -0,0 +1,143 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type UpdateProjectQuotaRequest struct {
+	MaxPipelines      int `json:"max_pipelines"`
+	MaxCredentials    int `json:"max_credentials"`
+	MaxMembers        int `json:"max_members"`
+	MaxConcurrentRuns int `json:"max_concurrent_runs"`
+	MaxRetainedBuilds int `json:"max_retained_builds"`
+}
+
+type ProjectQuotaResponse struct {
+	*models.ProjectQuota
+	Items []*ProjectQuotaItem `json:"items"`
+}
+
+// ProjectQuotaErrorResponse keeps the Error field of rest.Error and adds the exceeded quota.
+type ProjectQuotaErrorResponse struct {
+	Error string `json:"Error"`
+	*ProjectQuotaError
+}
+
+// writeProjectQuotaError writes a 403 with the exceeded quota, other errors are written as usual.
+func writeProjectQuotaError(w rest.ResponseWriter, err error) {
+	logger.Error("%+v", err)
+	quotaErr, ok := err.(*ProjectQuotaError)
+	if !ok {
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteHeader(http.StatusForbidden)
+	w.WriteJson(&ProjectQuotaErrorResponse{Error: quotaErr.Error(), ProjectQuotaError: quotaErr})
+}
+
+// GetProjectQuotaHandler returns the limits and the usage of a project to its members and platform admins.
+func (s *ProjectService) GetProjectQuotaHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	if !s.isPlatformAdmin(operator) {
+		_, err := s.getProjectUserRole(operator, projectId)
+		if err != nil {
+			logger.Error("%+v", err)
+			rest.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	quota, err := s.getProjectQuota(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	items, err := s.getProjectQuotaItems(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), stringutils.GetJenkinsStatusCode(err))
+		return
+	}
+	w.WriteJson(&ProjectQuotaResponse{ProjectQuota: quota, Items: items})
+	return
+}
+
+// UpdateProjectQuotaHandler sets the quota of a project, only platform admins are allowed.
+// The quota is not applied to the existing resources.
+func (s *ProjectService) UpdateProjectQuotaHandler(w rest.ResponseWriter, r *rest.Request) {
+	projectId := r.PathParams["id"]
+	operator := userutils.GetUserNameFromRequest(r)
+	request := &UpdateProjectQuotaRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not update quotas", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if request.MaxPipelines < 0 || request.MaxCredentials < 0 || request.MaxMembers < 0 ||
+		request.MaxConcurrentRuns < 0 || request.MaxRetainedBuilds < 0 {
+		err := fmt.Errorf("quota can not be negative")
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = s.getProject(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.checkProjectNotArchived(projectId)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	quota := models.NewProjectQuota(projectId, operator)
+	quota.MaxPipelines = request.MaxPipelines
+	quota.MaxCredentials = request.MaxCredentials
+	quota.MaxMembers = request.MaxMembers
+	quota.MaxConcurrentRuns = request.MaxConcurrentRuns
+	quota.MaxRetainedBuilds = request.MaxRetainedBuilds
+	err = s.setProjectQuota(quota)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(quota)
+	return
+}
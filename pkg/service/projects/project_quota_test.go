@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"testing"
+
+	"kubesphere.io/devops/pkg/models"
+)
+
+func TestProjectQuotaLimit(t *testing.T) {
+	quota := &models.ProjectQuota{MaxPipelines: 1, MaxCredentials: 2, MaxMembers: 3, MaxConcurrentRuns: 4, MaxRetainedBuilds: 5}
+	for i, name := range AllQuotaSlice {
+		if limit := projectQuotaLimit(quota, name); limit != i+1 {
+			t.Fatalf("limit of %s should be %d, got %d", name, i+1, limit)
+		}
+	}
+	if limit := projectQuotaLimit(quota, "executors"); limit != 0 {
+		t.Fatalf("unknown quota should be unlimited, got %d", limit)
+	}
+}
+
+func TestApplyRetainedBuildsQuota(t *testing.T) {
+	request := &JenkinsJobRequest{Type: JenkinsJobPipeline, Define: map[string]interface{}{"name": "build"}}
+	if err := applyRetainedBuildsQuota("project-demo", 0, request); err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if _, ok := request.Define["discarder"]; ok {
+		t.Fatalf("unlimited quota should not change the pipeline")
+	}
+
+	if err := applyRetainedBuildsQuota("project-demo", 10, request); err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	discarder := request.Define["discarder"].(map[string]interface{})
+	if discarder["num_to_keep"] != "10" {
+		t.Fatalf("pipeline without discarder should keep the limit, got %v", discarder)
+	}
+
+	request.Define["discarder"] = map[string]interface{}{"days_to_keep": "7", "num_to_keep": "5"}
+	if err := applyRetainedBuildsQuota("project-demo", 10, request); err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+
+	request.Define["discarder"] = map[string]interface{}{"num_to_keep": "20"}
+	err := applyRetainedBuildsQuota("project-demo", 10, request)
+	quotaErr, ok := err.(*ProjectQuotaError)
+	if !ok {
+		t.Fatalf("should get quota error, got %+v", err)
+	}
+	if quotaErr.Quota != QuotaRetainedBuilds || quotaErr.Limit != 10 || quotaErr.Usage != 20 {
+		t.Fatalf("unexpected quota error %+v", quotaErr)
+	}
+
+	request.Define["discarder"] = map[string]interface{}{"num_to_keep": "many"}
+	if err := applyRetainedBuildsQuota("project-demo", 10, request); err == nil {
+		t.Fatalf("invalid number should get error")
+	}
+
+	multiBranch := &JenkinsJobRequest{Type: JenkinsJobMultiBranchPipeline, Define: map[string]interface{}{"name": "build"}}
+	if err := applyRetainedBuildsQuota("project-demo", 10, multiBranch); err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if _, ok := multiBranch.Define["discarder"]; ok {
+		t.Fatalf("multi-branch pipeline should not be changed")
+	}
+}
+
+func TestJobUrlProject(t *testing.T) {
+	for _, item := range []struct {
+		url      string
+		expected string
+	}{
+		{"http://jenkins:8080/job/project-1/job/build/12/", "project-1"},
+		{"http://jenkins:8080/jenkins/job/project-1/job/build/job/master/3/", "project-1"},
+		// a pipeline of another project named as the project
+		{"http://jenkins:8080/job/other/job/project-1/5/", "other"},
+		{"http://jenkins:8080/job/project%201/job/build/", "project 1"},
+		{"http://jenkins:8080/computer/", ""},
+		{"http://jenkins:8080/job/", ""},
+	} {
+		if project := jobUrlProject(item.url); project != item.expected {
+			t.Fatalf("project of %s should be [%s], got [%s]", item.url, item.expected, project)
+		}
+	}
+}
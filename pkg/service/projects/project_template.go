@@ -0,0 +1,279 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/mitchellh/mapstructure"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+)
+
+// ProjectNameVariable is always set to the name of the created project.
+const ProjectNameVariable = "project_name"
+
+// templateVariableRegexp matches {{name}} references, ${name} is left to groovy and shell interpolation
+// of jenkinsfiles.
+var templateVariableRegexp = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+var templateVariableNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     string `json:"default"`
+	Required    bool   `json:"required"`
+}
+
+// ProjectTemplateContent is the starter setup applied to a new project,
+// the strings of pipelines, credentials and members can reference variables as {{name}}.
+// Credentials are stubs, their secrets are expected to be filled in after creation.
+type ProjectTemplateContent struct {
+	Variables   []*TemplateVariable  `json:"variables"`
+	Pipelines   []*JenkinsJobRequest `json:"pipelines"`
+	Credentials []*CredentialRequest `json:"credentials"`
+	Members     []*BundleMember      `json:"members"`
+	// Discarder is used by the pipelines which do not define one.
+	Discarder *DiscarderProperty `json:"discarder"`
+}
+
+// renderTemplateValue replaces the variables of every string in a decoded json value.
+func renderTemplateValue(value interface{}, variables map[string]string) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		var err error
+		rendered := templateVariableRegexp.ReplaceAllStringFunc(value, func(reference string) string {
+			name := templateVariableRegexp.FindStringSubmatch(reference)[1]
+			variable, ok := variables[name]
+			if !ok && err == nil {
+				err = fmt.Errorf("variable [%s] is not defined", name)
+			}
+			return variable
+		})
+		return rendered, err
+	case []interface{}:
+		for i, item := range value {
+			rendered, err := renderTemplateValue(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = rendered
+		}
+		return value, nil
+	case map[string]interface{}:
+		for key, item := range value {
+			rendered, err := renderTemplateValue(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = rendered
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
+
+// templateVariables merges the given values with the defaults of the template,
+// unknown variables and missing required variables are rejected.
+func templateVariables(content *ProjectTemplateContent, projectName string, values map[string]string) (map[string]string, error) {
+	variables := map[string]string{ProjectNameVariable: projectName}
+	declared := make([]string, 0)
+	for _, variable := range content.Variables {
+		declared = append(declared, variable.Name)
+		value, ok := values[variable.Name]
+		if !ok || value == "" {
+			if variable.Required {
+				return nil, fmt.Errorf("variable [%s] is required", variable.Name)
+			}
+			value = variable.Default
+		}
+		variables[variable.Name] = value
+	}
+	for name := range values {
+		if !reflectutils.In(name, declared) {
+			return nil, fmt.Errorf("variable [%s] is not declared by the template", name)
+		}
+	}
+	return variables, nil
+}
+
+// renderProjectTemplate returns the content of a template with the variables substituted
+// and the default discarder set on the pipelines.
+func renderProjectTemplate(content *ProjectTemplateContent, projectName string,
+	values map[string]string) (*ProjectTemplateContent, error) {
+	variables, err := templateVariables(content, projectName, values)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	if err != nil {
+		return nil, err
+	}
+	value, err = renderTemplateValue(value, variables)
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	rendered := &ProjectTemplateContent{}
+	err = json.Unmarshal(data, rendered)
+	if err != nil {
+		return nil, err
+	}
+	if rendered.Discarder != nil {
+		for _, pipeline := range rendered.Pipelines {
+			if pipeline.Define == nil {
+				continue
+			}
+			if _, ok := pipeline.Define["discarder"]; !ok {
+				pipeline.Define["discarder"] = map[string]interface{}{
+					"days_to_keep": rendered.Discarder.DaysToKeep,
+					"num_to_keep":  rendered.Discarder.NumToKeep,
+				}
+			}
+		}
+	}
+	return rendered, nil
+}
+
+// validateProjectTemplate renders a template with placeholder values and checks every item of it.
+func validateProjectTemplate(content *ProjectTemplateContent) error {
+	declared := []string{ProjectNameVariable}
+	values := make(map[string]string)
+	for _, variable := range content.Variables {
+		if !templateVariableNameRegexp.MatchString(variable.Name) {
+			return fmt.Errorf("invalid variable name [%s]", variable.Name)
+		}
+		if reflectutils.In(variable.Name, declared) {
+			return fmt.Errorf("variable [%s] is declared more than once", variable.Name)
+		}
+		declared = append(declared, variable.Name)
+		values[variable.Name] = variable.Name
+	}
+	rendered, err := renderProjectTemplate(content, ProjectNameVariable, values)
+	if err != nil {
+		return err
+	}
+	for _, member := range rendered.Members {
+		if govalidator.IsNull(member.Username) {
+			return fmt.Errorf("error need username of member")
+		}
+		if !reflectutils.In(member.Role, AllRoleSlice) {
+			return fmt.Errorf("member role [%s] is not a built-in role", member.Role)
+		}
+	}
+	pipelineNames := make([]string, 0)
+	for _, pipeline := range rendered.Pipelines {
+		name, _, err := jenkinsJobConfig(ProjectNameVariable, pipeline)
+		if err != nil {
+			return err
+		}
+		if reflectutils.In(name, pipelineNames) {
+			return fmt.Errorf("pipeline [%s] is defined more than once", name)
+		}
+		pipelineNames = append(pipelineNames, name)
+	}
+	for _, credential := range rendered.Credentials {
+		credentialContent, err := newCredentialContent(credential.Type)
+		if err != nil {
+			return err
+		}
+		err = mapstructure.Decode(credential.Content, credentialContent)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateImportItems plans the rendered template as the items of an import into a new project,
+// the creator is already the owner of the project.
+func templateImportItems(content *ProjectTemplateContent, creator string) ([]*BundleItemResult, error) {
+	items := make([]*BundleItemResult, 0)
+	for _, member := range content.Members {
+		item := &BundleItemResult{Kind: BundleItemMember, Name: member.Username, Action: BundleActionCreate, entry: member}
+		if member.Username == creator {
+			item.Action, item.Message = BundleActionSkip, "the creator is the owner of the project"
+		}
+		items = append(items, item)
+	}
+	for _, pipeline := range content.Pipelines {
+		items = append(items, &BundleItemResult{Kind: BundleItemPipeline, Name: jenkinsJobRequestName(pipeline),
+			Action: BundleActionCreate, entry: pipeline})
+	}
+	for _, credential := range content.Credentials {
+		credentialContent, err := newCredentialContent(credential.Type)
+		if err != nil {
+			return nil, err
+		}
+		err = mapstructure.Decode(credential.Content, credentialContent)
+		if err != nil {
+			return nil, err
+		}
+		id, _ := credential.Content["id"].(string)
+		items = append(items, &BundleItemResult{Kind: BundleItemCredential, Name: id, Action: BundleActionCreate,
+			entry: &bundleCredentialContent{Domain: credential.Domain, Content: credentialContent}})
+	}
+	return items, nil
+}
+
+func parseProjectTemplateContent(template *models.ProjectTemplate) (*ProjectTemplateContent, error) {
+	content := &ProjectTemplateContent{}
+	err := json.Unmarshal([]byte(template.Content), content)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (s *ProjectService) getProjectTemplate(templateId string) (*models.ProjectTemplate, error) {
+	template := &models.ProjectTemplate{}
+	err := s.Ds.Db.Select(models.ProjectTemplateColumns...).
+		From(models.ProjectTemplateTableName).
+		Where(db.Eq(models.ProjectTemplateIdColumn, templateId)).
+		LoadOne(template)
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// checkProjectTemplateName returns an error if another template has the name.
+func (s *ProjectService) checkProjectTemplateName(templateId, name string) error {
+	count, err := s.Ds.Db.Select(models.ProjectTemplateIdColumn).
+		From(models.ProjectTemplateTableName).
+		Where(db.And(db.Eq(models.ProjectTemplateNameColumn, name),
+			db.Neq(models.ProjectTemplateIdColumn, templateId))).
+		Count()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("template name [%s] has been used", name)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/userutils"
+)
+
+type ProjectTemplateRequest struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Content     *ProjectTemplateContent `json:"content"`
+}
+
+type ProjectTemplateResponse struct {
+	*models.ProjectTemplate
+	Content *ProjectTemplateContent `json:"content"`
+}
+
+// decodeProjectTemplateRequest decodes and validates a template, the returned content is encoded as json.
+func decodeProjectTemplateRequest(r *rest.Request) (*ProjectTemplateRequest, string, error) {
+	request := &ProjectTemplateRequest{}
+	err := r.DecodeJsonPayload(request)
+	if err != nil {
+		return nil, "", err
+	}
+	if govalidator.IsNull(request.Name) {
+		return nil, "", fmt.Errorf("error need name of template")
+	}
+	if request.Content == nil {
+		request.Content = &ProjectTemplateContent{}
+	}
+	err = validateProjectTemplate(request.Content)
+	if err != nil {
+		return nil, "", err
+	}
+	content, err := json.Marshal(request.Content)
+	if err != nil {
+		return nil, "", err
+	}
+	return request, string(content), nil
+}
+
+func (s *ProjectService) GetProjectTemplatesHandler(w rest.ResponseWriter, r *rest.Request) {
+	templates := make([]*models.ProjectTemplate, 0)
+	_, err := s.Ds.Db.Select(models.ProjectTemplateColumns...).
+		From(models.ProjectTemplateTableName).
+		Load(&templates)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(templates)
+	return
+}
+
+func (s *ProjectService) GetProjectTemplateHandler(w rest.ResponseWriter, r *rest.Request) {
+	templateId := r.PathParams["tid"]
+	template, err := s.getProjectTemplate(templateId)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	content, err := parseProjectTemplateContent(template)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(&ProjectTemplateResponse{ProjectTemplate: template, Content: content})
+	return
+}
+
+func (s *ProjectService) CreateProjectTemplateHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not create templates", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	request, content, err := decodeProjectTemplateRequest(r)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectTemplateName("", request.Name)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	template := models.NewProjectTemplate(request.Name, request.Description, content, operator)
+	_, err = s.Ds.Db.InsertInto(models.ProjectTemplateTableName).
+		Columns(models.ProjectTemplateColumns...).
+		Record(template).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(&ProjectTemplateResponse{ProjectTemplate: template, Content: request.Content})
+	return
+}
+
+func (s *ProjectService) UpdateProjectTemplateHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	templateId := r.PathParams["tid"]
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not update templates", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	template, err := s.getProjectTemplate(templateId)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	request, content, err := decodeProjectTemplateRequest(r)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.checkProjectTemplateName(templateId, request.Name)
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	template.Name, template.Description, template.Content = request.Name, request.Description, content
+	template.StatusTime = time.Now()
+	_, err = s.Ds.Db.Update(models.ProjectTemplateTableName).
+		Set(models.ProjectTemplateNameColumn, template.Name).
+		Set(models.ProjectTemplateDescriptionColumn, template.Description).
+		Set(models.ProjectTemplateContentColumn, template.Content).
+		Set(models.ProjectTemplateStatusTimeColumn, template.StatusTime).
+		Where(db.Eq(models.ProjectTemplateIdColumn, templateId)).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(&ProjectTemplateResponse{ProjectTemplate: template, Content: request.Content})
+	return
+}
+
+// DeleteProjectTemplateHandler removes a template, the projects created from it are not changed.
+func (s *ProjectService) DeleteProjectTemplateHandler(w rest.ResponseWriter, r *rest.Request) {
+	operator := userutils.GetUserNameFromRequest(r)
+	templateId := r.PathParams["tid"]
+	if !s.isPlatformAdmin(operator) {
+		err := fmt.Errorf("user [%s] can not delete templates", operator)
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	template, err := s.getProjectTemplate(templateId)
+	if err != nil {
+		logger.Error("%+v", err)
+		if err == db.ErrNotFound {
+			rest.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = s.Ds.Db.DeleteFrom(models.ProjectTemplateTableName).
+		Where(db.Eq(models.ProjectTemplateIdColumn, templateId)).Exec()
+	if err != nil {
+		logger.Error("%+v", err)
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteJson(template)
+	return
+}
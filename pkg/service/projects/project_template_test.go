@@ -0,0 +1,179 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestProjectTemplateContent() *ProjectTemplateContent {
+	return &ProjectTemplateContent{
+		Variables: []*TemplateVariable{
+			{Name: "repo", Required: true},
+			{Name: "branch", Default: "master"},
+		},
+		Pipelines: []*JenkinsJobRequest{{Type: JenkinsJobMultiBranchPipeline, Define: map[string]interface{}{
+			"name": "{{project_name}}-build",
+			"source": map[string]interface{}{"type": "git", "define": map[string]interface{}{
+				"url": "{{repo}}", "credential_id": "git", "regex_filter": "{{ branch }}"}},
+			"script_path": "Jenkinsfile",
+		}}},
+		Credentials: []*CredentialRequest{{Type: CredentialTypeUsernamePassword, Domain: "_",
+			Content: map[string]interface{}{"id": "git", "username": "{{project_name}}"}}},
+		Members:   []*BundleMember{{Username: "admin", Role: ProjectOwner}, {Username: "qa", Role: ProjectReporter}},
+		Discarder: &DiscarderProperty{DaysToKeep: "7", NumToKeep: "10"},
+	}
+}
+
+func TestRenderProjectTemplate(t *testing.T) {
+	content := newTestProjectTemplateContent()
+	rendered, err := renderProjectTemplate(content, "demo", map[string]string{"repo": "https://example.com/demo.git"})
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	define := rendered.Pipelines[0].Define
+	if define["name"] != "demo-build" {
+		t.Fatalf("pipeline name should be rendered, got %v", define["name"])
+	}
+	source := define["source"].(map[string]interface{})["define"].(map[string]interface{})
+	if source["url"] != "https://example.com/demo.git" || source["regex_filter"] != "master" {
+		t.Fatalf("source should be rendered with the default variable, got %v", source)
+	}
+	expectedDiscarder := map[string]interface{}{"days_to_keep": "7", "num_to_keep": "10"}
+	if !reflect.DeepEqual(define["discarder"], expectedDiscarder) {
+		t.Fatalf("discarder should be %v, got %v", expectedDiscarder, define["discarder"])
+	}
+	if rendered.Credentials[0].Content["username"] != "demo" {
+		t.Fatalf("credential should be rendered, got %v", rendered.Credentials[0].Content)
+	}
+	if content.Pipelines[0].Define["name"] != "{{project_name}}-build" {
+		t.Fatalf("rendering should not change the template")
+	}
+
+	if _, err := renderProjectTemplate(content, "demo", nil); err == nil {
+		t.Fatalf("missing required variable should get error")
+	}
+	if _, err := renderProjectTemplate(content, "demo", map[string]string{"repo": "r", "unknown": "u"}); err == nil {
+		t.Fatalf("undeclared variable should get error")
+	}
+}
+
+const testSonarJenkinsfile = `pipeline {
+  agent any
+  environment {
+    IMAGE = "registry.example.com/{{project_name}}:${BUILD_NUMBER}"
+  }
+  stages {
+    stage('sonar') {
+      steps {
+        withSonarQubeEnv('{{sonar_server}}') {
+          sh "mvn sonar:sonar -Dsonar.projectKey={{project_name}} -Dsonar.projectVersion=${GIT_COMMIT}"
+        }
+      }
+    }
+    stage('build') {
+      steps {
+        sh 'docker build -t $IMAGE . && echo "${env.BUILD_URL}"'
+      }
+    }
+  }
+}`
+
+func TestRenderProjectTemplateJenkinsfile(t *testing.T) {
+	content := &ProjectTemplateContent{
+		Variables: []*TemplateVariable{{Name: "sonar_server", Default: "sonar"}},
+		Pipelines: []*JenkinsJobRequest{{Type: JenkinsJobPipeline, Define: map[string]interface{}{
+			"name":        "{{project_name}}-sonar",
+			"jenkinsfile": testSonarJenkinsfile,
+		}}},
+	}
+	if err := validateProjectTemplate(content); err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	rendered, err := renderProjectTemplate(content, "demo", nil)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	jenkinsfile := rendered.Pipelines[0].Define["jenkinsfile"].(string)
+	for _, expected := range []string{
+		`IMAGE = "registry.example.com/demo:${BUILD_NUMBER}"`,
+		`withSonarQubeEnv('sonar')`,
+		`-Dsonar.projectKey=demo -Dsonar.projectVersion=${GIT_COMMIT}`,
+		`echo "${env.BUILD_URL}"`,
+	} {
+		if !strings.Contains(jenkinsfile, expected) {
+			t.Fatalf("jenkinsfile should contain %s, got %s", expected, jenkinsfile)
+		}
+	}
+}
+
+func TestValidateProjectTemplate(t *testing.T) {
+	if err := validateProjectTemplate(newTestProjectTemplateContent()); err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+
+	content := newTestProjectTemplateContent()
+	content.Pipelines[0].Define["description"] = "{{undefined}}"
+	if err := validateProjectTemplate(content); err == nil {
+		t.Fatalf("reference to undefined variable should get error")
+	}
+
+	content = newTestProjectTemplateContent()
+	content.Members[1].Role = "custom"
+	if err := validateProjectTemplate(content); err == nil {
+		t.Fatalf("custom member role should get error")
+	}
+
+	content = newTestProjectTemplateContent()
+	content.Pipelines = append(content.Pipelines, content.Pipelines[0])
+	if err := validateProjectTemplate(content); err == nil {
+		t.Fatalf("duplicated pipeline should get error")
+	}
+
+	content = newTestProjectTemplateContent()
+	content.Credentials[0].Type = "certificate"
+	if err := validateProjectTemplate(content); err == nil {
+		t.Fatalf("unsupported credential type should get error")
+	}
+
+	content = newTestProjectTemplateContent()
+	content.Variables = append(content.Variables, &TemplateVariable{Name: "project_name"})
+	if err := validateProjectTemplate(content); err == nil {
+		t.Fatalf("redeclared variable should get error")
+	}
+}
+
+func TestTemplateImportItems(t *testing.T) {
+	content, err := renderProjectTemplate(newTestProjectTemplateContent(), "demo", map[string]string{"repo": "r"})
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	items, err := templateImportItems(content, "admin")
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	actions := make([]string, 0)
+	for _, item := range items {
+		actions = append(actions, item.Kind+"/"+item.Name+"/"+item.Action)
+	}
+	expected := []string{"member/admin/skip", "member/qa/create", "pipeline/demo-build/create", "credential/git/create"}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Fatalf("items should be %v, got %v", expected, actions)
+	}
+	if _, ok := items[3].entry.(*bundleCredentialContent).Content.(*UsernamePasswordCredentialRequest); !ok {
+		t.Fatalf("credential should be decoded into its request")
+	}
+}
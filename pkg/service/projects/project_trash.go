@@ -0,0 +1,383 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/logger"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+	"kubesphere.io/devops/pkg/utils/stringutils"
+)
+
+const ProjectPurgeInterval = time.Hour
+
+// the reasons of the jobs disabled by the api
+const (
+	JobDisabledByTrash   = "trash"
+	JobDisabledByArchive = "archive"
+)
+
+// errProjectStatusChanged is returned if the project was changed by another request meanwhile.
+var errProjectStatusChanged = errors.New("project status was changed by another request")
+
+func (s *ProjectService) getProject(projectId string) (*models.Project, error) {
+	project := &models.Project{}
+	err := s.Ds.Db.Select(models.ProjectColumns...).
+		From(models.ProjectTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		LoadOne(project)
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// isProjectAvailable returns false for projects in the trash or not created yet,
+// archived projects stay available for reading.
+func (s *ProjectService) isProjectAvailable(projectId string) (bool, error) {
+	project := &models.Project{}
+	err := s.Ds.Db.Select(constants.StatusColumn).
+		From(models.ProjectTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		LoadOne(project)
+	if err == db.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return project.Status == constants.StatusActive || project.Status == constants.StatusArchived, nil
+}
+
+// getProjectJenkinsRoleNames returns the jenkins roles of the built-in, custom and pipeline member roles.
+func (s *ProjectService) getProjectJenkinsRoleNames(projectId string) ([]string, error) {
+	roleNames := make([]string, 0)
+	for role := range JenkinsProjectPermissionMap {
+		roleNames = append(roleNames, GetProjectRoleName(projectId, role))
+		roleNames = append(roleNames, GetPipelineRoleName(projectId, role))
+	}
+	customRoles := make([]*models.ProjectRole, 0)
+	_, err := s.Ds.Db.Select(models.ProjectRoleNameColumn).
+		From(models.ProjectRoleTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		Load(&customRoles)
+	if err != nil {
+		return nil, err
+	}
+	for _, customRole := range customRoles {
+		roleNames = append(roleNames, GetProjectRoleName(projectId, customRole.Name))
+		roleNames = append(roleNames, GetPipelineRoleName(projectId, customRole.Name))
+	}
+	pipelineMemberships, err := s.getPipelineMemberships(projectId)
+	if err != nil {
+		return nil, err
+	}
+	for _, pipelineMembership := range pipelineMemberships {
+		roleName := GetPipelineMemberRoleName(projectId, pipelineMembership.PipelineId, pipelineMembership.Role)
+		if !reflectutils.In(roleName, roleNames) {
+			roleNames = append(roleNames, roleName)
+		}
+	}
+	return roleNames, nil
+}
+
+// disableProjectJobs disables the enabled jobs in the project folder and records them with the reason,
+// jobs which can not be disabled such as multi-branch pipelines are skipped.
+// A job is recorded before it is disabled so an interrupted call never leaves a disabled job unrecorded.
+func (s *ProjectService) disableProjectJobs(projectId, reason string) error {
+	folder, err := s.Ds.Jenkins.GetFolder(projectId)
+	if err != nil {
+		if stringutils.GetJenkinsStatusCode(err) == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	for _, innerJob := range folder.Raw.Jobs {
+		if innerJob.Color == "disabled" {
+			continue
+		}
+		job, err := s.Ds.Jenkins.GetJob(innerJob.Name, projectId)
+		if err != nil {
+			if stringutils.GetJenkinsStatusCode(err) == http.StatusNotFound {
+				continue
+			}
+			return err
+		}
+		_, err = s.Ds.Db.InsertInto(models.ProjectDisabledJobTableName).
+			Columns(models.ProjectDisabledJobColumns...).
+			Record(models.NewProjectDisabledJob(projectId, innerJob.Name, reason)).Exec()
+		if err != nil && !isDuplicateEntryError(err) {
+			return err
+		}
+		_, err = job.Disable()
+		if err != nil {
+			code := stringutils.GetJenkinsStatusCode(err)
+			if code == http.StatusNotFound || code == http.StatusMethodNotAllowed {
+				logger.Warn("job [%s/%s] can not be disabled", projectId, innerJob.Name)
+				err = s.deleteProjectDisabledJob(projectId, innerJob.Name)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// enableProjectJobs enables the jobs disabled by the api for the reasons, other jobs are not changed.
+func (s *ProjectService) enableProjectJobs(projectId string, reasons ...string) error {
+	disabledJobs := make([]*models.ProjectDisabledJob, 0)
+	_, err := s.Ds.Db.Select(models.ProjectDisabledJobColumns...).
+		From(models.ProjectDisabledJobTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectDisabledJobReasonColumn, reasons))).
+		Load(&disabledJobs)
+	if err != nil {
+		return err
+	}
+	for _, disabledJob := range disabledJobs {
+		job, err := s.Ds.Jenkins.GetJob(disabledJob.JobName, projectId)
+		if err == nil {
+			_, err = job.Enable()
+		}
+		if err != nil && stringutils.GetJenkinsStatusCode(err) != http.StatusNotFound {
+			return err
+		}
+		err = s.deleteProjectDisabledJob(projectId, disabledJob.JobName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ProjectService) deleteProjectDisabledJob(projectId, jobName string) error {
+	_, err := s.Ds.Db.DeleteFrom(models.ProjectDisabledJobTableName).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(models.ProjectDisabledJobNameColumn, jobName))).Exec()
+	return err
+}
+
+// trashProject disables the jobs and deletes the jenkins roles of a project,
+// the members and roles are kept in the database so the project can be restored.
+func (s *ProjectService) trashProject(project *models.Project) error {
+	err := s.disableProjectJobs(project.ProjectId, JobDisabledByTrash)
+	if err != nil {
+		return err
+	}
+	roleNames, err := s.getProjectJenkinsRoleNames(project.ProjectId)
+	if err != nil {
+		return err
+	}
+	err = s.Ds.Jenkins.DeleteProjectRoles(roleNames...)
+	if err != nil {
+		return err
+	}
+	_, err = s.Ds.Db.DeleteFrom(models.ProjectMembershipTableName).
+		Where(db.And(
+			db.Eq(models.ProjectMembershipProjectIdColumn, project.ProjectId),
+			db.Eq(constants.StatusColumn, constants.StatusPending))).Exec()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	_, err = s.Ds.Db.Update(models.ProjectTableName).
+		Set(constants.StatusColumn, constants.StatusTrashed).
+		Set(models.ProjectDeleteTimeColumn, now).
+		Where(db.Eq(models.ProjectIdColumn, project.ProjectId)).Exec()
+	if err != nil {
+		return err
+	}
+	project.Status = constants.StatusTrashed
+	project.DeleteTime = &now
+	if project.Visibility == constants.VisibilityPublic {
+		return s.SyncJenkinsPublicRole()
+	}
+	return nil
+}
+
+// restoreJenkinsProjectRoles creates or overwrites the jenkins roles of a project with the permissions
+// of their roles and assigns their members.
+func (s *ProjectService) restoreJenkinsProjectRoles(projectId string) error {
+	for role, ids := range JenkinsProjectPermissionMap {
+		err := s.overwriteJenkinsProjectRole(projectId, role, GetProjectRoleName(projectId, role),
+			GetProjectRolePattern(projectId), ids)
+		if err != nil {
+			return err
+		}
+	}
+	err := s.overwriteJenkinsProjectRole(projectId, ProjectOwner, GetPipelineRoleName(projectId, ProjectOwner),
+		GetPipelineRolePattern(projectId), JenkinsPipelinePermissionMap[ProjectOwner])
+	if err != nil {
+		return err
+	}
+	customRoles := make([]*models.ProjectRole, 0)
+	_, err = s.Ds.Db.Select(models.ProjectRoleNameColumn).
+		From(models.ProjectRoleTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		Load(&customRoles)
+	if err != nil {
+		return err
+	}
+	for _, customRole := range customRoles {
+		role, err := s.getCustomRole(projectId, customRole.Name)
+		if err != nil {
+			return err
+		}
+		err = s.syncJenkinsCustomRole(role)
+		if err != nil {
+			return err
+		}
+	}
+	// the pipeline roles of the other built-in roles exclude the restricted pipelines
+	err = s.syncJenkinsPipelineRoles(projectId)
+	if err != nil {
+		return err
+	}
+	return s.overwriteJenkinsPipelineMemberRoles(projectId, false)
+}
+
+// restoreProject moves a project out of the trash, it becomes active even if it was archived before,
+// so only the jobs disabled by the trash or the archive are enabled.
+// errProjectStatusChanged is returned if the project left the trash meanwhile, e.g. it is purged.
+func (s *ProjectService) restoreProject(project *models.Project) error {
+	err := s.restoreJenkinsProjectRoles(project.ProjectId)
+	if err != nil {
+		return err
+	}
+	err = s.enableProjectJobs(project.ProjectId, JobDisabledByTrash, JobDisabledByArchive)
+	if err != nil {
+		return err
+	}
+	result, err := s.Ds.Db.Update(models.ProjectTableName).
+		Set(constants.StatusColumn, constants.StatusActive).
+		Set(models.ProjectDeleteTimeColumn, nil).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, project.ProjectId),
+			db.Eq(constants.StatusColumn, constants.StatusTrashed))).Exec()
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errProjectStatusChanged
+	}
+	project.Status = constants.StatusActive
+	project.DeleteTime = nil
+	if project.Visibility == constants.VisibilityPublic {
+		return s.SyncJenkinsPublicRole()
+	}
+	return nil
+}
+
+// purgeProject removes the jenkins folder, the jenkins roles and the members of a project for good,
+// the project is deleting while it is purged and deleted at last.
+func (s *ProjectService) purgeProject(projectId string) error {
+	result, err := s.Ds.Db.Update(models.ProjectTableName).
+		Set(constants.StatusColumn, constants.StatusDeleting).
+		Where(db.And(
+			db.Eq(models.ProjectIdColumn, projectId),
+			db.Eq(constants.StatusColumn, []string{constants.StatusTrashed, constants.StatusDeleting}))).Exec()
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return err
+	}
+	_, err = s.Ds.Jenkins.DeleteJob(projectId)
+	if err != nil && stringutils.GetJenkinsStatusCode(err) != http.StatusNotFound {
+		return err
+	}
+	roleNames, err := s.getProjectJenkinsRoleNames(projectId)
+	if err != nil {
+		return err
+	}
+	err = s.Ds.Jenkins.DeleteProjectRoles(roleNames...)
+	if err != nil {
+		return err
+	}
+	for _, table := range []string{
+		models.ProjectMembershipTableName,
+		models.ProjectRoleTableName,
+		models.PipelineMembershipTableName,
+		models.ProjectGroupBindingTableName,
+		models.ProjectQuotaTableName,
+		models.ProjectLabelTableName,
+		models.ProjectAnnotationTableName,
+		models.ProjectCredentialTableName,
+		models.ProjectCreationStepTableName,
+		models.ProjectDisabledJobTableName,
+		// user tokens have no project
+		models.ApiTokenTableName,
+	} {
+		_, err = s.Ds.Db.DeleteFrom(table).
+			Where(db.Eq(models.ProjectIdColumn, projectId)).Exec()
+		if err != nil {
+			return err
+		}
+	}
+	_, err = s.Ds.Db.Update(models.ProjectTableName).
+		Set(constants.StatusColumn, constants.StatusDeleted).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).Exec()
+	return err
+}
+
+// trashExpireTime returns the delete time before which trashed projects are purged.
+func trashExpireTime(retentionDays int, now time.Time) time.Time {
+	if retentionDays < 0 {
+		retentionDays = 0
+	}
+	return now.AddDate(0, 0, -retentionDays)
+}
+
+// PurgeExpiredProjects purges the projects whose retention in the trash is over,
+// projects left deleting by an interrupted purge are purged again.
+func (s *ProjectService) PurgeExpiredProjects() ([]string, error) {
+	expireTime := trashExpireTime(s.Trash.RetentionDays, time.Now())
+	projects := make([]*models.Project, 0)
+	_, err := s.Ds.Db.Select(models.ProjectIdColumn).
+		From(models.ProjectTableName).
+		Where(db.Or(
+			db.And(
+				db.Eq(constants.StatusColumn, constants.StatusTrashed),
+				db.Lt(models.ProjectDeleteTimeColumn, expireTime)),
+			db.Eq(constants.StatusColumn, constants.StatusDeleting))).
+		Load(&projects)
+	if err != nil {
+		return nil, err
+	}
+	purged := make([]string, 0)
+	for _, project := range projects {
+		err := s.purgeProject(project.ProjectId)
+		if err != nil {
+			logger.Error("failed to purge project [%s], %+v", project.ProjectId, err)
+			continue
+		}
+		purged = append(purged, project.ProjectId)
+	}
+	return purged, nil
+}
@@ -0,0 +1,30 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrashExpireTime(t *testing.T) {
+	now := time.Date(2018, 10, 8, 12, 0, 0, 0, time.UTC)
+	expected := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	if expireTime := trashExpireTime(7, now); !expireTime.Equal(expected) {
+		t.Fatalf("expire time should be %s, got %s", expected, expireTime)
+	}
+	if expireTime := trashExpireTime(-1, now); !expireTime.Equal(now) {
+		t.Fatalf("expire time with negative retention should be %s, got %s", now, expireTime)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"strings"
+
+	"kubesphere.io/devops/pkg/constants"
+	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/models"
+	"kubesphere.io/devops/pkg/utils/reflectutils"
+)
+
+const (
+	// JenkinsAuthenticatedSid is the jenkins sid of every logged in user
+	JenkinsAuthenticatedSid = "authenticated"
+	// jenkinsNoMatchPattern is the pattern of the all-users project role if there is no public project
+	jenkinsNoMatchPattern = "\\n\\s*\\r"
+)
+
+var VisibilitySlice = []string{constants.VisibilityPrivate, constants.VisibilityPublic}
+
+// GetPublicProjectsRolePattern matches the public projects and their pipelines except the restricted pipelines.
+func GetPublicProjectsRolePattern(restrictedPipelines map[string][]string, projectIds []string) string {
+	if len(projectIds) == 0 {
+		return jenkinsNoMatchPattern
+	}
+	patterns := make([]string, 0, 2*len(projectIds))
+	for _, projectId := range projectIds {
+		patterns = append(patterns, GetProjectRolePattern(projectId),
+			GetRestrictedPipelineRolePattern(projectId, restrictedPipelines[projectId]))
+	}
+	return strings.Join(patterns, "|")
+}
+
+func (s *ProjectService) getPublicProjectIds() ([]string, error) {
+	projects := make([]*models.Project, 0)
+	_, err := s.Ds.Db.Select(models.ProjectIdColumn).
+		From(models.ProjectTableName).
+		Where(db.And(
+			db.Eq(models.ProjectVisibilityColumn, constants.VisibilityPublic),
+			db.Eq(constants.StatusColumn, []string{constants.StatusActive, constants.StatusArchived}))).
+		Load(&projects)
+	if err != nil {
+		return nil, err
+	}
+	projectIds := make([]string, 0, len(projects))
+	for _, project := range projects {
+		projectIds = append(projectIds, project.ProjectId)
+	}
+	return projectIds, nil
+}
+
+func (s *ProjectService) isPublicProject(projectId string) bool {
+	project := &models.Project{}
+	err := s.Ds.Db.Select(models.ProjectVisibilityColumn, constants.StatusColumn).
+		From(models.ProjectTableName).
+		Where(db.Eq(models.ProjectIdColumn, projectId)).
+		LoadOne(project)
+	if err != nil {
+		return false
+	}
+	return project.Visibility == constants.VisibilityPublic &&
+		(project.Status == constants.StatusActive || project.Status == constants.StatusArchived)
+}
+
+// isPublicPipeline checks that non-members can read a pipeline, restricted pipelines are never public.
+func (s *ProjectService) isPublicPipeline(projectId, pipelineId string) bool {
+	if !s.isPublicProject(projectId) {
+		return false
+	}
+	restrictedPipelines, err := s.getRestrictedPipelines(projectId)
+	if err != nil {
+		return false
+	}
+	return !reflectutils.In(pipelineId, restrictedPipelines)
+}
+
+// SyncJenkinsPublicRole mirrors the public projects into the all-users jenkins project role,
+// which grants reporter permissions to every authenticated user.
+func (s *ProjectService) SyncJenkinsPublicRole() error {
+	projectIds, err := s.getPublicProjectIds()
+	if err != nil {
+		return err
+	}
+	restrictedPipelines := make(map[string][]string)
+	for _, projectId := range projectIds {
+		restrictedPipelines[projectId], err = s.getRestrictedPipelines(projectId)
+		if err != nil {
+			return err
+		}
+	}
+	role, err := s.Ds.Jenkins.AddProjectRole(constants.JenkinsAllUserRoleName,
+		GetPublicProjectsRolePattern(restrictedPipelines, projectIds),
+		JenkinsPipelinePermissionMap[ProjectReporter], true)
+	if err != nil {
+		return err
+	}
+	return role.AssignRole(JenkinsAuthenticatedSid)
+}
@@ -0,0 +1,30 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package projects
+
+import (
+	"testing"
+)
+
+func TestGetPublicProjectsRolePattern(t *testing.T) {
+	if pattern := GetPublicProjectsRolePattern(nil, nil); pattern != jenkinsNoMatchPattern {
+		t.Fatalf("pattern without public projects should be %s, got %s", jenkinsNoMatchPattern, pattern)
+	}
+	pattern := GetPublicProjectsRolePattern(map[string][]string{"project-b": {"deploy"}},
+		[]string{"project-a", "project-b"})
+	expected := "^project-a$|^project-a/.*|^project-b$|^project-b/(?!(deploy)(/.*)?$).*"
+	if pattern != expected {
+		t.Fatalf("pattern should be %s, got %s", expected, pattern)
+	}
+}
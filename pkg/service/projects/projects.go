@@ -15,17 +15,19 @@ package projects
 
 import (
 	"fmt"
+	"strings"
 
-	"kubesphere.io/devops/pkg/constants"
-	"kubesphere.io/devops/pkg/db"
+	"kubesphere.io/devops/pkg/config"
 	"kubesphere.io/devops/pkg/ds"
 	"kubesphere.io/devops/pkg/gojenkins"
-	"kubesphere.io/devops/pkg/models"
 	"kubesphere.io/devops/pkg/utils/reflectutils"
 )
 
 type ProjectService struct {
-	Ds *ds.Ds
+	Ds    *ds.Ds
+	Rbac  config.RbacConfig
+	Ldap  config.LdapConfig
+	Trash config.TrashConfig
 }
 
 const (
@@ -241,20 +243,63 @@ func GetPipelineRolePattern(projectId string) string {
 }
 
 func (s *ProjectService) checkProjectUserInRole(username, projectId string, roles []string) error {
-	if username == constants.KS_ADMIN {
+	if s.isPlatformAdmin(username) {
 		return nil
 	}
-	membership := &models.ProjectMembership{}
-	err := s.Ds.Db.Select(models.ProjectMembershipColumns...).
-		From(models.ProjectMembershipTableName).
-		Where(db.And(
-			db.Eq(models.ProjectMembershipUsernameColumn, username),
-			db.Eq(models.ProjectMembershipProjectIdColumn, projectId))).LoadOne(membership)
+	// auditors have read-only access to every project
+	if reflectutils.In(ProjectReporter, roles) && s.hasGlobalRole(username, GlobalRoleAuditor) {
+		return nil
+	}
+	role, err := s.getProjectUserRole(username, projectId)
+	if err == nil && roleInRoles(role, roles) {
+		return nil
+	}
+	// public projects are readable by every user
+	if reflectutils.In(ProjectReporter, roles) && s.isPublicProject(projectId) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("user [%s] in project [%s] role is not in %s", username, projectId, roles)
+}
+
+// roleInRoles checks a project role against the roles allowed by a handler,
+// custom roles have read access, other operations are checked by permissions.
+func roleInRoles(role string, roles []string) bool {
+	return reflectutils.In(role, roles) || (reflectutils.In(ProjectReporter, roles) && !reflectutils.In(role, AllRoleSlice))
+}
+
+// checkProjectUserPermission checks the service permission of the built-in or custom role of a user.
+func (s *ProjectService) checkProjectUserPermission(username, projectId, permission string) error {
+	if s.isPlatformAdmin(username) {
+		return nil
+	}
+	role, err := s.getProjectUserRole(username, projectId)
+	if err != nil {
+		return err
+	}
+	permissions, err := s.getProjectRolePermissions(projectId, role)
 	if err != nil {
 		return err
 	}
-	if !reflectutils.In(membership.Role, roles) {
-		return fmt.Errorf("user [%s] in project [%s] role is not in %s", username, projectId, roles)
+	if !reflectutils.In(permission, permissions) {
+		return fmt.Errorf("user [%s] in project [%s] does not have permission [%s]", username, projectId, permission)
 	}
 	return nil
 }
+
+// getProjectUserRole returns the role of a user or an api token identity in a project.
+func (s *ProjectService) getProjectUserRole(username, projectId string) (string, error) {
+	available, err := s.isProjectAvailable(projectId)
+	if err != nil {
+		return "", err
+	}
+	if !available {
+		return "", fmt.Errorf("project [%s] is not available", projectId)
+	}
+	if IsApiTokenUsername(username) {
+		return s.getApiTokenProjectRole(strings.TrimPrefix(username, ApiTokenUsernamePrefix), projectId)
+	}
+	return s.getMemberProjectRole(username, projectId)
+}
@@ -27,22 +27,79 @@ func Router(s *Server) (app rest.App) {
 		rest.Post("/projects", s.Projects.CreateProjectHandler),
 		rest.Patch("/projects/:id", s.Projects.UpdateProjectHandler),
 		rest.Delete("/projects/:id", s.Projects.DeleteProjectHandler),
+		rest.Post("/projects/:id/restore", s.Projects.RestoreProjectHandler),
+		rest.Post("/projects/:id/archive", s.Projects.ArchiveProjectHandler),
+		rest.Post("/projects/:id/unarchive", s.Projects.UnarchiveProjectHandler),
+		rest.Get("/projects/:id/export", s.Projects.ExportProjectHandler),
+		rest.Post("/projects/import", s.Projects.ImportProjectHandler),
+		rest.Get("/trash", s.Projects.GetTrashHandler),
+		rest.Get("/projects/:id/creation-steps", s.Projects.GetProjectCreationStepsHandler),
 		rest.Get("/projects/:id/members", s.Projects.GetMembersHandler),
 		rest.Get("/projects/:id/members/:uid", s.Projects.GetMemberHandler),
 		rest.Post("/projects/:id/members", s.Projects.AddProjectMemberHandler),
 		rest.Patch("/projects/:id/members/:uid", s.Projects.UpdateMemberHandler),
 		rest.Delete("/projects/:id/members/:uid", s.Projects.DeleteMemberHandler),
+		rest.Get("/projects/:id/roles", s.Projects.GetCustomRolesHandler),
+		rest.Post("/projects/:id/roles", s.Projects.CreateCustomRoleHandler),
+		rest.Put("/projects/:id/roles/:rid", s.Projects.UpdateCustomRoleHandler),
+		rest.Delete("/projects/:id/roles/:rid", s.Projects.DeleteCustomRoleHandler),
+		rest.Get("/projects/:id/permissions/me", s.Projects.GetMyPermissionsHandler),
+		rest.Get("/projects/:id/permissions/:uid", s.Projects.GetUserPermissionsHandler),
+		rest.Post("/projects/:id/transfer", s.Projects.TransferOwnershipHandler),
+		rest.Get("/invitations", s.Projects.GetInvitationsHandler),
+		rest.Post("/invitations/:id/accept", s.Projects.AcceptInvitationHandler),
+		rest.Post("/invitations/:id/decline", s.Projects.DeclineInvitationHandler),
+		rest.Get("/projects/:id/groups", s.Projects.GetProjectGroupsHandler),
+		rest.Post("/projects/:id/groups", s.Projects.AddProjectGroupHandler),
+		rest.Delete("/projects/:id/groups/:gid", s.Projects.DeleteProjectGroupHandler),
 		rest.Post("/projects/:id/credentials", s.Projects.CreateCredentialHandler),
+		rest.Post("/projects/:id/credentials/generate-ssh", s.Projects.GenerateSshCredentialHandler),
+		rest.Post("/projects/:id/credentials/import", s.Projects.ImportCredentialsHandler),
 		rest.Delete("/projects/:id/credentials/:cid", s.Projects.DeleteCredentialHandler),
 		rest.Put("/projects/:id/credentials/:cid", s.Projects.UpdateCredentialHandler),
+		rest.Get("/projects/:id/credentials/expiring", s.Projects.GetExpiringCredentialsHandler),
+		rest.Post("/projects/:id/credentials/:cid/rotate", s.Projects.RotateCredentialHandler),
+		rest.Post("/projects/:id/credentials/:cid/copy", s.Projects.CopyCredentialHandler),
 		rest.Get("/projects/:id/credentials/:cid", s.Projects.GetCredentialHandler),
 		rest.Get("/projects/:id/credentials", s.Projects.GetCredentialsHandler),
+		rest.Get("/projects/:id/credential-domains", s.Projects.GetCredentialDomainsHandler),
+		rest.Post("/projects/:id/credential-domains", s.Projects.CreateCredentialDomainHandler),
+		rest.Get("/projects/:id/credential-domains/:did", s.Projects.GetCredentialDomainHandler),
+		rest.Put("/projects/:id/credential-domains/:did", s.Projects.UpdateCredentialDomainHandler),
+		rest.Delete("/projects/:id/credential-domains/:did", s.Projects.DeleteCredentialDomainHandler),
 		rest.Get("/projects/:id/pipelines/:pid/config", s.Projects.GetPipelineHandler),
 		rest.Post("/projects/:id/pipelines", s.Projects.CreatePipelineHandler),
 		rest.Put("/projects/:id/pipelines/:pid", s.Projects.UpdatePipelineHandler),
 		rest.Delete("/projects/:id/pipelines/:pid", s.Projects.DeletePipelineHandler),
 		rest.Get("/projects/:id/pipelines/:pid/scm", s.Projects.GetPipelineScmHandler),
+		rest.Post("/projects/:id/pipelines/:pid/run", s.Projects.RunPipelineHandler),
+		rest.Get("/projects/:id/pipelines/:pid/members", s.Projects.GetPipelineMembersHandler),
+		rest.Post("/projects/:id/pipelines/:pid/members", s.Projects.AddPipelineMemberHandler),
+		rest.Delete("/projects/:id/pipelines/:pid/members/:uid", s.Projects.DeletePipelineMemberHandler),
+		rest.Get("/projects/:id/quota", s.Projects.GetProjectQuotaHandler),
+		rest.Put("/projects/:id/quota", s.Projects.UpdateProjectQuotaHandler),
 		rest.Get("/projects/default_roles/", s.Projects.GetProjectDefaultRolesHandler),
+		rest.Post("/credentials/reconcile", s.Projects.ReconcileCredentialsHandler),
+		rest.Post("/tokens", s.Projects.CreateApiTokenHandler),
+		rest.Get("/tokens", s.Projects.GetApiTokensHandler),
+		rest.Delete("/tokens/:tid", s.Projects.DeleteApiTokenHandler),
+		rest.Post("/groups/sync", s.Projects.SyncGroupsHandler),
+		rest.Get("/groups", s.Projects.GetGroupsHandler),
+		rest.Post("/groups", s.Projects.CreateGroupHandler),
+		rest.Get("/groups/:gid", s.Projects.GetGroupHandler),
+		rest.Delete("/groups/:gid", s.Projects.DeleteGroupHandler),
+		rest.Post("/groups/:gid/members", s.Projects.AddGroupMemberHandler),
+		rest.Delete("/groups/:gid/members/:uid", s.Projects.DeleteGroupMemberHandler),
+		rest.Get("/operations/:id", s.Projects.GetOperationHandler),
+		rest.Get("/templates", s.Projects.GetProjectTemplatesHandler),
+		rest.Post("/templates", s.Projects.CreateProjectTemplateHandler),
+		rest.Get("/templates/:tid", s.Projects.GetProjectTemplateHandler),
+		rest.Put("/templates/:tid", s.Projects.UpdateProjectTemplateHandler),
+		rest.Delete("/templates/:tid", s.Projects.DeleteProjectTemplateHandler),
+		rest.Get("/admin/impersonations", s.Projects.GetImpersonationLogsHandler),
+		rest.Get("/admin/roles", s.Projects.GetGlobalRolesHandler),
+		rest.Post("/admin/roles/:role/users", s.Projects.AddGlobalRoleUserHandler),
+		rest.Delete("/admin/roles/:role/users/:uid", s.Projects.DeleteGlobalRoleUserHandler),
 		rest.Get("/projects/:id/pipelines/:pid/sonarStatus", s.Projects.GetPipelineSonarHandler),
 		rest.Get("/projects/:id/pipelines/:pid/branches/:bid/sonarStatus", s.Projects.GetMultiBranchPipelineSonarHandler))
 
@@ -34,9 +34,21 @@ const APIVersion = "/api/v1alpha"
 
 func Serve(cfg *config.Config) {
 
+	authMiddleware, err := NewAuthMiddleware(&cfg.Auth)
+	if err != nil {
+		logger.Critical("failed to create auth middleware, %+v", err)
+		return
+	}
+
 	s := Server{}
 	s.Ds = ds.NewDs(cfg)
-	s.Projects = &projects.ProjectService{Ds: s.Ds}
+	s.Projects = &projects.ProjectService{Ds: s.Ds, Rbac: cfg.Rbac, Ldap: cfg.Ldap, Trash: cfg.Trash}
+
+	// the all-users jenkins project role is reset when connecting jenkins
+	err = s.Projects.SyncJenkinsPublicRole()
+	if err != nil {
+		logger.Error("failed to sync jenkins public project role, %+v", err)
+	}
 
 	// func to connect jenkins solve https://issues.jenkins-ci.org/browse/JENKINS-2489
 	go func() {
@@ -46,9 +58,48 @@ func Serve(cfg *config.Config) {
 		}
 	}()
 
+	go func() {
+		for {
+			time.Sleep(projects.CredentialReconcileInterval)
+			_, err := s.Projects.ReconcileCredentials(nil, false)
+			if err != nil {
+				logger.Error("failed to reconcile credentials, %+v", err)
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			time.Sleep(projects.ProjectPurgeInterval)
+			_, err := s.Projects.PurgeExpiredProjects()
+			if err != nil {
+				logger.Error("failed to purge projects, %+v", err)
+			}
+		}
+	}()
+
+	if cfg.Ldap.Address != "" {
+		go func() {
+			for {
+				_, err := s.Projects.SyncLdapGroups()
+				if err != nil {
+					logger.Error("failed to sync ldap groups, %+v", err)
+				}
+				time.Sleep(projects.GroupSyncInterval)
+			}
+		}()
+	}
+
+	app := Router(&s)
+	operationPool := NewOperationPool(s.Projects, app.AppFunc(), cfg.Operation.Workers)
+	operationPool.Start()
+
 	api := rest.NewApi()
 	api.Use(rest.DefaultDevStack...)
-	api.SetApp(Router(&s))
+	api.Use(&ApiTokenAuthMiddleware{Authenticator: s.Projects}, authMiddleware,
+		&ImpersonationMiddleware{Auditor: s.Projects, AllowWrites: cfg.Auth.AllowImpersonatedWrites},
+		&OperationMiddleware{Pool: operationPool})
+	api.SetApp(app)
 	http.Handle(APIVersion+"/", http.StripPrefix(APIVersion, api.MakeHandler()))
 	logger.Critical("%+v", http.ListenAndServe(":8080", nil))
 }
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cryptoutils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+const (
+	// PassphraseAlgorithm names the encryption of EncryptWithPassphrase.
+	PassphraseAlgorithm  = "pbkdf2-sha256-aes-256-gcm"
+	PassphraseIterations = 100000
+
+	passphraseSaltSize = 16
+	passphraseKeySize  = 32
+)
+
+// pbkdf2 derives a key as described in RFC 8018 with HMAC as pseudorandom function.
+func pbkdf2(password, salt []byte, iterations, keySize int, newHash func() hash.Hash) []byte {
+	prf := hmac.New(newHash, password)
+	hashSize := prf.Size()
+	blocks := (keySize + hashSize - 1) / hashSize
+	key := make([]byte, 0, blocks*hashSize)
+	buf := make([]byte, 4)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf, uint32(block))
+		prf.Write(buf)
+		u := prf.Sum(nil)
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keySize]
+}
+
+func newPassphraseCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2([]byte(passphrase), salt, PassphraseIterations, passphraseKeySize, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptWithPassphrase encrypts plaintext with a key derived from passphrase,
+// the result is the base64 encoded salt, nonce and sealed text.
+func EncryptWithPassphrase(plaintext []byte, passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase is required")
+	}
+	salt := make([]byte, passphraseSaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newPassphraseCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := append(salt, nonce...)
+	sealed = aead.Seal(sealed, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptWithPassphrase opens a text encrypted by EncryptWithPassphrase,
+// a wrong passphrase fails the authentication of the text.
+func DecryptWithPassphrase(ciphertext, passphrase string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted text: %v", err)
+	}
+	if len(sealed) < passphraseSaltSize {
+		return nil, fmt.Errorf("invalid encrypted text")
+	}
+	aead, err := newPassphraseCipher(passphrase, sealed[:passphraseSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	sealed = sealed[passphraseSaltSize:]
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted text")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt, the passphrase may be wrong")
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cryptoutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestPbkdf2(t *testing.T) {
+	// test vector of RFC 7914
+	expected := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+		"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	key := hex.EncodeToString(pbkdf2([]byte("passwd"), []byte("salt"), 1, 64, sha256.New))
+	if key != expected {
+		t.Fatalf("key should be %s, got %s", expected, key)
+	}
+}
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	ciphertext, err := EncryptWithPassphrase([]byte("secret"), "passphrase")
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	plaintext, err := DecryptWithPassphrase(ciphertext, "passphrase")
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if string(plaintext) != "secret" {
+		t.Fatalf("plaintext should be secret, got %s", plaintext)
+	}
+	_, err = DecryptWithPassphrase(ciphertext, "wrong")
+	if err == nil {
+		t.Fatalf("wrong passphrase should get error")
+	}
+	_, err = EncryptWithPassphrase([]byte("secret"), "")
+	if err == nil {
+		t.Fatalf("empty passphrase should get error")
+	}
+}
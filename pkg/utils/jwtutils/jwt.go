@@ -0,0 +1,240 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwtutils
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrTokenExpired     = errors.New("token is expired")
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
+)
+
+var hashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256,
+	"HS384": crypto.SHA384,
+	"HS512": crypto.SHA512,
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+type Claims map[string]interface{}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`
+}
+
+// Verifier verifies HMAC or RSA signed tokens, only the keys which are set are accepted.
+type Verifier struct {
+	HMACSecret []byte
+	// RSAKeys are indexed by key id, a key with empty id verifies tokens without kid
+	RSAKeys map[string]*rsa.PublicKey
+	// Issuer is checked against the iss claim if it is not empty
+	Issuer string
+	// Leeway is the allowed clock skew for exp and nbf
+	Leeway time.Duration
+}
+
+// Verify checks the signature, exp, nbf and iss of a compact serialized token and returns its claims.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	headerJson, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	h := &header{}
+	if err := json.Unmarshal(headerJson, h); err != nil {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	hash, ok := hashes[h.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported token algorithm [%s]", h.Algorithm)
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+	switch h.Algorithm[:2] {
+	case "HS":
+		if len(v.HMACSecret) == 0 {
+			return nil, fmt.Errorf("token algorithm [%s] is not accepted", h.Algorithm)
+		}
+		mac := hmac.New(hash.New, v.HMACSecret)
+		mac.Write(signingInput)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, ErrInvalidSignature
+		}
+	case "RS":
+		key := v.rsaKey(h.KeyId)
+		if key == nil {
+			return nil, fmt.Errorf("no rsa key found for token kid [%s]", h.KeyId)
+		}
+		hasher := hash.New()
+		hasher.Write(signingInput)
+		if err := rsa.VerifyPKCS1v15(key, hash, hasher.Sum(nil), signature); err != nil {
+			return nil, ErrInvalidSignature
+		}
+	}
+
+	claimsJson, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := Claims{}
+	decoder := json.NewDecoder(strings.NewReader(string(claimsJson)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := v.validate(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// tokens without kid are accepted when there is only one key
+func (v *Verifier) rsaKey(keyId string) *rsa.PublicKey {
+	if key, ok := v.RSAKeys[keyId]; ok {
+		return key
+	}
+	if keyId == "" && len(v.RSAKeys) == 1 {
+		for _, key := range v.RSAKeys {
+			return key
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) validate(claims Claims, now time.Time) error {
+	if exp, ok := claims.time("exp"); ok && now.After(exp.Add(v.Leeway)) {
+		return ErrTokenExpired
+	}
+	if nbf, ok := claims.time("nbf"); ok && now.Add(v.Leeway).Before(nbf) {
+		return ErrTokenNotValidYet
+	}
+	if v.Issuer != "" && claims.String("iss") != v.Issuer {
+		return fmt.Errorf("token issuer [%s] is not accepted", claims.String("iss"))
+	}
+	return nil
+}
+
+// String returns a string claim, other types are ignored.
+func (c Claims) String(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+func (c Claims) time(name string) (time.Time, bool) {
+	number, ok := c[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// ParseRSAPublicKey parses a PEM encoded PKIX or PKCS#1 public key or a certificate.
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode pem block of public key")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var certificate *x509.Certificate
+		certificate, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = certificate.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public key type [%s]", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported public key %T", key)
+	}
+	return rsaKey, nil
+}
+
+// ParseJWKS parses the RSA signing keys of a JSON Web Key Set, other keys are skipped.
+func ParseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	jwks := &struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyId   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key [%s]", jwk.KeyId)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key [%s]", jwk.KeyId)
+		}
+		keys[jwk.KeyId] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no rsa signing key found in jwks")
+	}
+	return keys, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwtutils
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func sign(t *testing.T, header, claims map[string]interface{}, key interface{}) string {
+	headerJson, _ := json.Marshal(header)
+	claimsJson, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJson) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJson)
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	verifier := &Verifier{
+		HMACSecret: secret,
+		RSAKeys:    map[string]*rsa.PublicKey{"key-1": &rsaKey.PublicKey},
+		Issuer:     "kubesphere",
+	}
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"username": "admin", "iss": "kubesphere", "exp": now + 60}
+
+	for name, test := range map[string]struct {
+		token string
+		err   bool
+	}{
+		"hmac":         {sign(t, map[string]interface{}{"alg": "HS256"}, valid, secret), false},
+		"rsa with kid": {sign(t, map[string]interface{}{"alg": "RS256", "kid": "key-1"}, valid, rsaKey), false},
+		// the only rsa key is used if there is no kid
+		"rsa without kid": {sign(t, map[string]interface{}{"alg": "RS256"}, valid, rsaKey), false},
+		"wrong secret":    {sign(t, map[string]interface{}{"alg": "HS256"}, valid, []byte("guess")), true},
+		"wrong rsa key":   {sign(t, map[string]interface{}{"alg": "RS256", "kid": "key-1"}, valid, otherKey), true},
+		"unknown kid":     {sign(t, map[string]interface{}{"alg": "RS256", "kid": "key-2"}, valid, rsaKey), true},
+		"none algorithm":  {sign(t, map[string]interface{}{"alg": "none"}, valid, nil), true},
+		"expired": {sign(t, map[string]interface{}{"alg": "HS256"},
+			map[string]interface{}{"username": "admin", "iss": "kubesphere", "exp": now - 60}, secret), true},
+		"not valid yet": {sign(t, map[string]interface{}{"alg": "HS256"},
+			map[string]interface{}{"username": "admin", "iss": "kubesphere", "nbf": now + 60}, secret), true},
+		"wrong issuer": {sign(t, map[string]interface{}{"alg": "HS256"},
+			map[string]interface{}{"username": "admin", "iss": "other"}, secret), true},
+		"malformed": {"a.b", true},
+	} {
+		claims, err := verifier.Verify(test.token)
+		if test.err {
+			if err == nil {
+				t.Fatalf("%s: should get error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: should not get error %+v", name, err)
+		}
+		if claims.String("username") != "admin" {
+			t.Fatalf("%s: username should be admin, got %s", name, claims.String("username"))
+		}
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	jwks := fmt.Sprintf(`{"keys":[
+{"kty":"EC","kid":"ec","crv":"P-256"},
+{"kty":"RSA","kid":"rsa","use":"sig","n":"%s","e":"%s"}]}`,
+		base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()))
+	keys, err := ParseJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if len(keys) != 1 || keys["rsa"] == nil || keys["rsa"].N.Cmp(rsaKey.N) != 0 || keys["rsa"].E != rsaKey.E {
+		t.Fatalf("should get the rsa key, got %+v", keys)
+	}
+}
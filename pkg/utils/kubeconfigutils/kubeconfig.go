@@ -0,0 +1,347 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kubeconfigutils parses kubeconfig credentials without client-go. Config only mirrors
+// the subset of clientcmd/api/v1.Config used by the validation and the summary, it is not a
+// replacement of clientcmd:
+//   - only a single yaml or json document is read, there is no merging, no KUBECONFIG list
+//     and no environment expansion.
+//   - unknown fields are ignored, so preferences, extensions, proxy-url, tls-server-name and
+//     the impersonation fields (as, as-groups, as-user-extra) are neither validated nor reported.
+//   - references to local files (certificate-authority, client-certificate, client-key, tokenFile)
+//     are rejected because a credential must be self-contained.
+//   - exec and auth-provider are only detected as auth types, their content is not validated.
+package kubeconfigutils
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	AuthTypeClientCertificate = "client-certificate"
+	AuthTypeToken             = "token"
+	AuthTypeBasicAuth         = "basic-auth"
+	AuthTypeExec              = "exec"
+	AuthTypeAuthProvider      = "auth-provider"
+	AuthTypeNone              = "none"
+)
+
+// Config mirrors k8s.io/client-go/tools/clientcmd/api/v1.Config
+type Config struct {
+	ApiVersion     string         `yaml:"apiVersion"`
+	Kind           string         `yaml:"kind"`
+	Clusters       []NamedCluster `yaml:"clusters"`
+	Contexts       []NamedContext `yaml:"contexts"`
+	Users          []NamedUser    `yaml:"users"`
+	CurrentContext string         `yaml:"current-context"`
+}
+
+type NamedCluster struct {
+	Name    string  `yaml:"name"`
+	Cluster Cluster `yaml:"cluster"`
+}
+
+type Cluster struct {
+	Server                   string `yaml:"server"`
+	InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+	CertificateAuthority     string `yaml:"certificate-authority"`
+	CertificateAuthorityData string `yaml:"certificate-authority-data"`
+}
+
+type NamedContext struct {
+	Name    string  `yaml:"name"`
+	Context Context `yaml:"context"`
+}
+
+type Context struct {
+	Cluster   string `yaml:"cluster"`
+	User      string `yaml:"user"`
+	Namespace string `yaml:"namespace"`
+}
+
+type NamedUser struct {
+	Name string   `yaml:"name"`
+	User AuthInfo `yaml:"user"`
+}
+
+type AuthInfo struct {
+	ClientCertificate     string                 `yaml:"client-certificate"`
+	ClientCertificateData string                 `yaml:"client-certificate-data"`
+	ClientKey             string                 `yaml:"client-key"`
+	ClientKeyData         string                 `yaml:"client-key-data"`
+	Token                 string                 `yaml:"token"`
+	TokenFile             string                 `yaml:"tokenFile"`
+	Username              string                 `yaml:"username"`
+	Password              string                 `yaml:"password"`
+	AuthProvider          map[string]interface{} `yaml:"auth-provider"`
+	Exec                  map[string]interface{} `yaml:"exec"`
+}
+
+// Info is the secret free summary of a kubeconfig.
+type Info struct {
+	CurrentContext string         `json:"current_context,omitempty"`
+	Clusters       []*ClusterInfo `json:"clusters"`
+	Contexts       []*ContextInfo `json:"contexts"`
+	Users          []*UserInfo    `json:"users"`
+	Warnings       []string       `json:"warnings,omitempty"`
+}
+
+type ClusterInfo struct {
+	Name                  string           `json:"name"`
+	Server                string           `json:"server"`
+	InsecureSkipTLSVerify bool             `json:"insecure_skip_tls_verify,omitempty"`
+	CertificateAuthority  *CertificateInfo `json:"certificate_authority,omitempty"`
+}
+
+type ContextInfo struct {
+	Name      string `json:"name"`
+	Cluster   string `json:"cluster"`
+	User      string `json:"user"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type UserInfo struct {
+	Name        string           `json:"name"`
+	AuthType    string           `json:"auth_type"`
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
+}
+
+// CertificateInfo describes a x509 certificate embedded in a kubeconfig.
+type CertificateInfo struct {
+	Subject     string    `json:"subject"`
+	Fingerprint string    `json:"fingerprint"`
+	Algorithm   string    `json:"algorithm"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+func Load(content string) (*Config, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, errors.New("kubeconfig is empty")
+	}
+	config := &Config{}
+	err := yaml.Unmarshal([]byte(content), config)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ParseCertificateData decodes base64 encoded PEM data such as client-certificate-data.
+func ParseCertificateData(data string) (*CertificateInfo, error) {
+	pemBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("failed to decode pem block of certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(cert.Raw)
+	return &CertificateInfo{
+		Subject:     cert.Subject.String(),
+		Fingerprint: fmt.Sprintf("SHA256:%s", base64.RawStdEncoding.EncodeToString(sum[:])),
+		Algorithm:   cert.PublicKeyAlgorithm.String(),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+	}, nil
+}
+
+func authType(user *AuthInfo) string {
+	switch {
+	case user.ClientCertificateData != "":
+		return AuthTypeClientCertificate
+	case user.Token != "":
+		return AuthTypeToken
+	case user.Username != "" || user.Password != "":
+		return AuthTypeBasicAuth
+	case len(user.Exec) > 0:
+		return AuthTypeExec
+	case len(user.AuthProvider) > 0:
+		return AuthTypeAuthProvider
+	}
+	return AuthTypeNone
+}
+
+// Validate follows the rules of clientcmd.Validate, a kubeconfig stored as credential
+// must be self-contained so references to local files are rejected as well.
+func (c *Config) Validate() error {
+	if len(c.Clusters) == 0 {
+		return errors.New("invalid kubeconfig: no clusters")
+	}
+	if len(c.Contexts) == 0 {
+		return errors.New("invalid kubeconfig: no contexts")
+	}
+	clusters := make(map[string]bool)
+	for _, cluster := range c.Clusters {
+		if cluster.Name == "" || clusters[cluster.Name] {
+			return fmt.Errorf("invalid kubeconfig: cluster name [%s] is empty or duplicated", cluster.Name)
+		}
+		clusters[cluster.Name] = true
+		server, err := url.Parse(cluster.Cluster.Server)
+		if err != nil || (server.Scheme != "http" && server.Scheme != "https") || server.Host == "" {
+			return fmt.Errorf("invalid kubeconfig: cluster [%s] has invalid server [%s]", cluster.Name, cluster.Cluster.Server)
+		}
+		if cluster.Cluster.CertificateAuthority != "" {
+			return fmt.Errorf("invalid kubeconfig: cluster [%s] refers to local file, use certificate-authority-data", cluster.Name)
+		}
+		if cluster.Cluster.CertificateAuthorityData != "" {
+			if _, err := ParseCertificateData(cluster.Cluster.CertificateAuthorityData); err != nil {
+				return fmt.Errorf("invalid kubeconfig: cluster [%s] certificate-authority-data: %v", cluster.Name, err)
+			}
+		}
+	}
+	users := make(map[string]bool)
+	for _, user := range c.Users {
+		if user.Name == "" || users[user.Name] {
+			return fmt.Errorf("invalid kubeconfig: user name [%s] is empty or duplicated", user.Name)
+		}
+		users[user.Name] = true
+		auth := user.User
+		if auth.ClientCertificate != "" || auth.ClientKey != "" || auth.TokenFile != "" {
+			return fmt.Errorf("invalid kubeconfig: user [%s] refers to local file, use embedded data", user.Name)
+		}
+		if (auth.ClientCertificateData == "") != (auth.ClientKeyData == "") {
+			return fmt.Errorf("invalid kubeconfig: user [%s] needs both client-certificate-data and client-key-data", user.Name)
+		}
+		if auth.ClientCertificateData != "" {
+			if _, err := ParseCertificateData(auth.ClientCertificateData); err != nil {
+				return fmt.Errorf("invalid kubeconfig: user [%s] client-certificate-data: %v", user.Name, err)
+			}
+		}
+		if auth.Token != "" && (auth.Username != "" || auth.Password != "") {
+			return fmt.Errorf("invalid kubeconfig: user [%s] has more than one authentication method", user.Name)
+		}
+	}
+	contexts := make(map[string]bool)
+	for _, context := range c.Contexts {
+		if context.Name == "" || contexts[context.Name] {
+			return fmt.Errorf("invalid kubeconfig: context name [%s] is empty or duplicated", context.Name)
+		}
+		contexts[context.Name] = true
+		if !clusters[context.Context.Cluster] {
+			return fmt.Errorf("invalid kubeconfig: cluster [%s] was not found for context [%s]", context.Context.Cluster, context.Name)
+		}
+		if !users[context.Context.User] {
+			return fmt.Errorf("invalid kubeconfig: user [%s] was not found for context [%s]", context.Context.User, context.Name)
+		}
+	}
+	if c.CurrentContext != "" && !contexts[c.CurrentContext] {
+		return fmt.Errorf("invalid kubeconfig: current-context [%s] was not found", c.CurrentContext)
+	}
+	return nil
+}
+
+// Inspect summarizes a validated kubeconfig, with check the offline security check
+// reports embedded tokens, basic auth passwords, insecure tls and expired certificates.
+func (c *Config) Inspect(check bool) (*Info, error) {
+	info := &Info{
+		CurrentContext: c.CurrentContext,
+		Clusters:       make([]*ClusterInfo, 0),
+		Contexts:       make([]*ContextInfo, 0),
+		Users:          make([]*UserInfo, 0),
+	}
+	now := time.Now()
+	warn := func(format string, args ...interface{}) {
+		if check {
+			info.Warnings = append(info.Warnings, fmt.Sprintf(format, args...))
+		}
+	}
+	for _, cluster := range c.Clusters {
+		clusterInfo := &ClusterInfo{
+			Name:                  cluster.Name,
+			Server:                cluster.Cluster.Server,
+			InsecureSkipTLSVerify: cluster.Cluster.InsecureSkipTLSVerify,
+		}
+		if cluster.Cluster.CertificateAuthorityData != "" {
+			cert, err := ParseCertificateData(cluster.Cluster.CertificateAuthorityData)
+			if err != nil {
+				return nil, err
+			}
+			clusterInfo.CertificateAuthority = cert
+			if cert.NotAfter.Before(now) {
+				warn("cluster [%s] certificate authority expired at %s", cluster.Name, cert.NotAfter)
+			}
+		}
+		if cluster.Cluster.InsecureSkipTLSVerify {
+			warn("cluster [%s] skips tls verification", cluster.Name)
+		}
+		if strings.HasPrefix(cluster.Cluster.Server, "http://") {
+			warn("cluster [%s] server is not using tls", cluster.Name)
+		}
+		info.Clusters = append(info.Clusters, clusterInfo)
+	}
+	for _, context := range c.Contexts {
+		info.Contexts = append(info.Contexts, &ContextInfo{
+			Name:      context.Name,
+			Cluster:   context.Context.Cluster,
+			User:      context.Context.User,
+			Namespace: context.Context.Namespace,
+		})
+	}
+	for _, user := range c.Users {
+		userInfo := &UserInfo{
+			Name:     user.Name,
+			AuthType: authType(&user.User),
+		}
+		if user.User.ClientCertificateData != "" {
+			cert, err := ParseCertificateData(user.User.ClientCertificateData)
+			if err != nil {
+				return nil, err
+			}
+			userInfo.Certificate = cert
+			if cert.NotAfter.Before(now) {
+				warn("user [%s] client certificate expired at %s", user.Name, cert.NotAfter)
+			}
+		}
+		if user.User.Token != "" {
+			warn("user [%s] embeds a bearer token", user.Name)
+		}
+		if user.User.Password != "" {
+			warn("user [%s] embeds a basic auth password", user.Name)
+		}
+		info.Users = append(info.Users, userInfo)
+	}
+	if c.CurrentContext == "" {
+		warn("current-context is not set")
+	}
+	return info, nil
+}
+
+// EarliestCertificate returns the client certificate which expires first.
+func (i *Info) EarliestCertificate() *CertificateInfo {
+	var earliest *CertificateInfo
+	for _, user := range i.Users {
+		if user.Certificate == nil {
+			continue
+		}
+		if earliest == nil || user.Certificate.NotAfter.Before(earliest.NotAfter) {
+			earliest = user.Certificate
+		}
+	}
+	return earliest
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfigutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCertificateData(t *testing.T, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "admin"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: local
+  cluster:
+    server: https://127.0.0.1:6443
+    insecure-skip-tls-verify: true
+contexts:
+- name: admin@local
+  context:
+    cluster: local
+    user: admin
+current-context: admin@local
+users:
+- name: admin
+  user:
+    client-certificate-data: %s
+    client-key-data: a2V5
+- name: robot
+  user:
+    token: abc
+`
+
+func TestConfig_Inspect(t *testing.T) {
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second).UTC()
+	content := fmt.Sprintf(testKubeconfig, newTestCertificateData(t, notAfter))
+
+	config, err := Load(content)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	err = config.Validate()
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	info, err := config.Inspect(false)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if len(info.Clusters) != 1 || len(info.Contexts) != 1 || len(info.Users) != 2 {
+		t.Fatalf("unexpected kubeconfig info %+v", info)
+	}
+	if len(info.Warnings) != 0 {
+		t.Fatalf("should not get warnings without check, got %s", info.Warnings)
+	}
+	certificate := info.EarliestCertificate()
+	if certificate == nil || !certificate.NotAfter.Equal(notAfter) {
+		t.Fatalf("certificate of admin should expire at %s", notAfter)
+	}
+	if certificate.Algorithm != "ECDSA" {
+		t.Fatalf("algorithm [%s] should be ECDSA", certificate.Algorithm)
+	}
+	if info.Users[1].AuthType != AuthTypeToken {
+		t.Fatalf("auth type [%s] should be %s", info.Users[1].AuthType, AuthTypeToken)
+	}
+
+	info, err = config.Inspect(true)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if len(info.Warnings) != 2 {
+		t.Fatalf("should get insecure tls and token warnings, got %s", info.Warnings)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	certificateData := newTestCertificateData(t, time.Now().Add(time.Hour))
+	inputs := []string{
+		"clusters: []",
+		strings.Replace(fmt.Sprintf(testKubeconfig, certificateData), "https://127.0.0.1:6443", "127.0.0.1", 1),
+		strings.Replace(fmt.Sprintf(testKubeconfig, certificateData), "user: admin", "user: nobody", 1),
+		strings.Replace(fmt.Sprintf(testKubeconfig, certificateData), "current-context: admin@local", "current-context: other", 1),
+		strings.Replace(fmt.Sprintf(testKubeconfig, certificateData), "    client-key-data: a2V5\n", "", 1),
+		strings.Replace(fmt.Sprintf(testKubeconfig, certificateData), "token: abc", "tokenFile: /var/run/token", 1),
+		fmt.Sprintf(testKubeconfig, "bm90IGEgY2VydGlmaWNhdGU="),
+	}
+	for _, input := range inputs {
+		config, err := Load(input)
+		if err != nil {
+			continue
+		}
+		if err := config.Validate(); err == nil {
+			t.Fatalf("kubeconfig [%s] should be invalid", input)
+		}
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for _, input := range []string{"   ", "not a kubeconfig", "clusters: {"} {
+		_, err := Load(input)
+		if err == nil {
+			t.Fatalf("input [%s] should get error", input)
+		}
+	}
+}
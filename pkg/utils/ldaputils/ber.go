@@ -0,0 +1,207 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ldaputils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// the subset of BER used by LDAP, see RFC 4511 section 5.1
+const (
+	classUniversal   = 0x00
+	classApplication = 0x40
+	classContext     = 0x80
+
+	typeConstructed = 0x20
+
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x10
+	tagSet         = 0x11
+
+	maxPacketLength = 16 << 20
+)
+
+type packet struct {
+	class       byte
+	constructed bool
+	tag         byte
+	value       []byte
+	children    []*packet
+}
+
+func newSequence(children ...*packet) *packet {
+	return &packet{class: classUniversal, constructed: true, tag: tagSequence, children: children}
+}
+
+func newConstructed(class, tag byte, children ...*packet) *packet {
+	return &packet{class: class, constructed: true, tag: tag, children: children}
+}
+
+func newOctetString(value string) *packet {
+	return &packet{class: classUniversal, tag: tagOctetString, value: []byte(value)}
+}
+
+func newPrimitive(class, tag byte, value string) *packet {
+	return &packet{class: class, tag: tag, value: []byte(value)}
+}
+
+func newInteger(tag byte, value int64) *packet {
+	return &packet{class: classUniversal, tag: tag, value: encodeInteger(value)}
+}
+
+func newBoolean(value bool) *packet {
+	if value {
+		return &packet{class: classUniversal, tag: tagBoolean, value: []byte{0xff}}
+	}
+	return &packet{class: classUniversal, tag: tagBoolean, value: []byte{0x00}}
+}
+
+// encodeInteger returns the minimal two's complement encoding
+func encodeInteger(value int64) []byte {
+	n := 1
+	for v := value; v > 127 || v < -128; v >>= 8 {
+		n++
+	}
+	encoded := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		encoded[i] = byte(value)
+		value >>= 8
+	}
+	return encoded
+}
+
+func (p *packet) is(class, tag byte) bool {
+	return p.class == class && p.tag == tag
+}
+
+func (p *packet) int() (int64, error) {
+	if len(p.value) == 0 || len(p.value) > 8 {
+		return 0, fmt.Errorf("invalid integer length %d", len(p.value))
+	}
+	value := int64(int8(p.value[0]))
+	for _, b := range p.value[1:] {
+		value = value<<8 | int64(b)
+	}
+	return value, nil
+}
+
+func (p *packet) string() string {
+	return string(p.value)
+}
+
+func (p *packet) encode() []byte {
+	content := p.value
+	if p.constructed {
+		content = make([]byte, 0)
+		for _, child := range p.children {
+			content = append(content, child.encode()...)
+		}
+	}
+	identifier := p.class | p.tag
+	if p.constructed {
+		identifier |= typeConstructed
+	}
+	encoded := []byte{identifier}
+	encoded = append(encoded, encodeLength(len(content))...)
+	return append(encoded, content...)
+}
+
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	lengthBytes := make([]byte, 0)
+	for l := length; l > 0; l >>= 8 {
+		lengthBytes = append([]byte{byte(l)}, lengthBytes...)
+	}
+	return append([]byte{0x80 | byte(len(lengthBytes))}, lengthBytes...)
+}
+
+func readPacket(reader *bufio.Reader) (*packet, error) {
+	identifier, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if identifier&0x1f == 0x1f {
+		return nil, fmt.Errorf("unsupported high tag number")
+	}
+	length, err := readLength(reader)
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	_, err = io.ReadFull(reader, content)
+	if err != nil {
+		return nil, err
+	}
+	return parsePacket(identifier, content)
+}
+
+func readLength(reader *bufio.Reader) (int, error) {
+	first, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first < 0x80 {
+		return int(first), nil
+	}
+	n := int(first & 0x7f)
+	if n == 0 || n > 4 {
+		return 0, fmt.Errorf("unsupported length of %d bytes", n)
+	}
+	length := 0
+	for i := 0; i < n; i++ {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	if length > maxPacketLength {
+		return 0, fmt.Errorf("packet length %d exceeds the limit", length)
+	}
+	return length, nil
+}
+
+func parsePacket(identifier byte, content []byte) (*packet, error) {
+	p := &packet{
+		class:       identifier & 0xc0,
+		constructed: identifier&typeConstructed != 0,
+		tag:         identifier & 0x1f,
+	}
+	if !p.constructed {
+		p.value = content
+		return p, nil
+	}
+	reader := bufio.NewReader(bytes.NewReader(content))
+	for {
+		child, err := readPacket(reader)
+		if err == io.EOF {
+			return p, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+	}
+}
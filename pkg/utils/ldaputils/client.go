@@ -0,0 +1,290 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ldaputils
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPort    = "389"
+	DefaultTLSPort = "636"
+
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+
+	ResultSuccess = 0
+)
+
+// protocol operations of RFC 4511 section 4.2
+const (
+	opBindRequest           = 0
+	opBindResponse          = 1
+	opUnbindRequest         = 2
+	opSearchRequest         = 3
+	opSearchResultEntry     = 4
+	opSearchResultDone      = 5
+	opSearchResultReference = 19
+)
+
+type Error struct {
+	ResultCode int64
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ldap result code %d, %s", e.ResultCode, e.Message)
+}
+
+// Conn is a minimal ldap v3 client which supports simple bind and search.
+type Conn struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	messageId int64
+	timeout   time.Duration
+}
+
+type SearchRequest struct {
+	BaseDN     string
+	Scope      int
+	Filter     string
+	Attributes []string
+	SizeLimit  int
+}
+
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// GetAttributeValues returns the values of an attribute, attribute names are case insensitive.
+func (e *Entry) GetAttributeValues(name string) []string {
+	for attribute, values := range e.Attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func (e *Entry) GetAttributeValue(name string) string {
+	values := e.GetAttributeValues(name)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Dial connects to an address like ldap://host:389 or ldaps://host:636.
+func Dial(address string, timeout time.Duration) (*Conn, error) {
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid ldap address [%s]", address)
+	}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), DefaultPort)
+		}
+		conn, err = net.DialTimeout("tcp", host, timeout)
+	case "ldaps":
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), DefaultTLSPort)
+		}
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", host,
+			&tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, fmt.Errorf("unsupported ldap scheme [%s]", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn, timeout), nil
+}
+
+func NewConn(conn net.Conn, timeout time.Duration) *Conn {
+	return &Conn{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+}
+
+func (c *Conn) send(op *packet) (int64, error) {
+	c.messageId++
+	message := newSequence(newInteger(tagInteger, c.messageId), op)
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	_, err := c.conn.Write(message.encode())
+	return c.messageId, err
+}
+
+func (c *Conn) receive(messageId int64) (*packet, error) {
+	message, err := readPacket(c.reader)
+	if err != nil {
+		return nil, err
+	}
+	if !message.is(classUniversal, tagSequence) || len(message.children) < 2 {
+		return nil, fmt.Errorf("invalid ldap message")
+	}
+	id, err := message.children[0].int()
+	if err != nil {
+		return nil, err
+	}
+	if id != messageId {
+		return nil, fmt.Errorf("unexpected ldap message id %d, expected %d", id, messageId)
+	}
+	return message.children[1], nil
+}
+
+func resultError(op *packet) error {
+	if len(op.children) < 3 {
+		return fmt.Errorf("invalid ldap result")
+	}
+	code, err := op.children[0].int()
+	if err != nil {
+		return err
+	}
+	if code != ResultSuccess {
+		return &Error{ResultCode: code, Message: op.children[2].string()}
+	}
+	return nil
+}
+
+// Bind authenticates with a simple bind, an empty dn binds anonymously.
+func (c *Conn) Bind(dn, password string) error {
+	messageId, err := c.send(newConstructed(classApplication, opBindRequest,
+		newInteger(tagInteger, 3),
+		newOctetString(dn),
+		newPrimitive(classContext, 0, password)))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(messageId)
+	if err != nil {
+		return err
+	}
+	if !op.is(classApplication, opBindResponse) {
+		return fmt.Errorf("unexpected ldap operation %d, expected bind response", op.tag)
+	}
+	return resultError(op)
+}
+
+// Search returns the entries of a search, referrals are ignored.
+func (c *Conn) Search(request *SearchRequest) ([]*Entry, error) {
+	filter, err := parseFilter(request.Filter)
+	if err != nil {
+		return nil, err
+	}
+	attributes := newSequence()
+	for _, attribute := range request.Attributes {
+		attributes.children = append(attributes.children, newOctetString(attribute))
+	}
+	messageId, err := c.send(newConstructed(classApplication, opSearchRequest,
+		newOctetString(request.BaseDN),
+		newInteger(tagEnumerated, int64(request.Scope)),
+		newInteger(tagEnumerated, 0),
+		newInteger(tagInteger, int64(request.SizeLimit)),
+		newInteger(tagInteger, 0),
+		newBoolean(false),
+		filter,
+		attributes))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, 0)
+	for {
+		op, err := c.receive(messageId)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case op.is(classApplication, opSearchResultEntry):
+			entry, err := parseEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case op.is(classApplication, opSearchResultReference):
+		case op.is(classApplication, opSearchResultDone):
+			return entries, resultError(op)
+		default:
+			return nil, fmt.Errorf("unexpected ldap operation %d in search", op.tag)
+		}
+	}
+}
+
+func parseEntry(op *packet) (*Entry, error) {
+	if len(op.children) < 2 {
+		return nil, fmt.Errorf("invalid ldap search result entry")
+	}
+	entry := &Entry{DN: op.children[0].string(), Attributes: make(map[string][]string)}
+	for _, attribute := range op.children[1].children {
+		if len(attribute.children) < 2 {
+			return nil, fmt.Errorf("invalid attribute of entry [%s]", entry.DN)
+		}
+		values := make([]string, 0)
+		for _, value := range attribute.children[1].children {
+			values = append(values, value.string())
+		}
+		entry.Attributes[attribute.children[0].string()] = values
+	}
+	return entry, nil
+}
+
+// Close sends an unbind request and closes the connection.
+func (c *Conn) Close() error {
+	c.send(newPrimitive(classApplication, opUnbindRequest, ""))
+	return c.conn.Close()
+}
+
+// GetRDNValue returns the value of the first relative distinguished name of a dn if its attribute
+// is the given attribute, a value which is not a dn is returned as is.
+func GetRDNValue(dn, attribute string) string {
+	if !strings.Contains(dn, "=") {
+		return dn
+	}
+	rdn := make([]byte, 0, len(dn))
+	for i := 0; i < len(dn); i++ {
+		if dn[i] == '\\' && i+1 < len(dn) {
+			rdn = append(rdn, dn[i], dn[i+1])
+			i++
+			continue
+		}
+		if dn[i] == ',' || dn[i] == '+' {
+			break
+		}
+		rdn = append(rdn, dn[i])
+	}
+	parts := strings.SplitN(string(rdn), "=", 2)
+	if !strings.EqualFold(strings.TrimSpace(parts[0]), attribute) {
+		return ""
+	}
+	value := strings.TrimSpace(parts[1])
+	unescaped := make([]byte, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		unescaped = append(unescaped, value[i])
+	}
+	return string(unescaped)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ldaputils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// filter choices of RFC 4511 section 4.5.1
+const (
+	filterAnd      = 0
+	filterOr       = 1
+	filterNot      = 2
+	filterEquality = 3
+	filterPresent  = 7
+)
+
+// parseFilter parses the string representation of RFC 4515,
+// only and, or, not, equality and presence filters are supported.
+func parseFilter(filter string) (*packet, error) {
+	p, rest, err := parseFilterComponent(strings.TrimSpace(filter))
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter [%s], %s", filter, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid filter [%s], unexpected [%s]", filter, rest)
+	}
+	return p, nil
+}
+
+func parseFilterComponent(filter string) (*packet, string, error) {
+	if !strings.HasPrefix(filter, "(") {
+		return nil, "", fmt.Errorf("filter should start with (")
+	}
+	filter = filter[1:]
+	if filter == "" {
+		return nil, "", fmt.Errorf("unexpected end of filter")
+	}
+	switch filter[0] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if filter[0] == '|' {
+			tag = filterOr
+		}
+		p := newConstructed(classContext, tag)
+		rest := filter[1:]
+		for strings.HasPrefix(rest, "(") {
+			child, childRest, err := parseFilterComponent(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			p.children = append(p.children, child)
+			rest = childRest
+		}
+		if len(p.children) == 0 {
+			return nil, "", fmt.Errorf("empty filter list")
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("filter list should end with )")
+		}
+		return p, rest[1:], nil
+	case '!':
+		child, rest, err := parseFilterComponent(filter[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("not filter should end with )")
+		}
+		return newConstructed(classContext, filterNot, child), rest[1:], nil
+	}
+	end := strings.Index(filter, ")")
+	if end < 0 {
+		return nil, "", fmt.Errorf("filter should end with )")
+	}
+	item, rest := filter[:end], filter[end+1:]
+	equal := strings.Index(item, "=")
+	if equal <= 0 {
+		return nil, "", fmt.Errorf("invalid filter item [%s]", item)
+	}
+	attribute, value := item[:equal], item[equal+1:]
+	if strings.ContainsAny(attribute[len(attribute)-1:], "<>~:") {
+		return nil, "", fmt.Errorf("unsupported filter item [%s]", item)
+	}
+	if value == "*" {
+		return newPrimitive(classContext, filterPresent, attribute), rest, nil
+	}
+	if strings.Contains(value, "*") {
+		return nil, "", fmt.Errorf("unsupported substring filter [%s]", item)
+	}
+	value, err := unescapeFilterValue(value)
+	if err != nil {
+		return nil, "", err
+	}
+	return newConstructed(classContext, filterEquality, newOctetString(attribute), newOctetString(value)), rest, nil
+}
+
+// unescapeFilterValue decodes the \XX escapes of a filter value
+func unescapeFilterValue(value string) (string, error) {
+	if !strings.Contains(value, "\\") {
+		return value, nil
+	}
+	unescaped := make([]byte, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			unescaped = append(unescaped, value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", fmt.Errorf("invalid escape in [%s]", value)
+		}
+		decoded, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in [%s]", value)
+		}
+		unescaped = append(unescaped, decoded...)
+		i += 2
+	}
+	return string(unescaped), nil
+}
+
+// EscapeFilterValue escapes the special characters of a filter value
+func EscapeFilterValue(value string) string {
+	escaped := make([]byte, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '*', '(', ')', '\\', 0:
+			escaped = append(escaped, []byte(fmt.Sprintf("\\%02x", value[i]))...)
+		default:
+			escaped = append(escaped, value[i])
+		}
+	}
+	return string(escaped)
+}
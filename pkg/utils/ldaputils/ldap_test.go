@@ -0,0 +1,214 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ldaputils
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncodeInteger(t *testing.T) {
+	for _, value := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 1 << 40} {
+		p := newInteger(tagInteger, value)
+		decoded, err := readPacket(bufio.NewReader(bytes.NewReader(p.encode())))
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+		got, err := decoded.int()
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+		if got != value {
+			t.Fatalf("integer %d should be decoded, got %d", value, got)
+		}
+	}
+}
+
+func TestEncodeLongPacket(t *testing.T) {
+	value := string(bytes.Repeat([]byte("a"), 70000))
+	p := newSequence(newOctetString(value), newOctetString("b"))
+	decoded, err := readPacket(bufio.NewReader(bytes.NewReader(p.encode())))
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if len(decoded.children) != 2 || decoded.children[0].string() != value || decoded.children[1].string() != "b" {
+		t.Fatalf("long packet should be decoded")
+	}
+	_, err = readPacket(bufio.NewReader(bytes.NewReader(p.encode()[:100])))
+	if err == nil {
+		t.Fatalf("truncated packet should get error")
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	p, err := parseFilter("(&(objectClass=groupOfNames)(!(cn=a\\2ab))(|(ou=*)(ou=dev)))")
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	expected := newConstructed(classContext, filterAnd,
+		newConstructed(classContext, filterEquality, newOctetString("objectClass"), newOctetString("groupOfNames")),
+		newConstructed(classContext, filterNot,
+			newConstructed(classContext, filterEquality, newOctetString("cn"), newOctetString("a*b"))),
+		newConstructed(classContext, filterOr,
+			newPrimitive(classContext, filterPresent, "ou"),
+			newConstructed(classContext, filterEquality, newOctetString("ou"), newOctetString("dev"))))
+	if !bytes.Equal(p.encode(), expected.encode()) {
+		t.Fatalf("filter should be encoded as %x, got %x", expected.encode(), p.encode())
+	}
+	for _, filter := range []string{"cn=a", "(cn=a", "(&)", "(cn=a*)", "(cn>=a)", "(cn=a)(cn=b)", "(cn=\\2)"} {
+		if _, err := parseFilter(filter); err == nil {
+			t.Fatalf("filter %s should be invalid", filter)
+		}
+	}
+	if escaped := EscapeFilterValue("a*(b)\\"); escaped != "a\\2a\\28b\\29\\5c" {
+		t.Fatalf("filter value should be escaped, got %s", escaped)
+	}
+}
+
+func TestGetRDNValue(t *testing.T) {
+	for _, test := range []struct {
+		dn        string
+		attribute string
+		expected  string
+	}{
+		{"uid=alice,ou=people,dc=example,dc=org", "uid", "alice"},
+		{"UID=alice,ou=people,dc=example,dc=org", "uid", "alice"},
+		{"cn=Smith\\, John,ou=people,dc=example,dc=org", "cn", "Smith, John"},
+		{"cn=alice,ou=people,dc=example,dc=org", "uid", ""},
+		{"bob", "uid", "bob"},
+	} {
+		if value := GetRDNValue(test.dn, test.attribute); value != test.expected {
+			t.Fatalf("value of %s in %s should be %s, got %s", test.attribute, test.dn, test.expected, value)
+		}
+	}
+}
+
+// serve answers a bind and a search like an ldap server
+func serve(t *testing.T, conn net.Conn, entries []*Entry) {
+	reader := bufio.NewReader(conn)
+	respond := func(messageId int64, op *packet) {
+		conn.Write(newSequence(newInteger(tagInteger, messageId), op).encode())
+	}
+	result := func(tag byte, code int64, message string) *packet {
+		return newConstructed(classApplication, tag,
+			newInteger(tagEnumerated, code), newOctetString(""), newOctetString(message))
+	}
+	for {
+		message, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		messageId, _ := message.children[0].int()
+		op := message.children[1]
+		switch {
+		case op.is(classApplication, opBindRequest):
+			if op.children[1].string() != "cn=admin,dc=example,dc=org" || op.children[2].string() != "secret" {
+				respond(messageId, result(opBindResponse, 49, "invalid credentials"))
+				continue
+			}
+			respond(messageId, result(opBindResponse, ResultSuccess, ""))
+		case op.is(classApplication, opSearchRequest):
+			for _, entry := range entries {
+				attributes := newSequence()
+				for name, values := range entry.Attributes {
+					set := &packet{class: classUniversal, constructed: true, tag: tagSet}
+					for _, value := range values {
+						set.children = append(set.children, newOctetString(value))
+					}
+					attributes.children = append(attributes.children, newSequence(newOctetString(name), set))
+				}
+				respond(messageId, newConstructed(classApplication, opSearchResultEntry,
+					newOctetString(entry.DN), attributes))
+			}
+			respond(messageId, result(opSearchResultDone, ResultSuccess, ""))
+		case op.is(classApplication, opUnbindRequest):
+			conn.Close()
+			return
+		}
+	}
+}
+
+func TestBindAndSearch(t *testing.T) {
+	entries := []*Entry{
+		{
+			DN: "cn=developers,ou=groups,dc=example,dc=org",
+			Attributes: map[string][]string{
+				"cn":     {"developers"},
+				"member": {"uid=alice,ou=people,dc=example,dc=org", "uid=bob,ou=people,dc=example,dc=org"},
+			},
+		},
+	}
+	client, server := net.Pipe()
+	go serve(t, server, entries)
+	conn := NewConn(client, 5*time.Second)
+	defer conn.Close()
+
+	err := conn.Bind("cn=admin,dc=example,dc=org", "wrong")
+	if ldapErr, ok := err.(*Error); !ok || ldapErr.ResultCode != 49 {
+		t.Fatalf("bind with wrong password should get result code 49, got %+v", err)
+	}
+	err = conn.Bind("cn=admin,dc=example,dc=org", "secret")
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	result, err := conn.Search(&SearchRequest{
+		BaseDN:     "dc=example,dc=org",
+		Scope:      ScopeWholeSubtree,
+		Filter:     "(objectClass=groupOfNames)",
+		Attributes: []string{"cn", "member"},
+	})
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if !reflect.DeepEqual(result, entries) {
+		t.Fatalf("search should return %+v, got %+v", entries, result)
+	}
+	if result[0].GetAttributeValue("CN") != "developers" {
+		t.Fatalf("attribute names should be case insensitive")
+	}
+}
+
+// TestLdapServer searches a real ldap server, e.g. a local openldap container, configured by
+// LDAP_TEST_ADDRESS, LDAP_TEST_BIND_DN, LDAP_TEST_BIND_PASSWORD and LDAP_TEST_BASE_DN.
+func TestLdapServer(t *testing.T) {
+	address := os.Getenv("LDAP_TEST_ADDRESS")
+	if address == "" {
+		t.Skip("LDAP_TEST_ADDRESS is not set")
+	}
+	conn, err := Dial(address, 10*time.Second)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	defer conn.Close()
+	err = conn.Bind(os.Getenv("LDAP_TEST_BIND_DN"), os.Getenv("LDAP_TEST_BIND_PASSWORD"))
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	entries, err := conn.Search(&SearchRequest{
+		BaseDN: os.Getenv("LDAP_TEST_BASE_DN"),
+		Scope:  ScopeWholeSubtree,
+		Filter: "(objectClass=*)",
+	})
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	if len(entries) == 0 {
+		t.Fatalf("search should return the base entry at least")
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sshutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+)
+
+const (
+	KeyTypeED25519 = "ed25519"
+	KeyTypeRSA     = "rsa"
+
+	DefaultRSAKeyBits = 4096
+	MinRSAKeyBits     = 2048
+	MaxRSAKeyBits     = 8192
+)
+
+// GenerateKey generates a private key of given type, bits is only used by rsa keys.
+func GenerateKey(keyType string, bits int) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeED25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	case KeyTypeRSA:
+		if bits == 0 {
+			bits = DefaultRSAKeyBits
+		}
+		if bits < MinRSAKeyBits || bits > MaxRSAKeyBits {
+			return nil, fmt.Errorf("rsa key bits should be between %d and %d", MinRSAKeyBits, MaxRSAKeyBits)
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	default:
+		return nil, fmt.Errorf("unsupported key type [%s]", keyType)
+	}
+}
+
+// MarshalPrivateKey encodes an unencrypted private key to PEM,
+// rsa keys use PKCS#1 and ed25519 keys use the openssh-key-v1 format.
+func MarshalPrivateKey(key crypto.Signer, comment string) ([]byte, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  rsaPrivateKeyPEM,
+			Bytes: x509.MarshalPKCS1PrivateKey(k),
+		}), nil
+	case ed25519.PrivateKey:
+		return marshalOpenSSHED25519PrivateKey(k, comment)
+	default:
+		return nil, fmt.Errorf("unsupported private key %T", key)
+	}
+}
+
+// AuthorizedKey returns the public key in authorized_keys format.
+func (k *PublicKey) AuthorizedKey(comment string) string {
+	authorizedKey := k.Algorithm + " " + base64.StdEncoding.EncodeToString(k.Blob)
+	if comment != "" {
+		authorizedKey += " " + comment
+	}
+	return authorizedKey
+}
+
+// see https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.key
+func marshalOpenSSHED25519PrivateKey(key ed25519.PrivateKey, comment string) ([]byte, error) {
+	publicKey, err := NewPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	check := make([]byte, 4)
+	if _, err := rand.Read(check); err != nil {
+		return nil, err
+	}
+	private := &bytes.Buffer{}
+	private.Write(check)
+	private.Write(check)
+	writeString(private, []byte(KeyAlgoED25519))
+	writeString(private, key.Public().(ed25519.PublicKey))
+	writeString(private, key)
+	writeString(private, []byte(comment))
+	// unencrypted keys are padded to the block size 8
+	for i := byte(1); private.Len()%8 != 0; i++ {
+		private.WriteByte(i)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(openSSHKeyMagic)
+	writeString(buf, []byte("none"))
+	writeString(buf, []byte("none"))
+	writeString(buf, []byte{})
+	nkeys := make([]byte, 4)
+	binary.BigEndian.PutUint32(nkeys, 1)
+	buf.Write(nkeys)
+	writeString(buf, publicKey.Blob)
+	writeString(buf, private.Bytes())
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  openSSHKeyPEM,
+		Bytes: buf.Bytes(),
+	}), nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sshutils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+)
+
+func TestMarshalPrivateKey(t *testing.T) {
+	ed25519Key, err := GenerateKey(KeyTypeED25519, 0)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	// a small rsa key keeps the test fast
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("should not get error %+v", err)
+	}
+	for algorithm, key := range map[string]crypto.Signer{
+		KeyAlgoED25519: ed25519Key,
+		KeyAlgoRSA:     rsaKey,
+	} {
+		privateKey, err := MarshalPrivateKey(key, "deploy")
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+		expected, err := NewPublicKey(key.Public())
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+		publicKey, err := ParsePrivateKey(privateKey, "")
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+		if publicKey.Algorithm != algorithm {
+			t.Fatalf("algorithm should be %s, got %s", algorithm, publicKey.Algorithm)
+		}
+		if publicKey.Fingerprint() != expected.Fingerprint() {
+			t.Fatalf("fingerprint should be %s, got %s", expected.Fingerprint(), publicKey.Fingerprint())
+		}
+		authorizedKey := publicKey.AuthorizedKey("deploy")
+		if !strings.HasPrefix(authorizedKey, algorithm+" ") || !strings.HasSuffix(authorizedKey, " deploy") {
+			t.Fatalf("unexpected authorized key %s", authorizedKey)
+		}
+	}
+}
+
+func TestGenerateKey_Invalid(t *testing.T) {
+	_, err := GenerateKey("dsa", 0)
+	if err == nil {
+		t.Fatalf("should get error with unsupported key type")
+	}
+	_, err = GenerateKey(KeyTypeRSA, 1024)
+	if err == nil {
+		t.Fatalf("should get error with weak rsa key")
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2018 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sshutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	KeyAlgoRSA       = "ssh-rsa"
+	KeyAlgoED25519   = "ssh-ed25519"
+	KeyAlgoECDSA256  = "ecdsa-sha2-nistp256"
+	KeyAlgoECDSA384  = "ecdsa-sha2-nistp384"
+	KeyAlgoECDSA521  = "ecdsa-sha2-nistp521"
+	openSSHKeyMagic  = "openssh-key-v1\x00"
+	openSSHKeyPEM    = "OPENSSH PRIVATE KEY"
+	rsaPrivateKeyPEM = "RSA PRIVATE KEY"
+	ecPrivateKeyPEM  = "EC PRIVATE KEY"
+	pkcs8KeyPEM      = "PRIVATE KEY"
+)
+
+var ErrPassphraseMissing = errors.New("private key is encrypted, passphrase is required")
+
+// PublicKey is the ssh wire format of a public key with its algorithm name.
+type PublicKey struct {
+	Algorithm string
+	Blob      []byte
+}
+
+// Fingerprint returns the SHA256 fingerprint as printed by ssh-keygen -l.
+func (k *PublicKey) Fingerprint() string {
+	sum := sha256.Sum256(k.Blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// ParsePrivateKey decodes a PEM encoded private key (PKCS#1, SEC1, PKCS#8 or openssh-key-v1)
+// and returns its public part. The passphrase is only used for legacy encrypted PEM blocks,
+// openssh keys keep the public key unencrypted.
+func ParsePrivateKey(privateKey []byte, passphrase string) (*PublicKey, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, errors.New("failed to decode pem block of private key")
+	}
+	if block.Type == openSSHKeyPEM {
+		return parseOpenSSHPublicKey(block.Bytes)
+	}
+
+	der := block.Bytes
+	if x509.IsEncryptedPEMBlock(block) {
+		if passphrase == "" {
+			return nil, ErrPassphraseMissing
+		}
+		decrypted, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+		if err != nil {
+			return nil, err
+		}
+		der = decrypted
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case rsaPrivateKeyPEM:
+		key, err = x509.ParsePKCS1PrivateKey(der)
+	case ecPrivateKeyPEM:
+		key, err = x509.ParseECPrivateKey(der)
+	case pkcs8KeyPEM:
+		key, err = x509.ParsePKCS8PrivateKey(der)
+	default:
+		return nil, fmt.Errorf("unsupported private key type [%s]", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key %T", key)
+	}
+	return NewPublicKey(signer.Public())
+}
+
+// NewPublicKey encodes a crypto public key into the ssh wire format.
+func NewPublicKey(pub crypto.PublicKey) (*PublicKey, error) {
+	buf := &bytes.Buffer{}
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		writeString(buf, []byte(KeyAlgoRSA))
+		writeMpint(buf, big.NewInt(int64(key.E)))
+		writeMpint(buf, key.N)
+		return &PublicKey{Algorithm: KeyAlgoRSA, Blob: buf.Bytes()}, nil
+	case *ecdsa.PublicKey:
+		algorithm, curveName, err := ecdsaNames(key.Curve)
+		if err != nil {
+			return nil, err
+		}
+		writeString(buf, []byte(algorithm))
+		writeString(buf, []byte(curveName))
+		writeString(buf, elliptic.Marshal(key.Curve, key.X, key.Y))
+		return &PublicKey{Algorithm: algorithm, Blob: buf.Bytes()}, nil
+	case ed25519.PublicKey:
+		writeString(buf, []byte(KeyAlgoED25519))
+		writeString(buf, key)
+		return &PublicKey{Algorithm: KeyAlgoED25519, Blob: buf.Bytes()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key %T", pub)
+	}
+}
+
+func ecdsaNames(curve elliptic.Curve) (string, string, error) {
+	switch curve.Params().BitSize {
+	case 256:
+		return KeyAlgoECDSA256, "nistp256", nil
+	case 384:
+		return KeyAlgoECDSA384, "nistp384", nil
+	case 521:
+		return KeyAlgoECDSA521, "nistp521", nil
+	}
+	return "", "", fmt.Errorf("unsupported ecdsa curve %s", curve.Params().Name)
+}
+
+// see https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.key
+func parseOpenSSHPublicKey(data []byte) (*PublicKey, error) {
+	if !bytes.HasPrefix(data, []byte(openSSHKeyMagic)) {
+		return nil, errors.New("invalid openssh private key magic")
+	}
+	rest := data[len(openSSHKeyMagic):]
+	var err error
+	// ciphername, kdfname, kdfoptions
+	for i := 0; i < 3; i++ {
+		if _, rest, err = readString(rest); err != nil {
+			return nil, err
+		}
+	}
+	if len(rest) < 4 {
+		return nil, errors.New("invalid openssh private key")
+	}
+	if binary.BigEndian.Uint32(rest) != 1 {
+		return nil, errors.New("openssh private key with multiple keys is not supported")
+	}
+	blob, _, err := readString(rest[4:])
+	if err != nil {
+		return nil, err
+	}
+	algorithm, _, err := readString(blob)
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{Algorithm: string(algorithm), Blob: blob}, nil
+}
+
+func readString(in []byte) ([]byte, []byte, error) {
+	if len(in) < 4 {
+		return nil, nil, errors.New("unexpected end of ssh data")
+	}
+	length := binary.BigEndian.Uint32(in)
+	in = in[4:]
+	if uint32(len(in)) < length {
+		return nil, nil, errors.New("unexpected end of ssh data")
+	}
+	return in[:length], in[length:], nil
+}
+
+func writeString(buf *bytes.Buffer, s []byte) {
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(s)))
+	buf.Write(length)
+	buf.Write(s)
+}
+
+func writeMpint(buf *bytes.Buffer, n *big.Int) {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	writeString(buf, b)
+}
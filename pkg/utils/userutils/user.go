@@ -15,6 +15,23 @@ package userutils
 
 import "github.com/ant0ine/go-json-rest/rest"
 
+const (
+	// RemoteUserEnv is the request env key of the authenticated username, same as the go-json-rest auth middlewares
+	RemoteUserEnv = "REMOTE_USER"
+	// ImpersonatorEnv is the request env key of the admin who impersonates the remote user
+	ImpersonatorEnv = "IMPERSONATOR"
+)
+
+// GetUserNameFromRequest returns the username set by the auth middleware,
+// it is empty if the request is not authenticated.
 func GetUserNameFromRequest(request *rest.Request) string {
-	return request.Header.Get("X-Token-Username")
+	username, _ := request.Env[RemoteUserEnv].(string)
+	return username
+}
+
+// GetImpersonatorFromRequest returns the admin who impersonates the request user,
+// it is empty if the request is not impersonated.
+func GetImpersonatorFromRequest(request *rest.Request) string {
+	impersonator, _ := request.Env[ImpersonatorEnv].(string)
+	return impersonator
 }